@@ -0,0 +1,110 @@
+package main
+
+import (
+	"fmt"
+	"net"
+	"sync"
+	"time"
+)
+
+// discoverLiveHosts pings each candidate host and returns the subset that
+// responded. It tries an ICMP echo first and falls back to a quick ARP-style
+// probe (a TCP dial to a common port) when ICMP is unavailable, e.g. because
+// the process lacks permission to open raw sockets. A host that answers
+// neither probe is left out, including when a probe could not be sent at
+// all; there is no assume-alive fallback.
+func discoverLiveHosts(hosts []net.IP, timeout time.Duration) []net.IP {
+	var (
+		mu    sync.Mutex
+		alive []net.IP
+		wg    sync.WaitGroup
+	)
+
+	sem := make(chan struct{}, 256)
+	for _, h := range hosts {
+		wg.Add(1)
+		sem <- struct{}{}
+		go func(ip net.IP) {
+			defer wg.Done()
+			defer func() { <-sem }()
+			if pingHost(ip, timeout) {
+				mu.Lock()
+				alive = append(alive, ip)
+				mu.Unlock()
+			}
+		}(h)
+	}
+	wg.Wait()
+
+	return alive
+}
+
+// pingHost reports whether a host appears to be up, using an ICMP echo
+// request when possible and falling back to a TCP connect probe against a
+// handful of commonly open ports.
+func pingHost(ip net.IP, timeout time.Duration) bool {
+	if icmpEcho(ip, timeout) {
+		return true
+	}
+	return tcpProbeAlive(ip, timeout)
+}
+
+// icmpEcho sends a single ICMP echo request and waits for any reply. It
+// requires CAP_NET_RAW (or root); if the raw socket can't be opened, it
+// reports false so the caller can fall back to a TCP probe.
+func icmpEcho(ip net.IP, timeout time.Duration) bool {
+	conn, err := net.DialTimeout("ip4:icmp", ip.String(), timeout)
+	if err != nil {
+		return false
+	}
+	defer conn.Close()
+
+	id := uint16(time.Now().UnixNano() & 0xffff)
+	msg := []byte{
+		8, 0, 0, 0, // type: echo request, code: 0, checksum: filled below
+		byte(id >> 8), byte(id), 0, 1, // identifier, sequence
+	}
+	msg[2], msg[3] = checksum(msg)
+
+	conn.SetDeadline(time.Now().Add(timeout))
+	if _, err := conn.Write(msg); err != nil {
+		return false
+	}
+
+	reply := make([]byte, 64)
+	_, err = conn.Read(reply)
+	return err == nil
+}
+
+// tcpProbeAlive tries a handful of commonly open TCP ports and reports the
+// host alive if any of them accepts or actively refuses the connection
+// (a refusal still proves the host is up and routable).
+func tcpProbeAlive(ip net.IP, timeout time.Duration) bool {
+	for _, port := range []int{80, 443, 22, 445, 3389} {
+		conn, err := net.DialTimeout("tcp", fmt.Sprintf("%s:%d", ip.String(), port), timeout)
+		if err == nil {
+			conn.Close()
+			return true
+		}
+		if isConnRefused(err) {
+			return true
+		}
+	}
+	return false
+}
+
+func checksum(b []byte) (byte, byte) {
+	var sum uint32
+	for i := 0; i < len(b); i += 2 {
+		if i+1 < len(b) {
+			sum += uint32(b[i])<<8 | uint32(b[i+1])
+		} else {
+			sum += uint32(b[i]) << 8
+		}
+	}
+	for sum>>16 != 0 {
+		sum = (sum & 0xffff) + (sum >> 16)
+	}
+	cs := ^uint16(sum)
+	return byte(cs >> 8), byte(cs)
+}
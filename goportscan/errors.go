@@ -0,0 +1,54 @@
+package main
+
+import (
+	"errors"
+	"net"
+	"os"
+	"syscall"
+)
+
+// isConnRefused reports whether err is a TCP RST / ECONNREFUSED, as opposed
+// to a timeout or a resource exhaustion error.
+func isConnRefused(err error) bool {
+	var sysErr syscall.Errno
+	if errors.As(err, &sysErr) {
+		return sysErr == syscall.ECONNREFUSED
+	}
+	var opErr *net.OpError
+	if errors.As(err, &opErr) {
+		var errno syscall.Errno
+		if errors.As(opErr.Err, &errno) {
+			return errno == syscall.ECONNREFUSED
+		}
+	}
+	return false
+}
+
+// isTimeout reports whether err represents a dial timeout.
+func isTimeout(err error) bool {
+	var netErr net.Error
+	if errors.As(err, &netErr) {
+		return netErr.Timeout()
+	}
+	return false
+}
+
+// isTooManyOpenFiles reports whether err was caused by hitting the process's
+// open file descriptor limit (EMFILE/ENFILE).
+func isTooManyOpenFiles(err error) bool {
+	var sysErr syscall.Errno
+	if errors.As(err, &sysErr) {
+		return sysErr == syscall.EMFILE || sysErr == syscall.ENFILE
+	}
+	var opErr *net.OpError
+	if errors.As(err, &opErr) {
+		var errno syscall.Errno
+		if errors.As(opErr.Err, &errno) {
+			return errno == syscall.EMFILE || errno == syscall.ENFILE
+		}
+	}
+	if errors.Is(err, os.ErrDeadlineExceeded) {
+		return false
+	}
+	return false
+}
@@ -0,0 +1,61 @@
+package main
+
+import (
+	"bytes"
+	"sort"
+)
+
+// defaultBatchSize is how many scanOutcomes the default (non -stream)
+// aggregation path buffers before identifying services and writing them
+// out, when -batch-size isn't overridden. High enough that almost every
+// real scan finishes in a single batch and keeps today's fully-sorted
+// output, but bounded so a gigantic host x port matrix can't grow the
+// in-memory result set without limit.
+const defaultBatchSize = 50000
+
+// batchResults drains outcomes from ch in chunks of at most batchSize,
+// calling process on each chunk before discarding it and reading the next.
+// This is what lets the default aggregation path scan a /16 x 65535 matrix
+// without ever holding more than one batch's worth of scanOutcome and
+// per-result service/TLS/HTTP data in memory at once.
+func batchResults(ch <-chan scanOutcome, batchSize int, process func([]scanOutcome)) {
+	if batchSize <= 0 {
+		batchSize = defaultBatchSize
+	}
+	batch := make([]scanOutcome, 0, batchSize)
+	for outcome := range ch {
+		batch = append(batch, outcome)
+		if len(batch) >= batchSize {
+			process(batch)
+			batch = make([]scanOutcome, 0, batchSize)
+		}
+	}
+	if len(batch) > 0 {
+		process(batch)
+	}
+}
+
+// sortOutcomes orders a batch the same way the whole-scan result set used
+// to be sorted before batching: proxied (hostname) targets after every
+// IP-addressed one, each group ordered host/IP then port ascending. Batches
+// are sorted independently of each other, so the overall output across a
+// multi-batch scan is grouped-and-sorted-per-batch rather than one globally
+// ascending list.
+func sortOutcomes(outcomes []scanOutcome) {
+	sort.Slice(outcomes, func(i, j int) bool {
+		a, b := outcomes[i].Addr, outcomes[j].Addr
+		if (a.Host != "") != (b.Host != "") {
+			return a.Host == ""
+		}
+		if a.Host != "" {
+			if a.Host != b.Host {
+				return a.Host < b.Host
+			}
+			return a.Port < b.Port
+		}
+		if !a.IP.Equal(b.IP) {
+			return bytes.Compare(a.IP.To16(), b.IP.To16()) < 0
+		}
+		return a.Port < b.Port
+	})
+}
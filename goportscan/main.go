@@ -1,83 +1,276 @@
 package main
 
 import (
+	"flag"
 	"fmt"
 	"net"
+	"os"
 	"sort"
 	"sync"
 	"time"
 )
 
+// Addr is a single (host, port) pair to be dialed by a worker.
+type Addr struct {
+	IP   net.IP
+	Port int
+}
+
 func main() {
-	target := "192.168.1.1"
-	var wg sync.WaitGroup
-	
-	// Define the port range to scan
-	startPort := 1
-	endPort := 65535
-	
-	// Create a channel to collect open ports
-	openPorts := make(chan int, 100)
-	
-	// Set timeout for connection attempts
-	timeout := 500 * time.Millisecond
-	
-	// Start workers for parallel scanning
-	numWorkers := 1000
-	portsPerWorker := (endPort - startPort + 1) / numWorkers
-	
-	fmt.Printf("Starting ultra fast port scan on %s...\n", target)
+	excludePortsFlag := flag.String("exclude-ports", "", "comma-separated ports/ranges to skip, e.g. 135,445,3389")
+	excludeHostsFlag := flag.String("exclude-hosts", "", "comma-separated hosts/IPs to skip")
+	outputFlag := flag.String("o", "text", "output format: text, json, xml, or push")
+	streamFlag := flag.Bool("stream", false, "emit each result as soon as it's found, instead of buffering until the scan completes")
+	pushAddrFlag := flag.String("push-addr", ":50051", "listen address for -o push")
+	scanTypeFlag := flag.String("scan-type", "connect", "scan type: connect, syn, or udp")
+	noDiscoveryFlag := flag.Bool("Pn", false, "skip host discovery and treat every candidate as alive (nmap-style -Pn)")
+	flag.Parse()
+
+	targetSpecs := flag.Args()
+	if len(targetSpecs) == 0 {
+		targetSpecs = []string{"192.168.1.1"}
+	}
+
+	mode, err := parseScanMode(*scanTypeFlag)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, "error:", err)
+		os.Exit(1)
+	}
+
+	var syn synProber
+	if mode == scanModeSYN {
+		syn, err = newSYNProber()
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "syn scan unavailable (%v), falling back to connect scan\n", err)
+			mode = scanModeConnect
+		} else {
+			defer syn.Close()
+		}
+	}
+
+	hosts, explicitTargets, err := expandTargets(targetSpecs)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, "error:", err)
+		os.Exit(1)
+	}
+
+	sink, err := newOutputSink(*outputFlag, os.Stdout, *pushAddrFlag)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, "error:", err)
+		os.Exit(1)
+	}
+	if err := sink.Open(); err != nil {
+		fmt.Fprintln(os.Stderr, "error:", err)
+		os.Exit(1)
+	}
+
+	excludeHosts := map[string]bool{}
+	if *excludeHostsFlag != "" {
+		excludeHosts, err = parseHostSet(*excludeHostsFlag)
+		if err != nil {
+			fmt.Fprintln(os.Stderr, "error:", err)
+			os.Exit(1)
+		}
+	}
+
+	startPort, endPort := 1, 65535
+	excludePorts := map[int]bool{}
+	if *excludePortsFlag != "" {
+		excluded, err := parsePortList(*excludePortsFlag)
+		if err != nil {
+			fmt.Fprintln(os.Stderr, "error:", err)
+			os.Exit(1)
+		}
+		for _, p := range excluded {
+			excludePorts[p] = true
+		}
+	}
+
+	var candidates []net.IP
+	for _, ip := range hosts {
+		if !excludeHosts[ip.String()] {
+			candidates = append(candidates, ip)
+		}
+	}
+
+	var liveHosts []net.IP
+	if *noDiscoveryFlag {
+		liveHosts = candidates
+		fmt.Fprintf(os.Stderr, "Skipping host discovery (-Pn): treating all %d candidate(s) as alive\n", len(candidates))
+	} else {
+		fmt.Fprintf(os.Stderr, "Discovering live hosts among %d candidate(s)...\n", len(candidates))
+		discovered := discoverLiveHosts(candidates, 500*time.Millisecond)
+		live := make(map[string]bool, len(discovered))
+		for _, ip := range discovered {
+			live[ip.String()] = true
+		}
+		var forced int
+		for _, ip := range candidates {
+			switch {
+			case live[ip.String()]:
+				liveHosts = append(liveHosts, ip)
+			case explicitTargets[ip.String()]:
+				// A host the user named directly is scanned regardless of
+				// what discovery concluded: discovery is a heuristic and a
+				// single explicitly-named target is presumably intentional.
+				liveHosts = append(liveHosts, ip)
+				forced++
+			}
+		}
+		fmt.Fprintf(os.Stderr, "%d host(s) responded, %d force-included as explicit targets, %d skipped as dead\n",
+			len(discovered), forced, len(candidates)-len(liveHosts))
+	}
+
+	var ports []int
+	for p := startPort; p <= endPort; p++ {
+		if !excludePorts[p] {
+			ports = append(ports, p)
+		}
+	}
+
+	addrs := make(chan Addr, 1000)
+	results := make(chan Addr, 1000)
+
+	controller := newAdaptiveController()
+
+	fmt.Fprintf(os.Stderr, "Starting scan of %d host(s) x %d port(s) with adaptive rate limiting...\n", len(liveHosts), len(ports))
 	start := time.Now()
-	
-	// Launch workers
-	for i := 0; i < numWorkers; i++ {
+
+	var wg sync.WaitGroup
+	for i := 0; i < maxInFlight; i++ {
 		wg.Add(1)
-		workerStartPort := startPort + (i * portsPerWorker)
-		workerEndPort := workerStartPort + portsPerWorker - 1
-		
-		// Adjust the last worker to include any remaining ports
-		if i == numWorkers-1 {
-			workerEndPort = endPort
-		}
-		
-		go func(startP, endP int) {
+		go func() {
 			defer wg.Done()
-			for port := startP; port <= endP; port++ {
-				address := fmt.Sprintf("%s:%d", target, port)
-				conn, err := net.DialTimeout("tcp", address, timeout)
-				
-				if err == nil {
-					openPorts <- port
-					conn.Close()
+			for addr := range addrs {
+				timeout := controller.Acquire()
+
+				dialStart := time.Now()
+				var open bool
+				var err error
+				switch mode {
+				case scanModeSYN:
+					open, err = syn.Probe(addr, timeout)
+				case scanModeUDP:
+					open, err = udpProbe(addr, timeout)
+				default:
+					open, err = connectProbe(addr, timeout)
+				}
+				controller.Release(time.Since(dialStart), err)
+
+				if open {
+					results <- addr
 				}
 			}
-		}(workerStartPort, workerEndPort)
+		}()
 	}
-	
-	// Close the channel when all workers are done
+
+	go func() {
+		for _, ip := range liveHosts {
+			for _, port := range ports {
+				addrs <- Addr{IP: ip, Port: port}
+			}
+		}
+		close(addrs)
+	}()
+
 	go func() {
 		wg.Wait()
-		close(openPorts)
+		close(results)
 	}()
-	
-	// Collect and display results
-	var results []int
-	for port := range openPorts {
-		results = append(results, port)
-	}
-	
-	// Sort and display results
-	sort.Ints(results)
-	
-	fmt.Printf("\nScan completed in %s\n", time.Since(start))
-	fmt.Printf("Open ports on %s:\n", target)
-	
-	if len(results) == 0 {
-		fmt.Println("No open ports found")
+
+	var found int
+	if *streamFlag {
+		// Identify and emit each result the moment it's found, rather than
+		// waiting for the whole scan to finish.
+		var svcWG sync.WaitGroup
+		svcSem := make(chan struct{}, 100)
+		var mu sync.Mutex
+		for addr := range results {
+			found++
+			svcWG.Add(1)
+			svcSem <- struct{}{}
+			go func(addr Addr) {
+				defer svcWG.Done()
+				defer func() { <-svcSem }()
+				info := identifyService(addr, 2*time.Second)
+				mu.Lock()
+				sink.Emit(toScanResult(addr, info))
+				mu.Unlock()
+			}(addr)
+		}
+		svcWG.Wait()
 	} else {
-		for _, port := range results {
-			fmt.Printf("%d/tcp open\n", port)
+		var addrs []Addr
+		for addr := range results {
+			addrs = append(addrs, addr)
 		}
-		fmt.Printf("\nFound %d open ports\n", len(results))
+
+		sort.Slice(addrs, func(i, j int) bool {
+			if !addrs[i].IP.Equal(addrs[j].IP) {
+				return ipToUint32(addrs[i].IP) < ipToUint32(addrs[j].IP)
+			}
+			return addrs[i].Port < addrs[j].Port
+		})
+
+		if len(addrs) > 0 {
+			fmt.Fprintln(os.Stderr, "\nIdentifying services on open ports...")
+		}
+		services := make([]ServiceInfo, len(addrs))
+		var svcWG sync.WaitGroup
+		svcSem := make(chan struct{}, 100)
+		for i, addr := range addrs {
+			svcWG.Add(1)
+			svcSem <- struct{}{}
+			go func(i int, addr Addr) {
+				defer svcWG.Done()
+				defer func() { <-svcSem }()
+				services[i] = identifyService(addr, 2*time.Second)
+			}(i, addr)
+		}
+		svcWG.Wait()
+
+		for i, addr := range addrs {
+			sink.Emit(toScanResult(addr, services[i]))
+		}
+		found = len(addrs)
+	}
+
+	if err := sink.Close(); err != nil {
+		fmt.Fprintln(os.Stderr, "error:", err)
+	}
+
+	fmt.Fprintf(os.Stderr, "\nScan completed in %s\n", time.Since(start))
+	if found == 0 {
+		fmt.Fprintln(os.Stderr, "No open ports found")
+		return
+	}
+	fmt.Fprintf(os.Stderr, "Found %d open port(s)\n", found)
+}
+
+// toScanResult combines an Addr with its fingerprint into the shape emitted
+// by OutputSink implementations.
+func toScanResult(addr Addr, info ServiceInfo) ScanResult {
+	return ScanResult{
+		Host:    addr.IP.String(),
+		Port:    addr.Port,
+		Service: info.Name,
+		Version: info.Version,
+		Banner:  info.Banner,
+	}
+}
+
+// formatService renders a ServiceInfo the way nmap does, e.g.
+// "ssh  OpenSSH_8.9p1", falling back to just the banner, or "unknown" when
+// nothing was identified.
+func formatService(info ServiceInfo) string {
+	switch {
+	case info.Name != "" && info.Version != "":
+		return fmt.Sprintf("%s  %s", info.Name, info.Version)
+	case info.Name != "":
+		return info.Name
+	case info.Banner != "":
+		return info.Banner
+	default:
+		return "unknown"
 	}
 }
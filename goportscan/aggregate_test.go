@@ -0,0 +1,104 @@
+package main
+
+import (
+	"net"
+	"testing"
+)
+
+// TestBatchResults_BoundsMemoryAcrossTenMillionOutcomes feeds batchResults
+// far more outcomes than any real scan's process function would want to
+// hold in memory at once, and checks that every batch handed to process
+// stays within batchSize regardless of how many outcomes came before it --
+// the property that lets the default aggregation path scan a /16 x 65535
+// matrix without the whole result set ever being live at the same time.
+func TestBatchResults_BoundsMemoryAcrossTenMillionOutcomes(t *testing.T) {
+	const total = 10_000_000
+	const batchSize = 50_000
+
+	ch := make(chan scanOutcome, 1000)
+	go func() {
+		defer close(ch)
+		for i := 0; i < total; i++ {
+			ch <- scanOutcome{Addr: Addr{Port: i % 65536}, State: portClosed}
+		}
+	}()
+
+	var processed, batches int
+	batchResults(ch, batchSize, func(batch []scanOutcome) {
+		if len(batch) > batchSize {
+			t.Fatalf("batch of %d outcomes exceeds batchSize %d", len(batch), batchSize)
+		}
+		processed += len(batch)
+		batches++
+	})
+
+	if processed != total {
+		t.Errorf("processed %d outcomes, want %d", processed, total)
+	}
+	if want := total / batchSize; batches != want {
+		t.Errorf("got %d batches, want %d", batches, want)
+	}
+}
+
+func TestBatchResults_ZeroOrNegativeSizeFallsBackToDefault(t *testing.T) {
+	ch := make(chan scanOutcome, 1)
+	ch <- scanOutcome{Addr: Addr{Port: 80}, State: portOpen}
+	close(ch)
+
+	var sawBatch bool
+	batchResults(ch, 0, func(batch []scanOutcome) {
+		sawBatch = true
+		if len(batch) != 1 {
+			t.Errorf("batch len = %d, want 1", len(batch))
+		}
+	})
+	if !sawBatch {
+		t.Error("process was never called for a single-item channel")
+	}
+}
+
+func TestSortOutcomes_IPsAscendingThenHostsAfter(t *testing.T) {
+	outcomes := []scanOutcome{
+		{Addr: Addr{Host: "proxied.example", Port: 443}},
+		{Addr: Addr{IP: net.ParseIP("10.0.0.2"), Port: 22}},
+		{Addr: Addr{IP: net.ParseIP("10.0.0.1"), Port: 80}},
+		{Addr: Addr{IP: net.ParseIP("10.0.0.1"), Port: 22}},
+	}
+	sortOutcomes(outcomes)
+
+	gotOrder := make([]string, len(outcomes))
+	for i, o := range outcomes {
+		if o.Addr.Host != "" {
+			gotOrder[i] = o.Addr.Host
+		} else {
+			gotOrder[i] = o.Addr.IP.String()
+		}
+	}
+	if gotOrder[0] != "10.0.0.1" || gotOrder[1] != "10.0.0.1" || gotOrder[2] != "10.0.0.2" || gotOrder[3] != "proxied.example" {
+		t.Errorf("sorted order = %v, want IPs ascending (10.0.0.1 before 10.0.0.2) then hosts after", gotOrder)
+	}
+	if outcomes[0].Addr.Port != 22 || outcomes[1].Addr.Port != 80 {
+		t.Errorf("within 10.0.0.1, port order = %d, %d, want 22 then 80", outcomes[0].Addr.Port, outcomes[1].Addr.Port)
+	}
+}
+
+// BenchmarkBatchResults_TenMillionOutcomes exercises the same bounded-batch
+// aggregation path at the scale the -batch-size flag exists for, reporting
+// allocations so a future change that reintroduces whole-scan buffering
+// shows up as a large jump here instead of only on a real gigantic scan.
+func BenchmarkBatchResults_TenMillionOutcomes(b *testing.B) {
+	const total = 10_000_000
+	const batchSize = 50_000
+
+	b.ReportAllocs()
+	for i := 0; i < b.N; i++ {
+		ch := make(chan scanOutcome, 1000)
+		go func() {
+			defer close(ch)
+			for j := 0; j < total; j++ {
+				ch <- scanOutcome{Addr: Addr{Port: j % 65536}, State: portClosed}
+			}
+		}()
+		batchResults(ch, batchSize, func(batch []scanOutcome) {})
+	}
+}
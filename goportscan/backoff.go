@@ -0,0 +1,75 @@
+package main
+
+import (
+	"fmt"
+	"math/rand"
+	"time"
+)
+
+// backoffStrategy computes the delay before a retry, given the (0-indexed)
+// retry attempt number and -retry-backoff's base duration. It's a plain
+// function type rather than an interface so each named strategy, and a
+// test's fake strategy, are just functions -- parseBackoffStrategy is the
+// only place that needs to know all four names.
+type backoffStrategy func(attempt int, base time.Duration) time.Duration
+
+// noBackoff never delays between retries, for a link fast enough that a
+// retry's whole cost should be the probe itself.
+func noBackoff(int, time.Duration) time.Duration {
+	return 0
+}
+
+// constantBackoff waits the same base delay before every retry -- this
+// scanner's original, and still default, behavior.
+func constantBackoff(_ int, base time.Duration) time.Duration {
+	return base
+}
+
+// linearBackoff waits base*(attempt+1): base before the first retry, 2*base
+// before the second, and so on.
+func linearBackoff(attempt int, base time.Duration) time.Duration {
+	return base * time.Duration(attempt+1)
+}
+
+// exponentialJitterBackoff returns a backoffStrategy implementing "full
+// jitter" exponential backoff: the ceiling doubles every attempt (capped at
+// max), and the actual delay is picked uniformly between zero and that
+// ceiling, so retries from many concurrent workers spread out instead of
+// synchronizing on the same few instants. rnd must be a *rand.Rand private
+// to the caller's goroutine -- math/rand.Rand isn't safe for concurrent
+// use, which is why each worker gets its own in main.go rather than sharing
+// one across the pool.
+func exponentialJitterBackoff(max time.Duration, rnd *rand.Rand) backoffStrategy {
+	return func(attempt int, base time.Duration) time.Duration {
+		if base <= 0 {
+			return 0
+		}
+		ceiling := base << uint(attempt)
+		if ceiling <= 0 || ceiling > max { // <= 0 catches the shift overflowing into a negative duration
+			ceiling = max
+		}
+		if ceiling <= 0 {
+			return 0
+		}
+		return time.Duration(rnd.Int63n(int64(ceiling) + 1))
+	}
+}
+
+// parseBackoffStrategy resolves -backoff's flag value to a backoffStrategy.
+// rnd is only used by "exponential-jitter" and may be nil when the caller
+// just wants to validate the name (e.g. once at startup, before any
+// per-worker *rand.Rand exists).
+func parseBackoffStrategy(name string, max time.Duration, rnd *rand.Rand) (backoffStrategy, error) {
+	switch name {
+	case "none":
+		return noBackoff, nil
+	case "constant":
+		return constantBackoff, nil
+	case "linear":
+		return linearBackoff, nil
+	case "exponential-jitter":
+		return exponentialJitterBackoff(max, rnd), nil
+	default:
+		return nil, fmt.Errorf("unknown -backoff strategy %q (want none, constant, linear, or exponential-jitter)", name)
+	}
+}
@@ -0,0 +1,70 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"encoding/xml"
+	"strings"
+	"testing"
+)
+
+func TestJSONSink_EmitsOneObjectPerLine(t *testing.T) {
+	var buf bytes.Buffer
+	sink := &jsonSink{enc: json.NewEncoder(&buf)}
+
+	if err := sink.Emit(ScanResult{Host: "10.0.0.1", Port: 22, Service: "ssh", Version: "OpenSSH_8.9p1"}); err != nil {
+		t.Fatalf("Emit: %v", err)
+	}
+	if err := sink.Emit(ScanResult{Host: "10.0.0.1", Port: 80}); err != nil {
+		t.Fatalf("Emit: %v", err)
+	}
+
+	lines := strings.Split(strings.TrimRight(buf.String(), "\n"), "\n")
+	if len(lines) != 2 {
+		t.Fatalf("got %d lines, want 2: %q", len(lines), buf.String())
+	}
+
+	var r ScanResult
+	if err := json.Unmarshal([]byte(lines[0]), &r); err != nil {
+		t.Fatalf("unmarshal first line: %v", err)
+	}
+	if r.Host != "10.0.0.1" || r.Port != 22 || r.Service != "ssh" || r.Version != "OpenSSH_8.9p1" {
+		t.Errorf("first result = %+v, want host/port/service/version preserved", r)
+	}
+	if strings.Contains(lines[1], `"service"`) {
+		t.Errorf("second line should omit empty service field (omitempty): %s", lines[1])
+	}
+}
+
+func TestXMLSink_RendersNmapCompatibleDocument(t *testing.T) {
+	var buf bytes.Buffer
+	sink := &xmlSink{w: &buf}
+
+	sink.Emit(ScanResult{Host: "10.0.0.1", Port: 22, Service: "ssh", Version: "OpenSSH_8.9p1"})
+	sink.Emit(ScanResult{Host: "10.0.0.1", Port: 80})
+	sink.Emit(ScanResult{Host: "10.0.0.2", Port: 443})
+
+	if err := sink.Close(); err != nil {
+		t.Fatalf("Close: %v", err)
+	}
+
+	var run nmapRun
+	if err := xml.Unmarshal(buf.Bytes(), &run); err != nil {
+		t.Fatalf("unmarshal xml: %v\n%s", err, buf.String())
+	}
+	if len(run.Hosts) != 2 {
+		t.Fatalf("got %d hosts, want 2 (grouped by address): %+v", len(run.Hosts), run.Hosts)
+	}
+	if run.Hosts[0].Address.Addr != "10.0.0.1" || len(run.Hosts[0].Ports) != 2 {
+		t.Errorf("first host = %+v, want 10.0.0.1 with 2 ports", run.Hosts[0])
+	}
+	if run.Hosts[0].Ports[0].Service == nil || run.Hosts[0].Ports[0].Service.Name != "ssh" {
+		t.Errorf("port 22 service = %+v, want name=ssh", run.Hosts[0].Ports[0].Service)
+	}
+	if run.Hosts[0].Ports[1].Service != nil {
+		t.Errorf("port 80 service = %+v, want nil (no service identified)", run.Hosts[0].Ports[1].Service)
+	}
+	if !strings.Contains(buf.String(), xml.Header) {
+		t.Error("output missing XML header")
+	}
+}
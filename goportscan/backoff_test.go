@@ -0,0 +1,146 @@
+package main
+
+import (
+	"math/rand"
+	"testing"
+	"time"
+)
+
+// noopSleep discards the delay instead of actually waiting, so tests that
+// only care about probeWithRetry's retry count and returned state (not its
+// timing) run instantly.
+func noopSleep(time.Duration) {}
+
+// fakeClock stands in for time.Sleep: instead of blocking, it records every
+// requested delay so a test can assert a strategy's exact delay sequence
+// without the test itself taking as long as the delays it's checking.
+type fakeClock struct {
+	slept []time.Duration
+}
+
+func (c *fakeClock) sleep(d time.Duration) {
+	c.slept = append(c.slept, d)
+}
+
+func TestNoBackoff_AlwaysZero(t *testing.T) {
+	for attempt := 0; attempt < 5; attempt++ {
+		if d := noBackoff(attempt, 100*time.Millisecond); d != 0 {
+			t.Errorf("noBackoff(%d, ...) = %v, want 0", attempt, d)
+		}
+	}
+}
+
+func TestConstantBackoff_SameDelayEveryAttempt(t *testing.T) {
+	base := 50 * time.Millisecond
+	for attempt := 0; attempt < 5; attempt++ {
+		if d := constantBackoff(attempt, base); d != base {
+			t.Errorf("constantBackoff(%d, %v) = %v, want %v", attempt, base, d, base)
+		}
+	}
+}
+
+func TestLinearBackoff_GrowsByBaseEachAttempt(t *testing.T) {
+	base := 10 * time.Millisecond
+	want := []time.Duration{10 * time.Millisecond, 20 * time.Millisecond, 30 * time.Millisecond}
+	for attempt, w := range want {
+		if d := linearBackoff(attempt, base); d != w {
+			t.Errorf("linearBackoff(%d, %v) = %v, want %v", attempt, base, d, w)
+		}
+	}
+}
+
+func TestExponentialJitterBackoff_DeterministicWithSeededRNG(t *testing.T) {
+	base := 10 * time.Millisecond
+	max := 100 * time.Millisecond
+	rnd := rand.New(rand.NewSource(1))
+	strategy := exponentialJitterBackoff(max, rnd)
+
+	// Same seed, fresh strategy: the delay sequence must reproduce exactly,
+	// which is the whole point of injecting the RNG rather than using the
+	// package-level source.
+	replay := rand.New(rand.NewSource(1))
+	wantStrategy := exponentialJitterBackoff(max, replay)
+
+	for attempt := 0; attempt < 6; attempt++ {
+		got := strategy(attempt, base)
+		want := wantStrategy(attempt, base)
+		if got != want {
+			t.Fatalf("attempt %d: got %v, want %v (same seed should replay identically)", attempt, got, want)
+		}
+		if got < 0 || got > max {
+			t.Errorf("attempt %d: delay %v out of [0, %v]", attempt, got, max)
+		}
+	}
+}
+
+func TestExponentialJitterBackoff_CapsAtMax(t *testing.T) {
+	base := time.Millisecond
+	max := 20 * time.Millisecond
+	rnd := rand.New(rand.NewSource(42))
+	strategy := exponentialJitterBackoff(max, rnd)
+
+	// By attempt 10, base<<10 is far past max; the ceiling must clamp so the
+	// jittered delay never exceeds it.
+	for attempt := 0; attempt < 20; attempt++ {
+		if d := strategy(attempt, base); d > max {
+			t.Errorf("attempt %d: delay %v exceeds cap %v", attempt, d, max)
+		}
+	}
+}
+
+func TestExponentialJitterBackoff_ZeroBaseNeverSleeps(t *testing.T) {
+	rnd := rand.New(rand.NewSource(7))
+	strategy := exponentialJitterBackoff(time.Second, rnd)
+	if d := strategy(0, 0); d != 0 {
+		t.Errorf("exponentialJitterBackoff with base=0 = %v, want 0", d)
+	}
+}
+
+func TestParseBackoffStrategy_KnownNames(t *testing.T) {
+	rnd := rand.New(rand.NewSource(1))
+	for _, name := range []string{"none", "constant", "linear", "exponential-jitter"} {
+		if _, err := parseBackoffStrategy(name, time.Second, rnd); err != nil {
+			t.Errorf("parseBackoffStrategy(%q): %v", name, err)
+		}
+	}
+}
+
+func TestParseBackoffStrategy_UnknownNameErrors(t *testing.T) {
+	if _, err := parseBackoffStrategy("fibonacci", time.Second, nil); err == nil {
+		t.Error("parseBackoffStrategy(\"fibonacci\") = nil error, want one")
+	}
+}
+
+// TestProbeWithRetry_UsesStrategyForEachAttemptDelay drives probeWithRetry
+// with a fake clock and asserts the exact delay sequence linearBackoff
+// should produce, proving the strategy and base actually reach sleep rather
+// than probeWithRetry falling back to some fixed delay internally.
+func TestProbeWithRetry_UsesStrategyForEachAttemptDelay(t *testing.T) {
+	var calls int
+	probe := func() (portState, error) {
+		calls++
+		if calls <= 3 {
+			return portFiltered, nil
+		}
+		return portOpen, nil
+	}
+
+	clock := &fakeClock{}
+	state, err := probeWithRetry(probe, 3, 10*time.Millisecond, linearBackoff, clock.sleep)
+	if err != nil {
+		t.Errorf("probeWithRetry: unexpected error: %v", err)
+	}
+	if state != portOpen {
+		t.Errorf("probeWithRetry = %q, want %q", state, portOpen)
+	}
+
+	want := []time.Duration{10 * time.Millisecond, 20 * time.Millisecond, 30 * time.Millisecond}
+	if len(clock.slept) != len(want) {
+		t.Fatalf("slept %v, want %v", clock.slept, want)
+	}
+	for i, w := range want {
+		if clock.slept[i] != w {
+			t.Errorf("sleep %d = %v, want %v", i, clock.slept[i], w)
+		}
+	}
+}
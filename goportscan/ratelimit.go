@@ -0,0 +1,186 @@
+package main
+
+import (
+	"sort"
+	"sync"
+	"time"
+)
+
+const (
+	minInFlight      = 20
+	maxInFlight      = 1000
+	minTimeout       = 50 * time.Millisecond
+	maxTimeout       = 3 * time.Second
+	sampleWindow     = 50 // number of recent dials considered per recalibration
+	recalibrateEvery = 25
+)
+
+// adaptiveController tunes the scanner's concurrency and per-dial timeout on
+// the fly, based on a sliding window of recent dial outcomes. It grows the
+// timeout and shrinks concurrency (additive-increase/multiplicative-decrease)
+// when timeouts or descriptor exhaustion spike, and shrinks the timeout
+// toward the observed p95 latency when the host is clearly alive and simply
+// refusing connections quickly.
+type adaptiveController struct {
+	mu sync.Mutex
+
+	timeout  time.Duration
+	limit    int
+	inFlight int
+	notFull  *sync.Cond
+
+	samples     []sample
+	sinceRecalc int
+}
+
+type sample struct {
+	latency time.Duration
+	kind    outcomeKind
+}
+
+type outcomeKind int
+
+const (
+	outcomeOK outcomeKind = iota
+	outcomeRefused
+	outcomeTimeout
+	outcomeExhausted
+)
+
+func newAdaptiveController() *adaptiveController {
+	c := &adaptiveController{
+		timeout: 500 * time.Millisecond,
+		limit:   500,
+	}
+	c.notFull = sync.NewCond(&c.mu)
+	return c
+}
+
+// Acquire blocks until a dial slot is available and returns the timeout that
+// should be used for the next dial attempt.
+func (c *adaptiveController) Acquire() time.Duration {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	for c.inFlight >= c.limit {
+		c.notFull.Wait()
+	}
+	c.inFlight++
+	return c.timeout
+}
+
+// Release frees a dial slot and records the outcome of the dial that held
+// it, recalibrating the controller every recalibrateEvery samples.
+func (c *adaptiveController) Release(latency time.Duration, err error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.inFlight--
+	c.notFull.Signal()
+
+	c.samples = append(c.samples, sample{latency: latency, kind: classify(err)})
+	if len(c.samples) > sampleWindow {
+		c.samples = c.samples[len(c.samples)-sampleWindow:]
+	}
+
+	c.sinceRecalc++
+	if c.sinceRecalc >= recalibrateEvery && len(c.samples) >= sampleWindow/2 {
+		c.sinceRecalc = 0
+		c.recalibrate()
+	}
+}
+
+func classify(err error) outcomeKind {
+	switch {
+	case err == nil:
+		return outcomeOK
+	case isTooManyOpenFiles(err):
+		return outcomeExhausted
+	case isTimeout(err):
+		return outcomeTimeout
+	case isConnRefused(err):
+		return outcomeRefused
+	default:
+		return outcomeTimeout
+	}
+}
+
+// recalibrate adjusts limit and timeout based on the recent sample window.
+// Caller must hold c.mu.
+func (c *adaptiveController) recalibrate() {
+	var timeouts, refused, exhausted int
+	latencies := make([]time.Duration, 0, len(c.samples))
+	for _, s := range c.samples {
+		switch s.kind {
+		case outcomeTimeout:
+			timeouts++
+		case outcomeRefused:
+			refused++
+			latencies = append(latencies, s.latency)
+		case outcomeExhausted:
+			exhausted++
+		case outcomeOK:
+			latencies = append(latencies, s.latency)
+		}
+	}
+
+	n := len(c.samples)
+	timeoutRatio := float64(timeouts) / float64(n)
+	refusedRatio := float64(refused) / float64(n)
+
+	switch {
+	case exhausted > 0 || timeoutRatio > 0.3:
+		// Too-fast-for-the-wire or descriptor pressure: back off hard.
+		c.limit = max(c.limit/2, minInFlight)
+		c.timeout = minDur(c.timeout+100*time.Millisecond, maxTimeout)
+
+	case refusedRatio > 0.5 && len(latencies) > 0:
+		// Host is alive and responding quickly: shrink the timeout toward
+		// the observed p95 latency and allow a little more concurrency.
+		p95 := percentile(latencies, 0.95)
+		target := p95 + p95/2
+		c.timeout = clampDur(target, minTimeout, c.timeout)
+		c.limit = min(c.limit+c.limit/10+1, maxInFlight)
+
+	default:
+		// Steady state: creep concurrency up.
+		c.limit = min(c.limit+c.limit/20+1, maxInFlight)
+	}
+}
+
+func percentile(d []time.Duration, p float64) time.Duration {
+	sorted := append([]time.Duration(nil), d...)
+	sort.Slice(sorted, func(i, j int) bool { return sorted[i] < sorted[j] })
+	idx := int(p * float64(len(sorted)-1))
+	return sorted[idx]
+}
+
+func minDur(a, b time.Duration) time.Duration {
+	if a < b {
+		return a
+	}
+	return b
+}
+
+func clampDur(v, lo, hi time.Duration) time.Duration {
+	if v < lo {
+		return lo
+	}
+	if v > hi {
+		return hi
+	}
+	return v
+}
+
+func max(a, b int) int {
+	if a > b {
+		return a
+	}
+	return b
+}
+
+func min(a, b int) int {
+	if a < b {
+		return a
+	}
+	return b
+}
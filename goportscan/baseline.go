@@ -0,0 +1,132 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"sort"
+)
+
+// baselineSnapshot is the open-port surface for a set of hosts, built either
+// from a previous scan's -baseline file or from the current scan's own
+// results, so the two can be compared with the same diffBaseline logic.
+type baselineSnapshot map[string]map[int]bool
+
+// loadBaseline reads a previous scan's JSON output (the format -o json and
+// -out-file write: one ScanResult object after another, not wrapped in an
+// array) and returns the set of ports it reported open per host. Only open
+// ports matter for drift detection -- a closed or filtered port isn't part
+// of the exposure surface -baseline is trying to track.
+func loadBaseline(path string) (baselineSnapshot, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	snapshot := baselineSnapshot{}
+	dec := json.NewDecoder(f)
+	for {
+		var r ScanResult
+		if err := dec.Decode(&r); err != nil {
+			if err == io.EOF {
+				break
+			}
+			return nil, fmt.Errorf("parsing %s: %w", path, err)
+		}
+		if r.State != string(portOpen) {
+			continue
+		}
+		recordOpenPort(snapshot, r.Host, r.Port)
+	}
+	return snapshot, nil
+}
+
+// recordOpenPort marks host:port as open in snapshot, creating the host's
+// port set on first use.
+func recordOpenPort(snapshot baselineSnapshot, host string, port int) {
+	ports, ok := snapshot[host]
+	if !ok {
+		ports = map[int]bool{}
+		snapshot[host] = ports
+	}
+	ports[port] = true
+}
+
+// baselineDiff is one host's exposure change between a baseline and the
+// current scan, each slice sorted ascending.
+type baselineDiff struct {
+	Host        string `json:"host"`
+	NewlyOpen   []int  `json:"newlyOpen,omitempty"`
+	NewlyClosed []int  `json:"newlyClosed,omitempty"`
+	Unchanged   []int  `json:"unchanged,omitempty"`
+}
+
+// hasDrift reports whether this host's open-port set changed at all.
+func (d baselineDiff) hasDrift() bool {
+	return len(d.NewlyOpen) > 0 || len(d.NewlyClosed) > 0
+}
+
+// diffBaseline compares a previous scan's open ports against the current
+// scan's, host by host, and reports what's newly open, newly closed, and
+// unchanged. Hosts are reported in the same order sortOutcomes would put
+// them in: every host present in either snapshot, sorted by name.
+func diffBaseline(old, current baselineSnapshot) []baselineDiff {
+	hosts := map[string]bool{}
+	for host := range old {
+		hosts[host] = true
+	}
+	for host := range current {
+		hosts[host] = true
+	}
+	sortedHosts := make([]string, 0, len(hosts))
+	for host := range hosts {
+		sortedHosts = append(sortedHosts, host)
+	}
+	sort.Strings(sortedHosts)
+
+	diffs := make([]baselineDiff, 0, len(sortedHosts))
+	for _, host := range sortedHosts {
+		oldPorts, curPorts := old[host], current[host]
+		d := baselineDiff{Host: host}
+		for port := range curPorts {
+			if oldPorts[port] {
+				d.Unchanged = append(d.Unchanged, port)
+			} else {
+				d.NewlyOpen = append(d.NewlyOpen, port)
+			}
+		}
+		for port := range oldPorts {
+			if !curPorts[port] {
+				d.NewlyClosed = append(d.NewlyClosed, port)
+			}
+		}
+		sort.Ints(d.NewlyOpen)
+		sort.Ints(d.NewlyClosed)
+		sort.Ints(d.Unchanged)
+		diffs = append(diffs, d)
+	}
+	return diffs
+}
+
+// printBaselineDiff writes diffs to w in the same per-host, human-readable
+// style the rest of main's end-of-scan reporting uses, and reports whether
+// any host drifted so the caller can choose a distinct exit code for it.
+func printBaselineDiff(w io.Writer, diffs []baselineDiff) bool {
+	fmt.Fprintln(w, "\nBaseline diff:")
+	drift := false
+	for _, d := range diffs {
+		if d.hasDrift() {
+			drift = true
+		}
+		fmt.Fprintf(w, "  %s: %d newly open %v, %d newly closed %v, %d unchanged %v\n",
+			d.Host, len(d.NewlyOpen), d.NewlyOpen, len(d.NewlyClosed), d.NewlyClosed, len(d.Unchanged), d.Unchanged)
+	}
+	if drift {
+		fmt.Fprintln(w, "Drift detected against baseline")
+	} else {
+		fmt.Fprintln(w, "No drift against baseline")
+	}
+	return drift
+}
@@ -0,0 +1,126 @@
+package main
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestLoadBaseline_KeepsOnlyOpenPortsPerHost(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "baseline.json")
+	contents := `{"host":"10.0.0.1","port":22,"state":"open"}
+{"host":"10.0.0.1","port":80,"state":"closed"}
+{"host":"10.0.0.1","port":443,"state":"open"}
+{"host":"10.0.0.2","port":22,"state":"open"}
+`
+	if err := os.WriteFile(path, []byte(contents), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	snapshot, err := loadBaseline(path)
+	if err != nil {
+		t.Fatalf("loadBaseline: %v", err)
+	}
+	want := baselineSnapshot{
+		"10.0.0.1": {22: true, 443: true},
+		"10.0.0.2": {22: true},
+	}
+	if len(snapshot) != len(want) {
+		t.Fatalf("snapshot = %+v, want %+v", snapshot, want)
+	}
+	for host, ports := range want {
+		for port := range ports {
+			if !snapshot[host][port] {
+				t.Errorf("snapshot[%q][%d] = false, want true", host, port)
+			}
+		}
+	}
+}
+
+func TestLoadBaseline_MissingFileErrors(t *testing.T) {
+	if _, err := loadBaseline(filepath.Join(t.TempDir(), "nope.json")); err == nil {
+		t.Error("loadBaseline: want an error for a missing file")
+	}
+}
+
+func TestDiffBaseline_ClassifiesOpenNewlyOpenNewlyClosedAndUnchanged(t *testing.T) {
+	old := baselineSnapshot{
+		"10.0.0.1": {22: true, 8080: true},
+		"10.0.0.2": {22: true},
+	}
+	current := baselineSnapshot{
+		"10.0.0.1": {22: true, 443: true},
+		"10.0.0.3": {22: true},
+	}
+
+	diffs := diffBaseline(old, current)
+	byHost := map[string]baselineDiff{}
+	for _, d := range diffs {
+		byHost[d.Host] = d
+	}
+
+	h1 := byHost["10.0.0.1"]
+	if got, want := h1.NewlyOpen, []int{443}; !intSliceEqual(got, want) {
+		t.Errorf("10.0.0.1 NewlyOpen = %v, want %v", got, want)
+	}
+	if got, want := h1.NewlyClosed, []int{8080}; !intSliceEqual(got, want) {
+		t.Errorf("10.0.0.1 NewlyClosed = %v, want %v", got, want)
+	}
+	if got, want := h1.Unchanged, []int{22}; !intSliceEqual(got, want) {
+		t.Errorf("10.0.0.1 Unchanged = %v, want %v", got, want)
+	}
+
+	h2 := byHost["10.0.0.2"]
+	if got, want := h2.NewlyClosed, []int{22}; !intSliceEqual(got, want) {
+		t.Errorf("10.0.0.2 NewlyClosed = %v, want %v", got, want)
+	}
+	if !h2.hasDrift() {
+		t.Error("10.0.0.2: want hasDrift() = true after its only open port closed")
+	}
+
+	h3 := byHost["10.0.0.3"]
+	if got, want := h3.NewlyOpen, []int{22}; !intSliceEqual(got, want) {
+		t.Errorf("10.0.0.3 NewlyOpen = %v, want %v", got, want)
+	}
+}
+
+func TestDiffBaseline_NoChangesMeansNoDrift(t *testing.T) {
+	snapshot := baselineSnapshot{"10.0.0.1": {22: true}}
+	diffs := diffBaseline(snapshot, baselineSnapshot{"10.0.0.1": {22: true}})
+	if len(diffs) != 1 || diffs[0].hasDrift() {
+		t.Errorf("diffs = %+v, want one host with no drift", diffs)
+	}
+}
+
+func TestPrintBaselineDiff_ReturnsTrueOnlyWhenSomethingDrifted(t *testing.T) {
+	var buf bytes.Buffer
+	drifted := printBaselineDiff(&buf, []baselineDiff{{Host: "10.0.0.1", NewlyOpen: []int{443}}})
+	if !drifted {
+		t.Error("printBaselineDiff: want true when a host has newly-open ports")
+	}
+	if !bytes.Contains(buf.Bytes(), []byte("Drift detected against baseline")) {
+		t.Errorf("output = %q, want a drift-detected line", buf.String())
+	}
+
+	buf.Reset()
+	drifted = printBaselineDiff(&buf, []baselineDiff{{Host: "10.0.0.1", Unchanged: []int{22}}})
+	if drifted {
+		t.Error("printBaselineDiff: want false when no host drifted")
+	}
+	if !bytes.Contains(buf.Bytes(), []byte("No drift against baseline")) {
+		t.Errorf("output = %q, want a no-drift line", buf.String())
+	}
+}
+
+func intSliceEqual(a, b []int) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if a[i] != b[i] {
+			return false
+		}
+	}
+	return true
+}
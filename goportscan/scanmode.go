@@ -0,0 +1,110 @@
+package main
+
+import (
+	"fmt"
+	"net"
+	"time"
+)
+
+// scanMode selects how a port is probed.
+type scanMode string
+
+const (
+	scanModeConnect scanMode = "connect"
+	scanModeSYN     scanMode = "syn"
+	scanModeUDP     scanMode = "udp"
+)
+
+func parseScanMode(s string) (scanMode, error) {
+	switch scanMode(s) {
+	case "", scanModeConnect:
+		return scanModeConnect, nil
+	case scanModeSYN:
+		return scanModeSYN, nil
+	case scanModeUDP:
+		return scanModeUDP, nil
+	default:
+		return "", fmt.Errorf("unknown scan type %q (want connect, syn, or udp)", s)
+	}
+}
+
+// synProber is implemented by the platform-specific half-open scanner. On
+// platforms/privilege levels where raw sockets aren't available,
+// newSYNProber returns a nil synProber and the caller falls back to a
+// connect scan.
+type synProber interface {
+	// Probe sends a SYN to addr and reports whether the port answered open
+	// (SYN-ACK), closed (RST), or filtered (no reply within timeout).
+	Probe(addr Addr, timeout time.Duration) (open bool, err error)
+	Close() error
+}
+
+// connectProbe is the original full TCP handshake probe, reused as the
+// "connect" scan mode and as the fallback for syn when raw sockets aren't
+// available. UDP mode always uses udpProbe.
+func connectProbe(addr Addr, timeout time.Duration) (bool, error) {
+	address := fmt.Sprintf("%s:%d", addr.IP.String(), addr.Port)
+	conn, err := net.DialTimeout("tcp", address, timeout)
+	if err != nil {
+		return false, err
+	}
+	conn.Close()
+	return true, nil
+}
+
+// udpProbe sends a protocol-appropriate (or empty) UDP payload and
+// classifies the result: a reply or silence is reported as open (since
+// plenty of UDP services never answer an unsolicited probe), while an ICMP
+// port-unreachable is reported as closed.
+func udpProbe(addr Addr, timeout time.Duration) (bool, error) {
+	address := fmt.Sprintf("%s:%d", addr.IP.String(), addr.Port)
+	conn, err := net.DialTimeout("udp", address, timeout)
+	if err != nil {
+		return false, err
+	}
+	defer conn.Close()
+
+	payload := udpPayloadFor(addr.Port)
+	if _, err := conn.Write(payload); err != nil {
+		return false, err
+	}
+
+	conn.SetReadDeadline(time.Now().Add(timeout))
+	buf := make([]byte, 512)
+	_, err = conn.Read(buf)
+	if err == nil {
+		return true, nil // got a reply: definitely open
+	}
+	if isICMPPortUnreachable(err) {
+		return false, nil // closed
+	}
+	// No reply within the deadline: open|filtered, treated as open.
+	return true, nil
+}
+
+// udpPayloadFor returns a protocol-specific probe payload for well-known
+// UDP services, or an empty payload otherwise.
+func udpPayloadFor(port int) []byte {
+	switch port {
+	case 53: // minimal DNS query for "."
+		return []byte{
+			0x00, 0x00, 0x01, 0x00, 0x00, 0x01, 0x00, 0x00,
+			0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x01, 0x00,
+			0x01,
+		}
+	case 161: // SNMP GetRequest for sysDescr.0, community "public"
+		return []byte{
+			0x30, 0x26, 0x02, 0x01, 0x00, 0x04, 0x06, 'p', 'u', 'b', 'l', 'i', 'c',
+			0xa0, 0x19, 0x02, 0x01, 0x01, 0x02, 0x01, 0x00, 0x02, 0x01, 0x00,
+			0x30, 0x0e, 0x30, 0x0c, 0x06, 0x08, 0x2b, 0x06, 0x01, 0x02, 0x01, 0x01, 0x01, 0x00, 0x05, 0x00,
+		}
+	default:
+		return []byte{}
+	}
+}
+
+// isICMPPortUnreachable reports whether err represents an ICMP destination
+// unreachable (port unreachable) response to a UDP datagram.
+func isICMPPortUnreachable(err error) bool {
+	return isConnRefused(err)
+}
@@ -0,0 +1,113 @@
+package main
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"net"
+	"os"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// checkpointFlushInterval is how often a checkpointWriter flushes its
+// buffered entries to disk between probes, bounding how much progress a
+// crash (as opposed to a clean Ctrl-C, which flushes explicitly) can lose.
+const checkpointFlushInterval = 5 * time.Second
+
+// checkpointEntry is one line of a -checkpoint file: a single host:port
+// probe that has been completed (dialed and classified), regardless of
+// whether its state ended up reported in the scan's own output.
+type checkpointEntry struct {
+	Host string `json:"host"`
+	Port int    `json:"port"`
+}
+
+// checkpointKey is the string a checkpoint entry and a live Addr are
+// compared by: the same host-or-IP display string expandTargets and the
+// output sinks already use, so a resumed scan recognizes the exact
+// addresses it reported on last time.
+func checkpointKey(addr Addr) string {
+	return net.JoinHostPort(addr.Display(), strconv.Itoa(addr.Port))
+}
+
+// checkpointWriter appends completed probes to a -checkpoint file as JSON
+// lines, buffered and flushed periodically (see checkpointFlushInterval)
+// rather than on every Record, since a large scan can complete thousands of
+// probes a second and fsyncing each one would throttle the scan to disk
+// speed. Close flushes and syncs before returning, so callers that shut
+// down explicitly on Ctrl-C (see main) don't lose the last few seconds of
+// progress.
+type checkpointWriter struct {
+	mu  sync.Mutex
+	f   *os.File
+	w   *bufio.Writer
+	enc *json.Encoder
+}
+
+func newCheckpointWriter(path string) (*checkpointWriter, error) {
+	f, err := os.OpenFile(path, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0o644)
+	if err != nil {
+		return nil, fmt.Errorf("checkpoint: %w", err)
+	}
+	w := bufio.NewWriter(f)
+	return &checkpointWriter{f: f, w: w, enc: json.NewEncoder(w)}, nil
+}
+
+func (c *checkpointWriter) Record(addr Addr) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.enc.Encode(checkpointEntry{Host: addr.Display(), Port: addr.Port})
+}
+
+func (c *checkpointWriter) Flush() error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if err := c.w.Flush(); err != nil {
+		return err
+	}
+	return c.f.Sync()
+}
+
+func (c *checkpointWriter) Close() error {
+	flushErr := c.Flush()
+	closeErr := c.f.Close()
+	if flushErr != nil {
+		return flushErr
+	}
+	return closeErr
+}
+
+// loadCheckpoint reads a -checkpoint file (as written by checkpointWriter)
+// back into the set of host:port keys -resume should skip. A line that
+// doesn't parse as JSON -- the tail of a file truncated mid-write by a
+// crash, say -- is reported and skipped rather than failing the whole
+// resume, since every earlier line is still good progress worth keeping.
+func loadCheckpoint(path string) (map[string]bool, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, fmt.Errorf("resume: %w", err)
+	}
+	defer f.Close()
+
+	done := map[string]bool{}
+	scanner := bufio.NewScanner(f)
+	for lineNum := 1; scanner.Scan(); lineNum++ {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" {
+			continue
+		}
+		var entry checkpointEntry
+		if err := json.Unmarshal([]byte(line), &entry); err != nil {
+			fmt.Fprintf(os.Stderr, "warning: %s:%d: malformed checkpoint entry, skipping: %v\n", path, lineNum, err)
+			continue
+		}
+		done[net.JoinHostPort(entry.Host, strconv.Itoa(entry.Port))] = true
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("resume: %w", err)
+	}
+	return done, nil
+}
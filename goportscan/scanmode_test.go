@@ -0,0 +1,45 @@
+package main
+
+import "testing"
+
+func TestParseScanMode(t *testing.T) {
+	cases := []struct {
+		in      string
+		want    scanMode
+		wantErr bool
+	}{
+		{"", scanModeConnect, false},
+		{"connect", scanModeConnect, false},
+		{"syn", scanModeSYN, false},
+		{"udp", scanModeUDP, false},
+		{"bogus", "", true},
+	}
+	for _, c := range cases {
+		got, err := parseScanMode(c.in)
+		if c.wantErr {
+			if err == nil {
+				t.Errorf("parseScanMode(%q): expected error, got none", c.in)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("parseScanMode(%q): unexpected error: %v", c.in, err)
+			continue
+		}
+		if got != c.want {
+			t.Errorf("parseScanMode(%q) = %q, want %q", c.in, got, c.want)
+		}
+	}
+}
+
+func TestUDPPayloadFor(t *testing.T) {
+	if len(udpPayloadFor(53)) == 0 {
+		t.Error("udpPayloadFor(53): want a non-empty DNS query payload")
+	}
+	if len(udpPayloadFor(161)) == 0 {
+		t.Error("udpPayloadFor(161): want a non-empty SNMP payload")
+	}
+	if got := udpPayloadFor(12345); len(got) != 0 {
+		t.Errorf("udpPayloadFor(12345) = %v, want empty payload for an unrecognized port", got)
+	}
+}
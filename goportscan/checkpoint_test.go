@@ -0,0 +1,104 @@
+package main
+
+import (
+	"net"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestCheckpointKey(t *testing.T) {
+	got := checkpointKey(Addr{IP: net.ParseIP("10.0.0.1"), Port: 22})
+	if want := "10.0.0.1:22"; got != want {
+		t.Errorf("checkpointKey = %q, want %q", got, want)
+	}
+}
+
+func TestCheckpointWriter_RoundTripsThroughLoadCheckpoint(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "checkpoint.jsonl")
+	w, err := newCheckpointWriter(path)
+	if err != nil {
+		t.Fatalf("newCheckpointWriter: %v", err)
+	}
+
+	addrs := []Addr{
+		{IP: net.ParseIP("10.0.0.1"), Port: 22},
+		{IP: net.ParseIP("10.0.0.1"), Port: 80},
+		{Host: "web-01.internal", Port: 443},
+	}
+	for _, addr := range addrs {
+		if err := w.Record(addr); err != nil {
+			t.Fatalf("Record: %v", err)
+		}
+	}
+	if err := w.Close(); err != nil {
+		t.Fatalf("Close: %v", err)
+	}
+
+	done, err := loadCheckpoint(path)
+	if err != nil {
+		t.Fatalf("loadCheckpoint: %v", err)
+	}
+	for _, addr := range addrs {
+		if !done[checkpointKey(addr)] {
+			t.Errorf("loadCheckpoint missing entry for %s", checkpointKey(addr))
+		}
+	}
+	if len(done) != len(addrs) {
+		t.Errorf("loadCheckpoint returned %d entries, want %d", len(done), len(addrs))
+	}
+}
+
+func TestCheckpointWriter_AppendsAcrossRuns(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "checkpoint.jsonl")
+	w1, err := newCheckpointWriter(path)
+	if err != nil {
+		t.Fatalf("newCheckpointWriter: %v", err)
+	}
+	w1.Record(Addr{IP: net.ParseIP("10.0.0.1"), Port: 22})
+	if err := w1.Close(); err != nil {
+		t.Fatalf("Close: %v", err)
+	}
+
+	w2, err := newCheckpointWriter(path)
+	if err != nil {
+		t.Fatalf("newCheckpointWriter (second run): %v", err)
+	}
+	w2.Record(Addr{IP: net.ParseIP("10.0.0.1"), Port: 80})
+	if err := w2.Close(); err != nil {
+		t.Fatalf("Close: %v", err)
+	}
+
+	done, err := loadCheckpoint(path)
+	if err != nil {
+		t.Fatalf("loadCheckpoint: %v", err)
+	}
+	if len(done) != 2 {
+		t.Errorf("loadCheckpoint returned %d entries, want 2 (one from each run)", len(done))
+	}
+}
+
+func TestLoadCheckpoint_SkipsMalformedLinesButKeepsTheRest(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "checkpoint.jsonl")
+	content := `{"host":"10.0.0.1","port":22}` + "\n" + "not json" + "\n" + `{"host":"10.0.0.1","port":80}` + "\n"
+	if err := os.WriteFile(path, []byte(content), 0o644); err != nil {
+		t.Fatalf("WriteFile: %v", err)
+	}
+
+	done, err := loadCheckpoint(path)
+	if err != nil {
+		t.Fatalf("loadCheckpoint: %v", err)
+	}
+	if len(done) != 2 {
+		t.Errorf("loadCheckpoint returned %d entries, want 2 (malformed line skipped)", len(done))
+	}
+	if !done["10.0.0.1:22"] || !done["10.0.0.1:80"] {
+		t.Errorf("loadCheckpoint = %v, missing expected entries", done)
+	}
+}
+
+func TestLoadCheckpoint_MissingFileErrors(t *testing.T) {
+	if _, err := loadCheckpoint(filepath.Join(t.TempDir(), "nope.jsonl")); err == nil {
+		t.Error("loadCheckpoint on a missing file: expected an error, got nil")
+	}
+}
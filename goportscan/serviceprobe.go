@@ -0,0 +1,255 @@
+package main
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"net"
+	"regexp"
+	"strings"
+	"time"
+)
+
+// ServiceInfo describes what a ServiceProbe managed to learn about the
+// service listening on a port.
+type ServiceInfo struct {
+	Name    string // e.g. "ssh", "http"
+	Version string // e.g. "OpenSSH_8.9p1", empty if unknown
+	Banner  string // raw first bytes read, trimmed
+}
+
+// serviceProbe sends a protocol-specific probe and parses the response into
+// a ServiceInfo. It returns false if nothing useful could be read.
+type serviceProbe func(conn net.Conn) (ServiceInfo, bool)
+
+// probeReadTimeout bounds how long a probe waits for a response before
+// giving up on identifying the service.
+const probeReadTimeout = 1 * time.Second
+
+// portProbes maps well-known ports to the probe most likely to identify the
+// service listening there. When the port isn't listed here, or its probe
+// finds nothing, every probe in probeRegistry is tried at once and the
+// earliest-listed match (cheapest/most common first) wins.
+var portProbes = map[int]serviceProbe{
+	21:   probeBanner,
+	22:   probeSSH,
+	25:   probeBanner,
+	80:   probeHTTP,
+	110:  probeBanner,
+	443:  probeTLS,
+	445:  probeSMB,
+	587:  probeBanner,
+	8080: probeHTTP,
+}
+
+// probeRegistry is the ordered fallback list used when a port has no
+// dedicated entry in portProbes.
+var probeRegistry = []serviceProbe{
+	probeBanner,
+	probeHTTP,
+	probeSSH,
+	probeTLS,
+	probeSMB,
+}
+
+// identifyService dials addr and runs the best-matching probe(s) against it,
+// returning whatever ServiceInfo could be determined.
+func identifyService(addr Addr, timeout time.Duration) ServiceInfo {
+	address := fmt.Sprintf("%s:%d", addr.IP.String(), addr.Port)
+	conn, err := net.DialTimeout("tcp", address, timeout)
+	if err != nil {
+		return ServiceInfo{}
+	}
+
+	if probe, ok := portProbes[addr.Port]; ok {
+		conn.SetDeadline(time.Now().Add(probeReadTimeout))
+		if info, ok := probe(conn); ok {
+			conn.Close()
+			return info
+		}
+	}
+	conn.Close()
+
+	return probeFallback(address, probeReadTimeout)
+}
+
+// probeFallback runs every probe in probeRegistry against its own freshly
+// dialed connection, all at once, under a single shared deadline. Trying the
+// probes one after another (each waiting out its own deadline before the
+// next starts) could take len(probeRegistry)*probeReadTimeout per port --
+// on a large scan, where most open ports match nothing in portProbes, that
+// serial cost dominates the whole run. Running them concurrently bounds the
+// wall-clock cost of identifying an unrecognized port to a single
+// probeReadTimeout, however many probes are registered.
+func probeFallback(address string, timeout time.Duration) ServiceInfo {
+	type result struct {
+		idx  int
+		info ServiceInfo
+		ok   bool
+	}
+
+	results := make(chan result, len(probeRegistry))
+	for i, probe := range probeRegistry {
+		go func(i int, probe serviceProbe) {
+			conn, err := net.DialTimeout("tcp", address, timeout)
+			if err != nil {
+				results <- result{idx: i}
+				return
+			}
+			defer conn.Close()
+			conn.SetDeadline(time.Now().Add(timeout))
+			info, ok := probe(conn)
+			results <- result{idx: i, info: info, ok: ok}
+		}(i, probe)
+	}
+
+	// Preserve probeRegistry's priority order (the first probe to match
+	// wins) while still bounding total wait to one shared deadline.
+	deadline := time.After(timeout)
+	best := -1
+	var bestInfo ServiceInfo
+	for range probeRegistry {
+		select {
+		case r := <-results:
+			if r.ok && (best == -1 || r.idx < best) {
+				best = r.idx
+				bestInfo = r.info
+			}
+		case <-deadline:
+			return bestInfo
+		}
+	}
+	return bestInfo
+}
+
+// probeBanner passively reads whatever the service sends immediately after
+// connect, which is enough to identify FTP, SMTP, POP3 and SSH banners.
+func probeBanner(conn net.Conn) (ServiceInfo, bool) {
+	conn.SetReadDeadline(time.Now().Add(probeReadTimeout))
+	reader := bufio.NewReader(conn)
+	line, err := reader.ReadString('\n')
+	line = strings.TrimSpace(line)
+	if err != nil || line == "" {
+		return ServiceInfo{}, false
+	}
+	return parseBanner(line), true
+}
+
+// probeSSH actively reads the SSH version exchange line, e.g.
+// "SSH-2.0-OpenSSH_8.9p1 Ubuntu-3ubuntu0.4".
+func probeSSH(conn net.Conn) (ServiceInfo, bool) {
+	conn.SetReadDeadline(time.Now().Add(probeReadTimeout))
+	reader := bufio.NewReader(conn)
+	line, err := reader.ReadString('\n')
+	line = strings.TrimSpace(line)
+	if err != nil || !strings.HasPrefix(line, "SSH-") {
+		return ServiceInfo{}, false
+	}
+	return parseBanner(line), true
+}
+
+// probeHTTP sends a minimal HTTP/1.0 GET and reads back the status line and
+// Server header, if any.
+func probeHTTP(conn net.Conn) (ServiceInfo, bool) {
+	conn.SetDeadline(time.Now().Add(probeReadTimeout))
+	if _, err := conn.Write([]byte("GET / HTTP/1.0\r\nHost: scan\r\n\r\n")); err != nil {
+		return ServiceInfo{}, false
+	}
+
+	reader := bufio.NewReader(conn)
+	status, err := reader.ReadString('\n')
+	if err != nil || !strings.HasPrefix(status, "HTTP/") {
+		return ServiceInfo{}, false
+	}
+
+	info := ServiceInfo{Name: "http", Banner: strings.TrimSpace(status)}
+	for {
+		line, err := reader.ReadString('\n')
+		line = strings.TrimSpace(line)
+		if err != nil || line == "" {
+			break
+		}
+		if strings.HasPrefix(strings.ToLower(line), "server:") {
+			info.Version = strings.TrimSpace(line[len("server:"):])
+			break
+		}
+	}
+	return info, true
+}
+
+// probeTLS sends a minimal TLS ClientHello and checks for a TLS handshake or
+// alert record in response, which is enough to flag the port as running TLS
+// without doing a full handshake.
+func probeTLS(conn net.Conn) (ServiceInfo, bool) {
+	conn.SetDeadline(time.Now().Add(probeReadTimeout))
+	clientHello := []byte{
+		0x16, 0x03, 0x01, 0x00, 0x05, // TLS record header (handshake, TLS1.0, len=5)
+		0x01, 0x00, 0x00, 0x01, 0x00, // truncated ClientHello, enough to elicit an alert/ServerHello
+	}
+	if _, err := conn.Write(clientHello); err != nil {
+		return ServiceInfo{}, false
+	}
+
+	reply := make([]byte, 5)
+	if _, err := io.ReadFull(conn, reply); err != nil {
+		return ServiceInfo{}, false
+	}
+	if reply[0] != 0x16 && reply[0] != 0x15 { // handshake or alert record
+		return ServiceInfo{}, false
+	}
+	return ServiceInfo{Name: "ssl/tls"}, true
+}
+
+// probeSMB sends an SMB negotiate-protocol request and checks for a valid
+// SMB response header (\xffSMB).
+func probeSMB(conn net.Conn) (ServiceInfo, bool) {
+	conn.SetDeadline(time.Now().Add(probeReadTimeout))
+	negotiate := []byte{
+		0x00, 0x00, 0x00, 0x2f, // NetBIOS session header, length
+		0xff, 'S', 'M', 'B', 0x72, // SMB header, command=negotiate
+		0x00, 0x00, 0x00, 0x00, // status
+		0x18, 0x01, 0x28, // flags, flags2
+		0x00, 0x00, // PID high
+		0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, // signature
+		0x00, 0x00, // reserved
+		0x00, 0x00, 0xff, 0xfe, // TID, PID
+		0x00, 0x00, 0x00, 0x00, // UID, MID
+		0x00, 0x0c, 0x00, // word count 0, byte count 12
+		0x02, 'N', 'T', ' ', 'L', 'M', ' ', '0', '.', '1', '2', 0x00, // dialect "NT LM 0.12"
+	}
+	if _, err := conn.Write(negotiate); err != nil {
+		return ServiceInfo{}, false
+	}
+
+	reply := make([]byte, 8)
+	if _, err := io.ReadFull(conn, reply); err != nil {
+		return ServiceInfo{}, false
+	}
+	if string(reply[4:8]) != "\xffSMB" {
+		return ServiceInfo{}, false
+	}
+	return ServiceInfo{Name: "microsoft-ds"}, true
+}
+
+var (
+	sshBannerRE  = regexp.MustCompile(`^SSH-[\d.]+-(\S+)`)
+	ftpBannerRE  = regexp.MustCompile(`^220[- ](.*)`)
+	smtpBannerRE = regexp.MustCompile(`^220[- ](.*ESMTP.*|.*SMTP.*)`)
+	pop3BannerRE = regexp.MustCompile(`^\+OK (.*)`)
+)
+
+// parseBanner turns a raw banner line into a best-effort ServiceInfo guess.
+func parseBanner(line string) ServiceInfo {
+	switch {
+	case sshBannerRE.MatchString(line):
+		return ServiceInfo{Name: "ssh", Version: sshBannerRE.FindStringSubmatch(line)[1], Banner: line}
+	case smtpBannerRE.MatchString(line):
+		return ServiceInfo{Name: "smtp", Version: strings.TrimSpace(smtpBannerRE.FindStringSubmatch(line)[1]), Banner: line}
+	case ftpBannerRE.MatchString(line):
+		return ServiceInfo{Name: "ftp", Version: strings.TrimSpace(ftpBannerRE.FindStringSubmatch(line)[1]), Banner: line}
+	case pop3BannerRE.MatchString(line):
+		return ServiceInfo{Name: "pop3", Version: strings.TrimSpace(pop3BannerRE.FindStringSubmatch(line)[1]), Banner: line}
+	default:
+		return ServiceInfo{Banner: line}
+	}
+}
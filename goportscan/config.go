@@ -0,0 +1,91 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"sort"
+	"strings"
+	"time"
+
+	"gopkg.in/yaml.v3"
+)
+
+// scanProfile is one named profile from a -config file: a predefined bundle
+// of target/port/timing/output settings a team can check into version
+// control and select with -profile, instead of retyping the same flags for
+// every recurring scan. Every scalar field is a pointer so loadConfigProfile
+// can tell "not set in the file" apart from "set to the type's zero value"
+// -- only the former should ever yield to a flag's own built-in default.
+type scanProfile struct {
+	Targets        []string       `yaml:"targets"`
+	Ports          *string        `yaml:"ports"`
+	StartPort      *int           `yaml:"start-port"`
+	EndPort        *int           `yaml:"end-port"`
+	TopPorts       *int           `yaml:"top-ports"`
+	Workers        *int           `yaml:"workers"`
+	Timeout        *time.Duration `yaml:"timeout"`
+	MaxConcurrency *int           `yaml:"max-concurrency"`
+	Retries        *int           `yaml:"retries"`
+	Output         *string        `yaml:"output"`
+	OutFile        *string        `yaml:"out-file"`
+	Randomize      *bool          `yaml:"randomize"`
+	Stream         *bool          `yaml:"stream"`
+}
+
+// configFile is the top-level shape of a -config YAML file: one or more
+// named profiles, selected with -profile.
+type configFile struct {
+	Profiles map[string]scanProfile `yaml:"profiles"`
+}
+
+// loadConfigProfile reads path as YAML and returns the profile named
+// profileName. Any key the file defines that this struct doesn't recognize
+// is reported as an error rather than silently ignored, so a typo'd flag
+// name in a checked-in config fails loudly instead of quietly not doing
+// what its author intended.
+//
+// An empty profileName is only valid when the file defines exactly one
+// profile, or one named "default"; otherwise the caller must pass -profile.
+func loadConfigProfile(path, profileName string) (scanProfile, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return scanProfile{}, err
+	}
+	defer f.Close()
+
+	dec := yaml.NewDecoder(f)
+	dec.KnownFields(true)
+	var cfg configFile
+	if err := dec.Decode(&cfg); err != nil {
+		return scanProfile{}, fmt.Errorf("parsing %s: %w", path, err)
+	}
+
+	if profileName == "" {
+		if p, ok := cfg.Profiles["default"]; ok {
+			return p, nil
+		}
+		if len(cfg.Profiles) == 1 {
+			for _, p := range cfg.Profiles {
+				return p, nil
+			}
+		}
+		return scanProfile{}, fmt.Errorf("%s defines %d profile(s); pass -profile to pick one (%s)", path, len(cfg.Profiles), profileNames(cfg.Profiles))
+	}
+
+	p, ok := cfg.Profiles[profileName]
+	if !ok {
+		return scanProfile{}, fmt.Errorf("%s has no profile named %q (have: %s)", path, profileName, profileNames(cfg.Profiles))
+	}
+	return p, nil
+}
+
+// profileNames renders a config file's profile names sorted and
+// comma-joined, for error messages that list what was actually available.
+func profileNames(profiles map[string]scanProfile) string {
+	names := make([]string, 0, len(profiles))
+	for name := range profiles {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return strings.Join(names, ", ")
+}
@@ -0,0 +1,68 @@
+package main
+
+import (
+	"net"
+	"testing"
+	"time"
+)
+
+func TestParseBanner(t *testing.T) {
+	cases := []struct {
+		line        string
+		wantName    string
+		wantVersion string
+	}{
+		{"SSH-2.0-OpenSSH_8.9p1 Ubuntu-3ubuntu0.4", "ssh", "OpenSSH_8.9p1"},
+		{"220 mail.example.com ESMTP Postfix", "smtp", "mail.example.com ESMTP Postfix"},
+		{"220 ftp.example.com FTP server ready", "ftp", "ftp.example.com FTP server ready"},
+		{"+OK Dovecot ready.", "pop3", "Dovecot ready."},
+		{"not a recognized banner", "", ""},
+	}
+	for _, c := range cases {
+		info := parseBanner(c.line)
+		if info.Name != c.wantName {
+			t.Errorf("parseBanner(%q).Name = %q, want %q", c.line, info.Name, c.wantName)
+		}
+		if info.Version != c.wantVersion {
+			t.Errorf("parseBanner(%q).Version = %q, want %q", c.line, info.Version, c.wantVersion)
+		}
+		if info.Banner != c.line {
+			t.Errorf("parseBanner(%q).Banner = %q, want original line", c.line, info.Banner)
+		}
+	}
+}
+
+// TestIdentifyServiceFallbackIsBoundedBySingleDeadline guards against the
+// fallback registry regressing to its old behavior of trying each probe in
+// series, which could take len(probeRegistry)*probeReadTimeout for a single
+// unidentified port.
+func TestIdentifyServiceFallbackIsBoundedBySingleDeadline(t *testing.T) {
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("listen: %v", err)
+	}
+	defer ln.Close()
+	go func() {
+		for {
+			conn, err := ln.Accept()
+			if err != nil {
+				return
+			}
+			// Accept but never write anything back, to exercise the
+			// fallback path (no probe will get a match).
+			go func() {
+				buf := make([]byte, 1024)
+				conn.Read(buf)
+			}()
+		}
+	}()
+
+	port := ln.Addr().(*net.TCPAddr).Port
+	addr := Addr{IP: net.ParseIP("127.0.0.1"), Port: port}
+
+	start := time.Now()
+	identifyService(addr, 2*time.Second)
+	if elapsed := time.Since(start); elapsed > 2*probeReadTimeout {
+		t.Fatalf("identifyService took %s, want well under %s (serial fallback regression)", elapsed, 2*probeReadTimeout)
+	}
+}
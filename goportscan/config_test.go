@@ -0,0 +1,124 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func writeConfig(t *testing.T, contents string) string {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), "scan.yaml")
+	if err := os.WriteFile(path, []byte(contents), 0o644); err != nil {
+		t.Fatalf("writing test config: %v", err)
+	}
+	return path
+}
+
+func TestLoadConfigProfile_NamedProfile(t *testing.T) {
+	path := writeConfig(t, `
+profiles:
+  quick:
+    targets: ["10.0.0.0/24"]
+    ports: "1-1024"
+    workers: 200
+    timeout: 250ms
+  full:
+    targets: ["10.0.0.0/16"]
+    top-ports: 1000
+`)
+
+	p, err := loadConfigProfile(path, "quick")
+	if err != nil {
+		t.Fatalf("loadConfigProfile: %v", err)
+	}
+	if len(p.Targets) != 1 || p.Targets[0] != "10.0.0.0/24" {
+		t.Errorf("Targets = %v, want [10.0.0.0/24]", p.Targets)
+	}
+	if p.Ports == nil || *p.Ports != "1-1024" {
+		t.Errorf("Ports = %v, want \"1-1024\"", p.Ports)
+	}
+	if p.Workers == nil || *p.Workers != 200 {
+		t.Errorf("Workers = %v, want 200", p.Workers)
+	}
+	if p.Timeout == nil || *p.Timeout != 250*time.Millisecond {
+		t.Errorf("Timeout = %v, want 250ms", p.Timeout)
+	}
+	if p.TopPorts != nil {
+		t.Errorf("TopPorts = %v, want nil (not set in the quick profile)", p.TopPorts)
+	}
+}
+
+func TestLoadConfigProfile_SingleProfileNeedsNoName(t *testing.T) {
+	path := writeConfig(t, `
+profiles:
+  only-one:
+    targets: ["10.0.0.1"]
+`)
+
+	p, err := loadConfigProfile(path, "")
+	if err != nil {
+		t.Fatalf("loadConfigProfile: %v", err)
+	}
+	if len(p.Targets) != 1 || p.Targets[0] != "10.0.0.1" {
+		t.Errorf("Targets = %v, want [10.0.0.1]", p.Targets)
+	}
+}
+
+func TestLoadConfigProfile_DefaultProfileNeedsNoName(t *testing.T) {
+	path := writeConfig(t, `
+profiles:
+  default:
+    targets: ["10.0.0.1"]
+  other:
+    targets: ["10.0.0.2"]
+`)
+
+	p, err := loadConfigProfile(path, "")
+	if err != nil {
+		t.Fatalf("loadConfigProfile: %v", err)
+	}
+	if len(p.Targets) != 1 || p.Targets[0] != "10.0.0.1" {
+		t.Errorf("Targets = %v, want the \"default\" profile's [10.0.0.1]", p.Targets)
+	}
+}
+
+func TestLoadConfigProfile_AmbiguousWithoutProfileFlag(t *testing.T) {
+	path := writeConfig(t, `
+profiles:
+  quick:
+    targets: ["10.0.0.1"]
+  full:
+    targets: ["10.0.0.2"]
+`)
+
+	if _, err := loadConfigProfile(path, ""); err == nil {
+		t.Error("loadConfigProfile with no -profile and no \"default\" profile = nil error, want one naming the choices")
+	}
+}
+
+func TestLoadConfigProfile_UnknownProfileName(t *testing.T) {
+	path := writeConfig(t, `
+profiles:
+  quick:
+    targets: ["10.0.0.1"]
+`)
+
+	if _, err := loadConfigProfile(path, "nonexistent"); err == nil {
+		t.Error("loadConfigProfile(\"nonexistent\") = nil error, want one")
+	}
+}
+
+func TestLoadConfigProfile_RejectsUnknownKeys(t *testing.T) {
+	path := writeConfig(t, `
+profiles:
+  quick:
+    targets: ["10.0.0.1"]
+    max-retries: 5
+`)
+
+	if _, err := loadConfigProfile(path, "quick"); err == nil {
+		t.Error("loadConfigProfile with an unrecognized key (max-retries) = nil error, want one")
+	}
+}
@@ -0,0 +1,62 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"sort"
+	"sync"
+	"sync/atomic"
+)
+
+// hostOpenTally counts open ports per host for -count-only, the same way
+// errorTally counts dial errors by category: an atomic counter per key,
+// with a mutex only guarding the rare first-sight creation of that key's
+// counter, not the hot-path increment.
+type hostOpenTally struct {
+	mu     sync.Mutex
+	counts map[string]*int64
+}
+
+func newHostOpenTally() *hostOpenTally {
+	return &hostOpenTally{counts: make(map[string]*int64)}
+}
+
+// Record tallies one open port seen on host.
+func (t *hostOpenTally) Record(host string) {
+	t.mu.Lock()
+	c, ok := t.counts[host]
+	if !ok {
+		c = new(int64)
+		t.counts[host] = c
+	}
+	t.mu.Unlock()
+	atomic.AddInt64(c, 1)
+}
+
+// Total sums every host's count, e.g. to drive -fail-on-open the same way
+// the normal result path's found count does.
+func (t *hostOpenTally) Total() int64 {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	var total int64
+	for _, c := range t.counts {
+		total += atomic.LoadInt64(c)
+	}
+	return total
+}
+
+// printHostOpenTally writes one "host: N open" line per host that had at
+// least one open port, sorted by host, mirroring printPortHostGroups'
+// sorted-and-flat summary format for -horizontal.
+func printHostOpenTally(w io.Writer, t *hostOpenTally) {
+	t.mu.Lock()
+	hosts := make([]string, 0, len(t.counts))
+	for host := range t.counts {
+		hosts = append(hosts, host)
+	}
+	t.mu.Unlock()
+	sort.Strings(hosts)
+	for _, host := range hosts {
+		fmt.Fprintf(w, "%s: %d open\n", host, atomic.LoadInt64(t.counts[host]))
+	}
+}
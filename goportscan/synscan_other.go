@@ -0,0 +1,12 @@
+//go:build !linux
+
+package main
+
+import "errors"
+
+// newSYNProber is only implemented for Linux, where raw AF_INET sockets are
+// straightforward to open with CAP_NET_RAW. On other platforms the caller
+// falls back to a connect scan.
+func newSYNProber() (synProber, error) {
+	return nil, errors.New("syn scan is only supported on linux")
+}
@@ -0,0 +1,60 @@
+package main
+
+import (
+	"bytes"
+	"testing"
+)
+
+func TestHostOpenTally_RecordsPerHost(t *testing.T) {
+	tally := newHostOpenTally()
+	tally.Record("10.0.0.1")
+	tally.Record("10.0.0.2")
+	tally.Record("10.0.0.1")
+
+	if got := tally.Total(); got != 3 {
+		t.Errorf("Total() = %d, want 3", got)
+	}
+}
+
+func TestPrintHostOpenTally_SortsHostsAscending(t *testing.T) {
+	tally := newHostOpenTally()
+	tally.Record("10.0.0.2")
+	tally.Record("10.0.0.1")
+	tally.Record("10.0.0.1")
+
+	var buf bytes.Buffer
+	printHostOpenTally(&buf, tally)
+
+	want := "10.0.0.1: 2 open\n10.0.0.2: 1 open\n"
+	if buf.String() != want {
+		t.Errorf("printHostOpenTally = %q, want %q", buf.String(), want)
+	}
+}
+
+// BenchmarkHostOpenTally_VsToScanResult backs up -count-only's claim of
+// being the fast path: it never builds a ServiceInfo or a ScanResult, just
+// an atomic increment, for every open port on an (imagined) all-open host.
+func BenchmarkHostOpenTally_VsToScanResult(b *testing.B) {
+	const openPorts = 1000
+
+	b.Run("count-only", func(b *testing.B) {
+		b.ReportAllocs()
+		for i := 0; i < b.N; i++ {
+			tally := newHostOpenTally()
+			for port := 0; port < openPorts; port++ {
+				tally.Record("10.0.0.1")
+			}
+		}
+	})
+
+	b.Run("full-result", func(b *testing.B) {
+		b.ReportAllocs()
+		for i := 0; i < b.N; i++ {
+			for port := 0; port < openPorts; port++ {
+				outcome := scanOutcome{Addr: Addr{Host: "10.0.0.1", Port: port}, State: portOpen}
+				info := ServiceInfo{Name: "unknown"}
+				_ = toScanResult(outcome, info, TLSInfo{}, false, HTTPInfo{}, false, DetectResult{}, false, LBDetectResult{}, false, 0, false, nil)
+			}
+		}
+	})
+}
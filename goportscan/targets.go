@@ -0,0 +1,252 @@
+package main
+
+import (
+	"fmt"
+	"net"
+	"strconv"
+	"strings"
+)
+
+// expandTargets turns a list of CLI target specs into a flat, deduplicated
+// list of IPs to scan, along with the subset of those IPs that came from a
+// spec naming a single host directly (as opposed to a CIDR block or range).
+// Each spec may be a single IP, a hostname, a CIDR block (e.g.
+// "10.0.0.0/24"), or a dashed IP range (e.g. "10.0.0.1-10.0.0.254").
+//
+// Callers use the explicit set to make sure a host the user named directly
+// is still scanned even if host discovery marks it dead, since discovery is
+// a heuristic and a single explicitly-named target is presumably intentional.
+func expandTargets(specs []string) (ips []net.IP, explicit map[string]bool, err error) {
+	seen := make(map[string]bool)
+	explicit = make(map[string]bool)
+
+	add := func(ip net.IP, isExplicit bool) {
+		key := ip.String()
+		if !seen[key] {
+			seen[key] = true
+			ips = append(ips, ip)
+		}
+		if isExplicit {
+			explicit[key] = true
+		}
+	}
+
+	for _, spec := range specs {
+		spec = strings.TrimSpace(spec)
+		if spec == "" {
+			continue
+		}
+
+		switch {
+		case strings.Contains(spec, "/"):
+			expanded, err := expandCIDR(spec)
+			if err != nil {
+				return nil, nil, fmt.Errorf("target %q: %w", spec, err)
+			}
+			for _, ip := range expanded {
+				add(ip, false)
+			}
+
+		case isDashedIPRange(spec):
+			expanded, err := expandRange(spec)
+			if err != nil {
+				return nil, nil, fmt.Errorf("target %q: %w", spec, err)
+			}
+			for _, ip := range expanded {
+				add(ip, false)
+			}
+
+		default:
+			if ip := net.ParseIP(spec); ip != nil {
+				v4 := ip.To4()
+				if v4 == nil {
+					return nil, nil, fmt.Errorf("target %q: IPv6 targets are not supported", spec)
+				}
+				add(v4, true)
+				continue
+			}
+			resolved, err := net.LookupIP(spec)
+			if err != nil {
+				return nil, nil, fmt.Errorf("target %q: %w", spec, err)
+			}
+			for _, ip := range resolved {
+				if v4 := ip.To4(); v4 != nil {
+					add(v4, true)
+				}
+			}
+		}
+	}
+
+	return ips, explicit, nil
+}
+
+// isDashedIPRange reports whether spec looks like a dashed IP range
+// ("10.0.0.1-10.0.0.254" or the "10.0.0.1-254" shorthand) rather than a
+// hostname that merely happens to contain a hyphen (e.g. "web-01.internal").
+// It requires the part before the hyphen to parse as a dotted IPv4 address;
+// anything else falls through to hostname resolution.
+func isDashedIPRange(spec string) bool {
+	idx := strings.Index(spec, "-")
+	if idx < 0 {
+		return false
+	}
+	start := net.ParseIP(strings.TrimSpace(spec[:idx]))
+	return start != nil && start.To4() != nil
+}
+
+// expandCIDR enumerates every host address in a CIDR block. Network and
+// broadcast addresses are skipped for blocks larger than /31.
+func expandCIDR(cidr string) ([]net.IP, error) {
+	ip, ipNet, err := net.ParseCIDR(cidr)
+	if err != nil {
+		return nil, err
+	}
+
+	var ips []net.IP
+	for cur := ip.Mask(ipNet.Mask); ipNet.Contains(cur); incIP(cur) {
+		ips = append(ips, cloneIP(cur))
+	}
+
+	ones, bits := ipNet.Mask.Size()
+	if bits-ones > 1 && len(ips) > 2 {
+		ips = ips[1 : len(ips)-1] // drop network and broadcast addresses
+	}
+	return ips, nil
+}
+
+// expandRange enumerates the inclusive IP range described by "start-end",
+// e.g. "10.0.0.1-10.0.0.254".
+func expandRange(spec string) ([]net.IP, error) {
+	parts := strings.SplitN(spec, "-", 2)
+	if len(parts) != 2 {
+		return nil, fmt.Errorf("invalid range")
+	}
+
+	startIP := net.ParseIP(strings.TrimSpace(parts[0])).To4()
+	if startIP == nil {
+		return nil, fmt.Errorf("invalid range start")
+	}
+
+	endPart := strings.TrimSpace(parts[1])
+	var endIP net.IP
+	if dotted := net.ParseIP(endPart); dotted != nil {
+		endIP = dotted.To4()
+	} else {
+		// Shorthand like "10.0.0.1-254": reuse the start's first three octets.
+		last, err := strconv.Atoi(endPart)
+		if err != nil || last < 0 || last > 255 {
+			return nil, fmt.Errorf("invalid range end")
+		}
+		endIP = cloneIP(startIP)
+		endIP[3] = byte(last)
+	}
+	if endIP == nil {
+		return nil, fmt.Errorf("invalid range end")
+	}
+
+	if ipToUint32(endIP) < ipToUint32(startIP) {
+		return nil, fmt.Errorf("range end is before start")
+	}
+
+	var ips []net.IP
+	for cur := cloneIP(startIP); ; incIP(cur) {
+		ips = append(ips, cloneIP(cur))
+		if cur.Equal(endIP) {
+			break
+		}
+	}
+	return ips, nil
+}
+
+func cloneIP(ip net.IP) net.IP {
+	out := make(net.IP, len(ip))
+	copy(out, ip)
+	return out
+}
+
+func incIP(ip net.IP) {
+	for i := len(ip) - 1; i >= 0; i-- {
+		ip[i]++
+		if ip[i] != 0 {
+			return
+		}
+	}
+}
+
+func ipToUint32(ip net.IP) uint32 {
+	v4 := ip.To4()
+	return uint32(v4[0])<<24 | uint32(v4[1])<<16 | uint32(v4[2])<<8 | uint32(v4[3])
+}
+
+// parsePortList parses a comma-separated port/range spec such as
+// "22,80,8000-8100" into a deduplicated slice of ports, in the order they
+// first appear (the result is not sorted).
+func parsePortList(spec string) ([]int, error) {
+	seen := make(map[int]bool)
+	var ports []int
+
+	for _, field := range strings.Split(spec, ",") {
+		field = strings.TrimSpace(field)
+		if field == "" {
+			continue
+		}
+		if strings.Contains(field, "-") {
+			bounds := strings.SplitN(field, "-", 2)
+			if len(bounds) != 2 {
+				return nil, fmt.Errorf("invalid port range %q", field)
+			}
+			lo, err := strconv.Atoi(strings.TrimSpace(bounds[0]))
+			if err != nil {
+				return nil, fmt.Errorf("invalid port range %q", field)
+			}
+			hi, err := strconv.Atoi(strings.TrimSpace(bounds[1]))
+			if err != nil {
+				return nil, fmt.Errorf("invalid port range %q", field)
+			}
+			for p := lo; p <= hi; p++ {
+				if !seen[p] {
+					seen[p] = true
+					ports = append(ports, p)
+				}
+			}
+			continue
+		}
+
+		p, err := strconv.Atoi(field)
+		if err != nil {
+			return nil, fmt.Errorf("invalid port %q", field)
+		}
+		if !seen[p] {
+			seen[p] = true
+			ports = append(ports, p)
+		}
+	}
+
+	return ports, nil
+}
+
+// parseHostSet parses a comma-separated list of hosts/IPs to exclude into a
+// lookup set keyed by IP string.
+func parseHostSet(spec string) (map[string]bool, error) {
+	set := make(map[string]bool)
+	for _, field := range strings.Split(spec, ",") {
+		field = strings.TrimSpace(field)
+		if field == "" {
+			continue
+		}
+		if ip := net.ParseIP(field); ip != nil {
+			set[ip.String()] = true
+			continue
+		}
+		resolved, err := net.LookupIP(field)
+		if err != nil {
+			return nil, fmt.Errorf("exclude-hosts %q: %w", field, err)
+		}
+		for _, ip := range resolved {
+			if v4 := ip.To4(); v4 != nil {
+				set[v4.String()] = true
+			}
+		}
+	}
+	return set, nil
+}
@@ -0,0 +1,61 @@
+package main
+
+import (
+	"log/slog"
+	"os"
+	"time"
+)
+
+// debugLevel is how much per-dial diagnostic logging -v/-vv enable. The
+// zero value, debugOff, must produce no stderr output at all -- scripting
+// against -o json/xml already relies on stdout staying clean, and -v/-vv
+// shouldn't put stray lines on stderr for a plain scan either.
+type debugLevel int
+
+const (
+	debugOff    debugLevel = iota // default: no dial logging
+	debugDial                     // -v: one line per dial with its classified outcome
+	debugDetail                   // -vv: debugDial plus the raw error for failed dials
+)
+
+// newDebugLogger returns an slog.Logger writing structured text lines to
+// stderr, gated by level so debugOff logs nothing: its handler level is set
+// above slog.LevelError, and logDial only ever logs at Info or Debug.
+func newDebugLogger(level debugLevel) *slog.Logger {
+	handlerLevel := slog.LevelError + 1
+	switch level {
+	case debugDial:
+		handlerLevel = slog.LevelInfo
+	case debugDetail:
+		handlerLevel = slog.LevelDebug
+	}
+	return slog.New(slog.NewTextHandler(os.Stderr, &slog.HandlerOptions{Level: handlerLevel}))
+}
+
+// classifyErr names a dial error the way -v's summary line reports it:
+// "refused" and "timeout" are the two outcomes worth grepping for, and
+// anything else falls back to the error's own text.
+func classifyErr(err error) string {
+	switch {
+	case err == nil:
+		return ""
+	case isConnRefused(err):
+		return "refused"
+	case isTimeout(err):
+		return "timeout"
+	default:
+		return err.Error()
+	}
+}
+
+// logDial records one dial attempt's outcome at -v, and, at -vv, the raw
+// error text behind a non-nil classification too (classifyErr already
+// collapses "connection refused" and "i/o timeout" variants down to one
+// word each, which -vv's extra line lets a caller get back if they need
+// it).
+func logDial(logger *slog.Logger, addr Addr, state portState, err error, rtt time.Duration) {
+	logger.Info("dial", "addr", addr.Display(), "port", addr.Port, "state", string(state), "rtt", rtt, "errType", classifyErr(err))
+	if err != nil {
+		logger.Debug("dial error detail", "addr", addr.Display(), "port", addr.Port, "error", err.Error())
+	}
+}
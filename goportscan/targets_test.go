@@ -0,0 +1,152 @@
+package main
+
+import (
+	"net"
+	"reflect"
+	"testing"
+)
+
+func TestIsDashedIPRange(t *testing.T) {
+	cases := []struct {
+		spec string
+		want bool
+	}{
+		{"10.0.0.1-10.0.0.254", true},
+		{"10.0.0.1-254", true},
+		{"web-01.internal", false},
+		{"my-bad-host.example.invalid", false},
+		{"example.com", false},
+		{"10.0.0.1", false},
+	}
+	for _, c := range cases {
+		if got := isDashedIPRange(c.spec); got != c.want {
+			t.Errorf("isDashedIPRange(%q) = %v, want %v", c.spec, got, c.want)
+		}
+	}
+}
+
+func TestExpandTargets_HyphenatedHostnameIsNotARange(t *testing.T) {
+	// A hyphenated hostname should attempt DNS resolution, not fail with
+	// "invalid range start" the way a malformed dashed IP range would.
+	_, _, err := expandTargets([]string{"my-bad-host.example.invalid"})
+	if err == nil {
+		t.Fatal("expected a resolution error for an unresolvable hostname, got nil")
+	}
+	if got := err.Error(); got == `target "my-bad-host.example.invalid": invalid range start` {
+		t.Fatalf("hyphenated hostname was misrouted into expandRange: %v", err)
+	}
+}
+
+func TestExpandTargets_RejectsIPv6(t *testing.T) {
+	_, _, err := expandTargets([]string{"::1"})
+	if err == nil {
+		t.Fatal("expected an error for an IPv6 target, got nil")
+	}
+}
+
+func TestExpandTargets_SingleHostIsExplicit(t *testing.T) {
+	ips, explicit, err := expandTargets([]string{"10.0.0.5", "10.0.0.0/30"})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if !explicit["10.0.0.5"] {
+		t.Errorf("expected 10.0.0.5 to be marked explicit, got %v", explicit)
+	}
+	for _, ip := range ips {
+		if ip.String() != "10.0.0.5" && explicit[ip.String()] {
+			t.Errorf("CIDR-expanded address %s should not be marked explicit", ip)
+		}
+	}
+}
+
+func TestExpandRange(t *testing.T) {
+	cases := []struct {
+		spec    string
+		want    []string
+		wantErr bool
+	}{
+		{"10.0.0.1-10.0.0.3", []string{"10.0.0.1", "10.0.0.2", "10.0.0.3"}, false},
+		{"10.0.0.253-255", []string{"10.0.0.253", "10.0.0.254", "10.0.0.255"}, false},
+		{"10.0.0.3-10.0.0.1", nil, true},
+		{"not-an-ip-1", nil, true},
+		{"10.0.0.1-999", nil, true},
+	}
+	for _, c := range cases {
+		got, err := expandRange(c.spec)
+		if c.wantErr {
+			if err == nil {
+				t.Errorf("expandRange(%q): expected error, got none", c.spec)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("expandRange(%q): unexpected error: %v", c.spec, err)
+			continue
+		}
+		var gotStrs []string
+		for _, ip := range got {
+			gotStrs = append(gotStrs, ip.String())
+		}
+		if !reflect.DeepEqual(gotStrs, c.want) {
+			t.Errorf("expandRange(%q) = %v, want %v", c.spec, gotStrs, c.want)
+		}
+	}
+}
+
+func TestExpandCIDR(t *testing.T) {
+	ips, err := expandCIDR("10.0.0.0/30")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	// /30 has 4 addresses; network and broadcast are dropped, leaving 2 hosts.
+	want := []string{"10.0.0.1", "10.0.0.2"}
+	var got []string
+	for _, ip := range ips {
+		got = append(got, ip.String())
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("expandCIDR(10.0.0.0/30) = %v, want %v", got, want)
+	}
+}
+
+func TestIpToUint32(t *testing.T) {
+	cases := []struct {
+		ip   string
+		want uint32
+	}{
+		{"0.0.0.1", 1},
+		{"10.0.0.1", 10<<24 | 1},
+		{"255.255.255.255", 0xffffffff},
+	}
+	for _, c := range cases {
+		got := ipToUint32(net.ParseIP(c.ip))
+		if got != c.want {
+			t.Errorf("ipToUint32(%q) = %d, want %d", c.ip, got, c.want)
+		}
+	}
+}
+
+func TestParsePortList(t *testing.T) {
+	got, err := parsePortList("22,80,8000-8002,80")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	want := []int{22, 80, 8000, 8001, 8002}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("parsePortList = %v, want %v", got, want)
+	}
+
+	if _, err := parsePortList("not-a-port"); err == nil {
+		t.Error("expected error for invalid port, got nil")
+	}
+}
+
+func TestParseHostSet(t *testing.T) {
+	set, err := parseHostSet("10.0.0.1, 10.0.0.2")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if !set["10.0.0.1"] || !set["10.0.0.2"] {
+		t.Errorf("parseHostSet missing expected entries: %v", set)
+	}
+}
@@ -0,0 +1,83 @@
+package main
+
+import (
+	"bytes"
+	"errors"
+	"log/slog"
+	"net"
+	"syscall"
+	"testing"
+	"time"
+)
+
+func TestClassifyErr(t *testing.T) {
+	cases := []struct {
+		name string
+		err  error
+		want string
+	}{
+		{"nil", nil, ""},
+		{"refused", &net.OpError{Err: syscall.ECONNREFUSED}, "refused"},
+		{"timeout", &timeoutErrStub{}, "timeout"},
+		{"other", errors.New("boom"), "boom"},
+	}
+	for _, c := range cases {
+		if got := classifyErr(c.err); got != c.want {
+			t.Errorf("classifyErr(%v) = %q, want %q", c.name, got, c.want)
+		}
+	}
+}
+
+// timeoutErrStub satisfies net.Error with Timeout() == true, for exercising
+// classifyErr's timeout branch without depending on a real dial timing out.
+type timeoutErrStub struct{}
+
+func (*timeoutErrStub) Error() string   { return "stub timeout" }
+func (*timeoutErrStub) Timeout() bool   { return true }
+func (*timeoutErrStub) Temporary() bool { return true }
+
+func TestNewDebugLogger_OffLevelLogsNothing(t *testing.T) {
+	var buf bytes.Buffer
+	logger := slog.New(slog.NewTextHandler(&buf, &slog.HandlerOptions{Level: slog.LevelError + 1}))
+	logDial(logger, Addr{IP: net.ParseIP("10.0.0.1"), Port: 22}, portOpen, nil, time.Millisecond)
+	if buf.Len() != 0 {
+		t.Errorf("expected no output at debugOff, got %q", buf.String())
+	}
+}
+
+func TestLogDial_DialLevelLogsOneLineWithoutRawError(t *testing.T) {
+	var buf bytes.Buffer
+	logger := newDebugLoggerForTest(&buf, debugDial)
+	logDial(logger, Addr{IP: net.ParseIP("10.0.0.1"), Port: 80}, portClosed, &net.OpError{Err: syscall.ECONNREFUSED}, 5*time.Millisecond)
+
+	out := buf.String()
+	if !bytes.Contains(buf.Bytes(), []byte("errType=refused")) {
+		t.Errorf("expected classified errType in output, got %q", out)
+	}
+	if bytes.Contains(buf.Bytes(), []byte("error detail")) {
+		t.Errorf("-v should not include the -vv detail line, got %q", out)
+	}
+}
+
+func TestLogDial_DetailLevelIncludesRawError(t *testing.T) {
+	var buf bytes.Buffer
+	logger := newDebugLoggerForTest(&buf, debugDetail)
+	logDial(logger, Addr{IP: net.ParseIP("10.0.0.1"), Port: 80}, portClosed, errors.New("connect: connection refused"), 5*time.Millisecond)
+
+	if !bytes.Contains(buf.Bytes(), []byte("error detail")) {
+		t.Errorf("-vv should include the raw error detail line, got %q", buf.String())
+	}
+}
+
+// newDebugLoggerForTest builds the same handler newDebugLogger would, but
+// over a buffer instead of os.Stderr, so tests can assert on the output.
+func newDebugLoggerForTest(buf *bytes.Buffer, level debugLevel) *slog.Logger {
+	handlerLevel := slog.LevelError + 1
+	switch level {
+	case debugDial:
+		handlerLevel = slog.LevelInfo
+	case debugDetail:
+		handlerLevel = slog.LevelDebug
+	}
+	return slog.New(slog.NewTextHandler(buf, &slog.HandlerOptions{Level: handlerLevel}))
+}
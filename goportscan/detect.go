@@ -0,0 +1,168 @@
+package main
+
+import (
+	"bufio"
+	"context"
+	"net"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Probe is a plugin-style service-detection check, run against an open
+// port's connection when -detect is set. Unlike the built-in probeRegistry
+// identifyService always consults, Probes are opt-in and meant for
+// extending fingerprinting without editing scanner internals: register one
+// with RegisterProbe, typically from an init function.
+type Probe interface {
+	// Match reports whether this probe is worth trying against port, so a
+	// probe that only makes sense for one protocol isn't dialed against
+	// every open port.
+	Match(port int) bool
+	// Detect attempts to identify the service speaking on conn. A nil err
+	// with an empty service means the probe ran but didn't recognize
+	// anything, which is an ordinary outcome, not a failure; err is for
+	// actual I/O problems (write failed, connection reset).
+	Detect(conn net.Conn) (service string, extra map[string]string, err error)
+}
+
+var (
+	detectMu       sync.Mutex
+	detectRegistry []Probe
+)
+
+// RegisterProbe adds p to the registry runDetectProbes consults for every
+// open port once -detect is set. Third parties extend service detection by
+// calling this, rather than editing identifyService or probeRegistry.
+func RegisterProbe(p Probe) {
+	detectMu.Lock()
+	defer detectMu.Unlock()
+	detectRegistry = append(detectRegistry, p)
+}
+
+func init() {
+	RegisterProbe(httpDetectProbe{})
+	RegisterProbe(sshDetectProbe{})
+	RegisterProbe(redisDetectProbe{})
+}
+
+// DetectResult is the -detect plugin-probe outcome attached to a
+// ScanResult, omitted entirely for ports that weren't probed or whose
+// registered Probes found nothing.
+type DetectResult struct {
+	Service string            `json:"service"`
+	Extra   map[string]string `json:"extra,omitempty"`
+}
+
+// runDetectProbes dials addr once per registered Probe that matches its
+// port, in registration order, and returns the first one that identifies
+// something. A fresh connection per probe (rather than one connection
+// shared across probes) keeps each Probe.Detect free to assume it's reading
+// a service's very first bytes, the same assumption probeBanner and its
+// siblings make.
+func runDetectProbes(addr Addr, timeout time.Duration) (DetectResult, bool) {
+	detectMu.Lock()
+	probes := make([]Probe, len(detectRegistry))
+	copy(probes, detectRegistry)
+	detectMu.Unlock()
+
+	address := addr.Dial()
+	for _, p := range probes {
+		if !p.Match(addr.Port) {
+			continue
+		}
+		conn, err := dialTCP(context.Background(), address, timeout)
+		if err != nil {
+			return DetectResult{}, false
+		}
+		conn.SetDeadline(time.Now().Add(timeout))
+		service, extra, err := p.Detect(conn)
+		conn.Close()
+		if err != nil || service == "" {
+			continue
+		}
+		return DetectResult{Service: service, Extra: extra}, true
+	}
+	return DetectResult{}, false
+}
+
+// httpDetectProbe identifies HTTP services by sending a minimal GET and
+// checking for a valid status line, reporting the status line and Server
+// header (if any) as extra detail.
+type httpDetectProbe struct{}
+
+func (httpDetectProbe) Match(port int) bool {
+	return port == 80 || port == 8080 || port == 8000 || port == 8888
+}
+
+func (httpDetectProbe) Detect(conn net.Conn) (string, map[string]string, error) {
+	if _, err := conn.Write([]byte("GET / HTTP/1.0\r\nHost: scan\r\n\r\n")); err != nil {
+		return "", nil, err
+	}
+
+	reader := bufio.NewReader(conn)
+	status, err := reader.ReadString('\n')
+	if err != nil {
+		return "", nil, err
+	}
+	status = strings.TrimSpace(status)
+	if !strings.HasPrefix(status, "HTTP/") {
+		return "", nil, nil
+	}
+
+	extra := map[string]string{"status": status}
+	for {
+		line, err := reader.ReadString('\n')
+		line = strings.TrimSpace(line)
+		if err != nil || line == "" {
+			break
+		}
+		if strings.HasPrefix(strings.ToLower(line), "server:") {
+			extra["server"] = strings.TrimSpace(line[len("server:"):])
+			break
+		}
+	}
+	return "http", extra, nil
+}
+
+// sshDetectProbe identifies SSH services from their version exchange
+// banner, e.g. "SSH-2.0-OpenSSH_8.9p1".
+type sshDetectProbe struct{}
+
+func (sshDetectProbe) Match(port int) bool {
+	return port == 22
+}
+
+func (sshDetectProbe) Detect(conn net.Conn) (string, map[string]string, error) {
+	line, err := bufio.NewReader(conn).ReadString('\n')
+	if err != nil {
+		return "", nil, err
+	}
+	line = strings.TrimSpace(line)
+	if !strings.HasPrefix(line, "SSH-") {
+		return "", nil, nil
+	}
+	return "ssh", map[string]string{"banner": line}, nil
+}
+
+// redisDetectProbe identifies Redis by sending PING and checking for the
+// "+PONG" simple-string reply.
+type redisDetectProbe struct{}
+
+func (redisDetectProbe) Match(port int) bool {
+	return port == 6379
+}
+
+func (redisDetectProbe) Detect(conn net.Conn) (string, map[string]string, error) {
+	if _, err := conn.Write([]byte("PING\r\n")); err != nil {
+		return "", nil, err
+	}
+	line, err := bufio.NewReader(conn).ReadString('\n')
+	if err != nil {
+		return "", nil, err
+	}
+	if !strings.HasPrefix(strings.TrimSpace(line), "+PONG") {
+		return "", nil, nil
+	}
+	return "redis", nil, nil
+}
@@ -0,0 +1,190 @@
+package main
+
+import (
+	"errors"
+	"net"
+	"testing"
+	"time"
+)
+
+func TestHTTPDetectProbe_IdentifiesStatusAndServerHeader(t *testing.T) {
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("listen: %v", err)
+	}
+	defer ln.Close()
+	go func() {
+		conn, err := ln.Accept()
+		if err != nil {
+			return
+		}
+		defer conn.Close()
+		buf := make([]byte, 1024)
+		conn.Read(buf)
+		conn.Write([]byte("HTTP/1.0 200 OK\r\nServer: nginx\r\n\r\n"))
+	}()
+
+	conn, err := net.Dial("tcp", ln.Addr().String())
+	if err != nil {
+		t.Fatalf("dial: %v", err)
+	}
+	defer conn.Close()
+	conn.SetDeadline(time.Now().Add(time.Second))
+
+	service, extra, err := (httpDetectProbe{}).Detect(conn)
+	if err != nil {
+		t.Fatalf("Detect: %v", err)
+	}
+	if service != "http" {
+		t.Errorf("service = %q, want %q", service, "http")
+	}
+	if extra["server"] != "nginx" {
+		t.Errorf("extra[server] = %q, want %q", extra["server"], "nginx")
+	}
+}
+
+func TestSSHDetectProbe_ReadsVersionBanner(t *testing.T) {
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("listen: %v", err)
+	}
+	defer ln.Close()
+	go func() {
+		conn, err := ln.Accept()
+		if err != nil {
+			return
+		}
+		defer conn.Close()
+		conn.Write([]byte("SSH-2.0-OpenSSH_8.9p1\r\n"))
+	}()
+
+	conn, err := net.Dial("tcp", ln.Addr().String())
+	if err != nil {
+		t.Fatalf("dial: %v", err)
+	}
+	defer conn.Close()
+	conn.SetDeadline(time.Now().Add(time.Second))
+
+	service, extra, err := (sshDetectProbe{}).Detect(conn)
+	if err != nil {
+		t.Fatalf("Detect: %v", err)
+	}
+	if service != "ssh" {
+		t.Errorf("service = %q, want %q", service, "ssh")
+	}
+	if extra["banner"] != "SSH-2.0-OpenSSH_8.9p1" {
+		t.Errorf("extra[banner] = %q, want the raw banner line", extra["banner"])
+	}
+}
+
+func TestRedisDetectProbe_RecognizesPong(t *testing.T) {
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("listen: %v", err)
+	}
+	defer ln.Close()
+	go func() {
+		conn, err := ln.Accept()
+		if err != nil {
+			return
+		}
+		defer conn.Close()
+		buf := make([]byte, 1024)
+		conn.Read(buf)
+		conn.Write([]byte("+PONG\r\n"))
+	}()
+
+	conn, err := net.Dial("tcp", ln.Addr().String())
+	if err != nil {
+		t.Fatalf("dial: %v", err)
+	}
+	defer conn.Close()
+	conn.SetDeadline(time.Now().Add(time.Second))
+
+	service, _, err := (redisDetectProbe{}).Detect(conn)
+	if err != nil {
+		t.Fatalf("Detect: %v", err)
+	}
+	if service != "redis" {
+		t.Errorf("service = %q, want %q", service, "redis")
+	}
+}
+
+// fakeProbe lets TestRunDetectProbes_RegistrationOrderAndMatch exercise
+// RegisterProbe without depending on the built-ins' exact port numbers.
+type fakeProbe struct {
+	port    int
+	service string
+	err     error
+}
+
+func (p fakeProbe) Match(port int) bool { return port == p.port }
+
+func (p fakeProbe) Detect(conn net.Conn) (string, map[string]string, error) {
+	if p.err != nil {
+		return "", nil, p.err
+	}
+	return p.service, map[string]string{"from": p.service}, nil
+}
+
+func TestRunDetectProbes_StopsAtFirstMatch(t *testing.T) {
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("listen: %v", err)
+	}
+	defer ln.Close()
+	go func() {
+		for {
+			conn, err := ln.Accept()
+			if err != nil {
+				return
+			}
+			conn.Close()
+		}
+	}()
+
+	_, port, _ := net.SplitHostPort(ln.Addr().String())
+	var portNum int
+	for _, c := range port {
+		portNum = portNum*10 + int(c-'0')
+	}
+
+	detectMu.Lock()
+	saved := detectRegistry
+	detectRegistry = nil
+	detectMu.Unlock()
+	defer func() {
+		detectMu.Lock()
+		detectRegistry = saved
+		detectMu.Unlock()
+	}()
+
+	RegisterProbe(fakeProbe{port: portNum, err: errors.New("boom")})
+	RegisterProbe(fakeProbe{port: portNum, service: "first"})
+	RegisterProbe(fakeProbe{port: portNum, service: "second"})
+
+	result, ok := runDetectProbes(Addr{IP: net.ParseIP("127.0.0.1"), Port: portNum}, time.Second)
+	if !ok {
+		t.Fatal("runDetectProbes = false, want true")
+	}
+	if result.Service != "first" {
+		t.Errorf("Service = %q, want %q (the first probe whose Detect succeeded after the erroring one)", result.Service, "first")
+	}
+}
+
+func TestRunDetectProbes_NoMatchingProbeReturnsFalse(t *testing.T) {
+	detectMu.Lock()
+	saved := detectRegistry
+	detectRegistry = nil
+	detectMu.Unlock()
+	defer func() {
+		detectMu.Lock()
+		detectRegistry = saved
+		detectMu.Unlock()
+	}()
+
+	_, ok := runDetectProbes(Addr{IP: net.ParseIP("127.0.0.1"), Port: 1}, time.Second)
+	if ok {
+		t.Error("runDetectProbes with an empty registry = true, want false")
+	}
+}
@@ -0,0 +1,146 @@
+package main
+
+import (
+	"fmt"
+	"net"
+	"os"
+	"strconv"
+	"sync"
+	"time"
+
+	"golang.org/x/net/icmp"
+	"golang.org/x/net/ipv4"
+	"golang.org/x/net/ipv6"
+)
+
+// icmpFallbackNotice makes sure the "no raw socket privilege" notice is
+// printed at most once per run, no matter how many hosts end up falling
+// back to tcpProbeAlive.
+var icmpFallbackNotice sync.Once
+
+// discoverLiveHosts pings each candidate host and returns the subset that
+// responded. It tries a real ICMP echo first and falls back to a TCP-ping
+// probe when the process lacks the privilege to open a raw ICMP socket.
+// Hosts are assumed alive if neither probe can be performed, so discovery
+// failures never cause a host to be skipped outright.
+func discoverLiveHosts(hosts []net.IP, timeout time.Duration) []net.IP {
+	var (
+		mu    sync.Mutex
+		alive []net.IP
+		wg    sync.WaitGroup
+	)
+
+	sem := make(chan struct{}, 256)
+	for _, h := range hosts {
+		wg.Add(1)
+		sem <- struct{}{}
+		go func(ip net.IP) {
+			defer wg.Done()
+			defer func() { <-sem }()
+			if pingHost(ip, timeout) {
+				mu.Lock()
+				alive = append(alive, ip)
+				mu.Unlock()
+			}
+		}(h)
+	}
+	wg.Wait()
+
+	return alive
+}
+
+// pingHost reports whether a host appears to be up, using an ICMP echo
+// request when possible and falling back to a TCP connect probe against a
+// handful of commonly open ports.
+func pingHost(ip net.IP, timeout time.Duration) bool {
+	if icmpEcho(ip, timeout) {
+		return true
+	}
+	return tcpProbeAlive(ip, timeout)
+}
+
+// icmpEcho sends a single ICMP echo request and waits for any reply, using
+// golang.org/x/net/icmp so IPv4 and IPv6 share the same request/parse
+// logic modulo their different protocol numbers and message types. It
+// requires CAP_NET_RAW (or root) to open the raw socket; when that fails,
+// it logs a one-time notice and reports false so the caller falls back to
+// tcpProbeAlive.
+func icmpEcho(ip net.IP, timeout time.Duration) bool {
+	if v4 := ip.To4(); v4 != nil {
+		return icmpEchoFamily(v4, timeout, "ip4:icmp", "0.0.0.0", ipv4.ICMPTypeEcho, ipv4.ICMPTypeEchoReply)
+	}
+	return icmpEchoFamily(ip, timeout, "ip6:ipv6-icmp", "::", ipv6.ICMPTypeEchoRequest, ipv6.ICMPTypeEchoReply)
+}
+
+// icmpEchoFamily is icmpEcho's shared body: listen on a raw socket for the
+// given network, send one echo request of the given type, and wait for a
+// reply of the matching reply type. network/laddr/request/reply differ
+// between icmpEcho's IPv4 and IPv6 callers; everything else is identical.
+func icmpEchoFamily(ip net.IP, timeout time.Duration, network, laddr string, request, reply icmp.Type) bool {
+	conn, err := icmp.ListenPacket(network, laddr)
+	if err != nil {
+		noteICMPFallback(err)
+		return false
+	}
+	defer conn.Close()
+
+	id := int(uint16(time.Now().UnixNano() & 0xffff))
+	msg := icmp.Message{
+		Type: request,
+		Code: 0,
+		Body: &icmp.Echo{ID: id, Seq: 1, Data: []byte("goportscan")},
+	}
+	wb, err := msg.Marshal(nil)
+	if err != nil {
+		return false
+	}
+
+	conn.SetDeadline(time.Now().Add(timeout))
+	if _, err := conn.WriteTo(wb, &net.IPAddr{IP: ip}); err != nil {
+		return false
+	}
+
+	proto := reply.Protocol()
+	rb := make([]byte, 512)
+	for {
+		n, _, err := conn.ReadFrom(rb)
+		if err != nil {
+			// Either a real failure or the deadline from above -- either
+			// way there's nothing more worth waiting for.
+			return false
+		}
+		parsed, err := icmp.ParseMessage(proto, rb[:n])
+		if err != nil {
+			continue
+		}
+		if parsed.Type == reply {
+			return true
+		}
+	}
+}
+
+// tcpProbeAlive tries a handful of commonly open TCP ports and reports the
+// host alive if any of them accepts or actively refuses the connection
+// (a refusal still proves the host is up and routable).
+func tcpProbeAlive(ip net.IP, timeout time.Duration) bool {
+	for _, port := range []int{80, 443, 22, 445, 3389} {
+		conn, err := net.DialTimeout("tcp", net.JoinHostPort(ip.String(), strconv.Itoa(port)), timeout)
+		if err == nil {
+			conn.Close()
+			return true
+		}
+		if isConnRefused(err) {
+			return true
+		}
+	}
+	return false
+}
+
+// noteICMPFallback logs, once per process, that a raw ICMP socket couldn't
+// be opened and discovery is falling back to tcpProbeAlive -- almost always
+// because the process lacks CAP_NET_RAW or isn't running as root.
+func noteICMPFallback(err error) {
+	icmpFallbackNotice.Do(func() {
+		fmt.Fprintf(os.Stderr, "notice: ICMP echo unavailable (%v); falling back to TCP-ping for host discovery\n", err)
+	})
+}
@@ -0,0 +1,59 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"strconv"
+	"time"
+)
+
+// Environment variables applyEnvDefaults reads as a middle tier of
+// precedence between a -config file and each flag's built-in default:
+// flags > env > -config file > built-in defaults. This lets a container
+// set these once in its env instead of repeating entrypoint args, while a
+// checked-in -config file still only takes over when neither is given.
+const (
+	envTarget         = "GOPORTSCAN_TARGET"
+	envPorts          = "GOPORTSCAN_PORTS"
+	envTimeout        = "GOPORTSCAN_TIMEOUT"
+	envWorkers        = "GOPORTSCAN_WORKERS"
+	envMaxConcurrency = "GOPORTSCAN_RATE"
+)
+
+// applyEnvDefaults overwrites targetFlag, portsFlag, timeoutFlag,
+// workersFlag, and maxConcurrencyFlag from GOPORTSCAN_TARGET,
+// GOPORTSCAN_PORTS, GOPORTSCAN_TIMEOUT, GOPORTSCAN_WORKERS, and
+// GOPORTSCAN_RATE respectively, for any of those not given explicitly on
+// the command line. Call it after applying -config, so an env variable
+// overrides a config file's value the same way an explicit flag would,
+// without itself overriding an explicit flag.
+func applyEnvDefaults(setExplicitly map[string]bool, targetFlag, portsFlag *string, timeoutFlag *time.Duration, workersFlag, maxConcurrencyFlag *int) error {
+	if v, ok := os.LookupEnv(envTarget); ok && !setExplicitly["target"] {
+		*targetFlag = v
+	}
+	if v, ok := os.LookupEnv(envPorts); ok && !setExplicitly["ports"] {
+		*portsFlag = v
+	}
+	if v, ok := os.LookupEnv(envTimeout); ok && !setExplicitly["timeout"] {
+		d, err := time.ParseDuration(v)
+		if err != nil {
+			return fmt.Errorf("%s: invalid duration %q: %w", envTimeout, v, err)
+		}
+		*timeoutFlag = d
+	}
+	if v, ok := os.LookupEnv(envWorkers); ok && !setExplicitly["workers"] {
+		n, err := strconv.Atoi(v)
+		if err != nil {
+			return fmt.Errorf("%s: invalid integer %q: %w", envWorkers, v, err)
+		}
+		*workersFlag = n
+	}
+	if v, ok := os.LookupEnv(envMaxConcurrency); ok && !setExplicitly["max-concurrency"] {
+		n, err := strconv.Atoi(v)
+		if err != nil {
+			return fmt.Errorf("%s: invalid integer %q: %w", envMaxConcurrency, v, err)
+		}
+		*maxConcurrencyFlag = n
+	}
+	return nil
+}
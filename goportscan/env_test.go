@@ -0,0 +1,66 @@
+package main
+
+import (
+	"testing"
+	"time"
+)
+
+func TestApplyEnvDefaults_FillsUnsetFlagsFromEnv(t *testing.T) {
+	t.Setenv(envTarget, "10.0.0.0/24")
+	t.Setenv(envPorts, "1-1024")
+	t.Setenv(envTimeout, "250ms")
+	t.Setenv(envWorkers, "200")
+	t.Setenv(envMaxConcurrency, "50")
+
+	target, ports := "", ""
+	timeout := time.Duration(0)
+	workers, maxConcurrency := 0, 0
+	if err := applyEnvDefaults(map[string]bool{}, &target, &ports, &timeout, &workers, &maxConcurrency); err != nil {
+		t.Fatalf("applyEnvDefaults: %v", err)
+	}
+	if target != "10.0.0.0/24" {
+		t.Errorf("target = %q, want 10.0.0.0/24", target)
+	}
+	if ports != "1-1024" {
+		t.Errorf("ports = %q, want 1-1024", ports)
+	}
+	if timeout != 250*time.Millisecond {
+		t.Errorf("timeout = %v, want 250ms", timeout)
+	}
+	if workers != 200 {
+		t.Errorf("workers = %d, want 200", workers)
+	}
+	if maxConcurrency != 50 {
+		t.Errorf("maxConcurrency = %d, want 50", maxConcurrency)
+	}
+}
+
+func TestApplyEnvDefaults_ExplicitFlagsWinOverEnv(t *testing.T) {
+	t.Setenv(envWorkers, "200")
+	t.Setenv(envTimeout, "250ms")
+
+	timeout := time.Second
+	workers := 42
+	explicit := map[string]bool{"workers": true}
+	target, ports, maxConcurrency := "", "", 0
+	if err := applyEnvDefaults(explicit, &target, &ports, &timeout, &workers, &maxConcurrency); err != nil {
+		t.Fatalf("applyEnvDefaults: %v", err)
+	}
+	if workers != 42 {
+		t.Errorf("workers = %d, want the explicitly-set 42 left alone", workers)
+	}
+	if timeout != 250*time.Millisecond {
+		t.Errorf("timeout = %v, want GOPORTSCAN_TIMEOUT to apply since -timeout wasn't given", timeout)
+	}
+}
+
+func TestApplyEnvDefaults_RejectsMalformedValues(t *testing.T) {
+	t.Setenv(envWorkers, "not-a-number")
+
+	target, ports := "", ""
+	timeout := time.Duration(0)
+	workers, maxConcurrency := 0, 0
+	if err := applyEnvDefaults(map[string]bool{}, &target, &ports, &timeout, &workers, &maxConcurrency); err == nil {
+		t.Error("applyEnvDefaults with a malformed GOPORTSCAN_WORKERS: expected an error, got nil")
+	}
+}
@@ -0,0 +1,93 @@
+package main
+
+import (
+	"errors"
+	"net"
+	"os"
+	"syscall"
+)
+
+// isConnRefused reports whether err is a TCP RST / ECONNREFUSED, as opposed
+// to a timeout or a resource exhaustion error.
+func isConnRefused(err error) bool {
+	var sysErr syscall.Errno
+	if errors.As(err, &sysErr) {
+		return sysErr == syscall.ECONNREFUSED
+	}
+	var opErr *net.OpError
+	if errors.As(err, &opErr) {
+		var errno syscall.Errno
+		if errors.As(opErr.Err, &errno) {
+			return errno == syscall.ECONNREFUSED
+		}
+	}
+	return false
+}
+
+// isConnReset reports whether err is a TCP RST received after a connection
+// was already established, as opposed to the refusal isConnRefused
+// classifies (an RST to the SYN itself, before any connection existed).
+func isConnReset(err error) bool {
+	var sysErr syscall.Errno
+	if errors.As(err, &sysErr) {
+		return sysErr == syscall.ECONNRESET
+	}
+	var opErr *net.OpError
+	if errors.As(err, &opErr) {
+		var errno syscall.Errno
+		if errors.As(opErr.Err, &errno) {
+			return errno == syscall.ECONNRESET
+		}
+	}
+	return false
+}
+
+// isHostUnreachable reports whether err is ENETUNREACH or EHOSTUNREACH --
+// the kernel giving up on routing the dial at all, as opposed to the target
+// host itself refusing or ignoring it. A host or subnet returning this on
+// every dial is almost always simply down, which is what hostShortCircuit
+// uses this for: bailing out of the rest of that host's ports instead of
+// waiting out a full timeout on each one.
+func isHostUnreachable(err error) bool {
+	var sysErr syscall.Errno
+	if errors.As(err, &sysErr) {
+		return sysErr == syscall.ENETUNREACH || sysErr == syscall.EHOSTUNREACH
+	}
+	var opErr *net.OpError
+	if errors.As(err, &opErr) {
+		var errno syscall.Errno
+		if errors.As(opErr.Err, &errno) {
+			return errno == syscall.ENETUNREACH || errno == syscall.EHOSTUNREACH
+		}
+	}
+	return false
+}
+
+// isTimeout reports whether err represents a dial timeout.
+func isTimeout(err error) bool {
+	var netErr net.Error
+	if errors.As(err, &netErr) {
+		return netErr.Timeout()
+	}
+	return false
+}
+
+// isTooManyOpenFiles reports whether err was caused by hitting the process's
+// open file descriptor limit (EMFILE/ENFILE).
+func isTooManyOpenFiles(err error) bool {
+	var sysErr syscall.Errno
+	if errors.As(err, &sysErr) {
+		return sysErr == syscall.EMFILE || sysErr == syscall.ENFILE
+	}
+	var opErr *net.OpError
+	if errors.As(err, &opErr) {
+		var errno syscall.Errno
+		if errors.As(opErr.Err, &errno) {
+			return errno == syscall.EMFILE || errno == syscall.ENFILE
+		}
+	}
+	if errors.Is(err, os.ErrDeadlineExceeded) {
+		return false
+	}
+	return false
+}
@@ -0,0 +1,165 @@
+package main
+
+import (
+	"errors"
+	"net"
+	"sync"
+	"sync/atomic"
+	"syscall"
+)
+
+// errorCategory buckets a dial error coarsely enough to answer "is this a
+// dead host or a firewall" at a glance, the same distinctions isConnRefused
+// and isTimeout already draw individually.
+type errorCategory int
+
+const (
+	errCategoryTimeout errorCategory = iota
+	errCategoryConnRefused
+	errCategoryNetworkUnreachable
+	errCategoryNoRoute
+	errCategoryOther
+)
+
+func (c errorCategory) String() string {
+	switch c {
+	case errCategoryTimeout:
+		return "timeout"
+	case errCategoryConnRefused:
+		return "connection refused"
+	case errCategoryNetworkUnreachable:
+		return "network unreachable"
+	case errCategoryNoRoute:
+		return "no route to host"
+	default:
+		return "other"
+	}
+}
+
+// classifyError buckets a dial error into one of errorCategory's values by
+// unwrapping to its underlying syscall.Errno, the same way isConnRefused
+// and isTooManyOpenFiles already unwrap errors elsewhere in this package.
+func classifyError(err error) errorCategory {
+	var netErr net.Error
+	if errors.As(err, &netErr) && netErr.Timeout() {
+		return errCategoryTimeout
+	}
+	var errno syscall.Errno
+	if errors.As(err, &errno) {
+		return errnoCategory(errno)
+	}
+	var opErr *net.OpError
+	if errors.As(err, &opErr) {
+		if errors.As(opErr.Err, &errno) {
+			return errnoCategory(errno)
+		}
+	}
+	return errCategoryOther
+}
+
+func errnoCategory(errno syscall.Errno) errorCategory {
+	switch errno {
+	case syscall.ECONNREFUSED:
+		return errCategoryConnRefused
+	case syscall.ENETUNREACH:
+		return errCategoryNetworkUnreachable
+	case syscall.EHOSTUNREACH:
+		return errCategoryNoRoute
+	default:
+		return errCategoryOther
+	}
+}
+
+// ErrorStats is the end-of-scan error tally: counts per category plus, for
+// each category that occurred, one example message -- enough to tell "0
+// open ports" apart as a dead host, a firewall dropping everything, or
+// something else entirely.
+type ErrorStats struct {
+	Timeout            int64 `json:"timeout"`
+	ConnectionRefused  int64 `json:"connectionRefused"`
+	NetworkUnreachable int64 `json:"networkUnreachable"`
+	NoRouteToHost      int64 `json:"noRouteToHost"`
+	Other              int64 `json:"other"`
+
+	Examples map[string]string `json:"examples,omitempty"`
+}
+
+// Total is the sum of every category's count, e.g. to skip printing the
+// report entirely when a scan had no dial errors at all.
+func (s ErrorStats) Total() int64 {
+	return s.Timeout + s.ConnectionRefused + s.NetworkUnreachable + s.NoRouteToHost + s.Other
+}
+
+// errorTally accumulates dial errors by category across every worker
+// goroutine. Counts are atomic since Record is on the same hot path as
+// scanStats.Record; the examples map is the one exception that needs its
+// own mutex, the same way main's filteredAddrs does for -verify.
+type errorTally struct {
+	timeout            int64
+	connRefused        int64
+	networkUnreachable int64
+	noRoute            int64
+	other              int64
+
+	mu       sync.Mutex
+	examples map[errorCategory]string
+}
+
+func newErrorTally() *errorTally {
+	return &errorTally{examples: make(map[errorCategory]string)}
+}
+
+// Record tallies one dial's error. A nil err (the common case: most dials
+// succeed or come back a clean refusal/timeout already reflected in
+// scanStats) is a no-op.
+func (t *errorTally) Record(err error) {
+	if err == nil {
+		return
+	}
+	cat := classifyError(err)
+	switch cat {
+	case errCategoryTimeout:
+		atomic.AddInt64(&t.timeout, 1)
+	case errCategoryConnRefused:
+		atomic.AddInt64(&t.connRefused, 1)
+	case errCategoryNetworkUnreachable:
+		atomic.AddInt64(&t.networkUnreachable, 1)
+	case errCategoryNoRoute:
+		atomic.AddInt64(&t.noRoute, 1)
+	default:
+		atomic.AddInt64(&t.other, 1)
+	}
+
+	t.mu.Lock()
+	if _, ok := t.examples[cat]; !ok {
+		t.examples[cat] = err.Error()
+	}
+	t.mu.Unlock()
+}
+
+// Snapshot reads every counter and example once into an ErrorStats.
+func (t *errorTally) Snapshot() ErrorStats {
+	examples := make(map[string]string, len(t.examples))
+	t.mu.Lock()
+	for cat, msg := range t.examples {
+		examples[cat.String()] = msg
+	}
+	t.mu.Unlock()
+
+	return ErrorStats{
+		Timeout:            atomic.LoadInt64(&t.timeout),
+		ConnectionRefused:  atomic.LoadInt64(&t.connRefused),
+		NetworkUnreachable: atomic.LoadInt64(&t.networkUnreachable),
+		NoRouteToHost:      atomic.LoadInt64(&t.noRoute),
+		Other:              atomic.LoadInt64(&t.other),
+		Examples:           examples,
+	}
+}
+
+// errorReporter is an optional capability a sink can implement to receive
+// the post-scan ErrorStats tally, mirroring statsReporter's Summary. Kept
+// separate from OutputSink for the same reason statsReporter is: most
+// sinks have no notion of a trailing error-report object.
+type errorReporter interface {
+	Errors(ErrorStats) error
+}
@@ -0,0 +1,72 @@
+package main
+
+import (
+	"net"
+	"syscall"
+	"testing"
+)
+
+func TestClassifyError_Categories(t *testing.T) {
+	cases := []struct {
+		name string
+		err  error
+		want errorCategory
+	}{
+		{"timeout", &net.OpError{Op: "dial", Err: timeoutError{}}, errCategoryTimeout},
+		{"connRefused", &net.OpError{Op: "dial", Err: syscall.ECONNREFUSED}, errCategoryConnRefused},
+		{"networkUnreachable", &net.OpError{Op: "dial", Err: syscall.ENETUNREACH}, errCategoryNetworkUnreachable},
+		{"noRoute", &net.OpError{Op: "dial", Err: syscall.EHOSTUNREACH}, errCategoryNoRoute},
+		{"other", &net.OpError{Op: "dial", Err: syscall.EINVAL}, errCategoryOther},
+		{"bareErrno", syscall.ECONNREFUSED, errCategoryConnRefused},
+	}
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			if got := classifyError(c.err); got != c.want {
+				t.Errorf("classifyError(%v) = %v, want %v", c.err, got, c.want)
+			}
+		})
+	}
+}
+
+// timeoutError is a minimal net.Error whose Timeout() is true, standing in
+// for the *net.timeoutError the real dial path would produce.
+type timeoutError struct{}
+
+func (timeoutError) Error() string   { return "i/o timeout" }
+func (timeoutError) Timeout() bool   { return true }
+func (timeoutError) Temporary() bool { return true }
+
+func TestErrorTally_RecordAndSnapshot(t *testing.T) {
+	tally := newErrorTally()
+	tally.Record(nil)
+	tally.Record(&net.OpError{Op: "dial", Err: syscall.ECONNREFUSED})
+	tally.Record(&net.OpError{Op: "dial", Err: syscall.ECONNREFUSED})
+	tally.Record(&net.OpError{Op: "dial", Err: syscall.EHOSTUNREACH})
+
+	snap := tally.Snapshot()
+	if snap.ConnectionRefused != 2 {
+		t.Errorf("ConnectionRefused = %d, want 2", snap.ConnectionRefused)
+	}
+	if snap.NoRouteToHost != 1 {
+		t.Errorf("NoRouteToHost = %d, want 1", snap.NoRouteToHost)
+	}
+	if snap.Total() != 3 {
+		t.Errorf("Total() = %d, want 3", snap.Total())
+	}
+	if _, ok := snap.Examples["connection refused"]; !ok {
+		t.Error("Examples missing an entry for connection refused")
+	}
+}
+
+func TestErrorTally_RecordKeepsFirstExamplePerCategory(t *testing.T) {
+	tally := newErrorTally()
+	first := &net.OpError{Op: "dial", Addr: &net.TCPAddr{}, Err: syscall.ECONNREFUSED}
+	second := &net.OpError{Op: "dial", Addr: &net.TCPAddr{Port: 22}, Err: syscall.ECONNREFUSED}
+	tally.Record(first)
+	tally.Record(second)
+
+	snap := tally.Snapshot()
+	if got := snap.Examples["connection refused"]; got != first.Error() {
+		t.Errorf("Examples[connection refused] = %q, want the first error recorded (%q)", got, first.Error())
+	}
+}
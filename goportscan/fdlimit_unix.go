@@ -0,0 +1,17 @@
+//go:build !windows
+
+package main
+
+import "golang.org/x/sys/unix"
+
+// softFDLimit returns the process's current (soft) open-file-descriptor
+// limit, RLIMIT_NOFILE, so the worker pool can be sized to stay under it
+// instead of discovering the ceiling the hard way via "too many open files"
+// errors mid-scan.
+func softFDLimit() (uint64, error) {
+	var rlimit unix.Rlimit
+	if err := unix.Getrlimit(unix.RLIMIT_NOFILE, &rlimit); err != nil {
+		return 0, err
+	}
+	return rlimit.Cur, nil
+}
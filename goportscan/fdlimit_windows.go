@@ -0,0 +1,12 @@
+//go:build windows
+
+package main
+
+import "errors"
+
+// softFDLimit isn't meaningful on Windows: handle limits aren't governed by
+// a POSIX-style RLIMIT_NOFILE, so there's nothing equivalent to query.
+// Callers fall back to the requested worker count unchanged.
+func softFDLimit() (uint64, error) {
+	return 0, errors.New("fd limit detection is not supported on windows")
+}
@@ -0,0 +1,127 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+)
+
+// fingerprintPort is one port's recorded state in a host's fingerprint: the
+// state it was found in and whatever service info was identified for it,
+// so a later run can tell a port apart that's still open with the same
+// banner from one that merely stayed open.
+type fingerprintPort struct {
+	Port    int    `json:"port"`
+	State   string `json:"state"`
+	Service string `json:"service,omitempty"`
+	Version string `json:"version,omitempty"`
+	Banner  string `json:"banner,omitempty"`
+}
+
+// hostFingerprint is one host's recorded port states, written to
+// <fingerprint-dir>/<host>.json after a scan and read back on the next run
+// so -fingerprint-dir can skip full service/banner detection on any port
+// whose open/closed state hasn't changed since then.
+type hostFingerprint struct {
+	Host  string            `json:"host"`
+	Ports []fingerprintPort `json:"ports"`
+}
+
+// fingerprintPath is the file a host's fingerprint lives at under dir, the
+// same sanitizeFilename-based naming -out-dir's perHostSink uses.
+func fingerprintPath(dir, host string) string {
+	return filepath.Join(dir, sanitizeFilename(host)+".json")
+}
+
+// loadFingerprints reads every *.json file in dir into a host -> port ->
+// fingerprintPort map. A missing dir is treated the same as an empty one
+// (the first run against a fresh -fingerprint-dir), so every port falls
+// back to a full scan rather than erroring. A file that doesn't parse is
+// reported and skipped, the same tolerance loadCheckpoint gives a
+// truncated checkpoint file, rather than failing the whole scan over one
+// corrupt fingerprint.
+func loadFingerprints(dir string) (map[string]map[int]fingerprintPort, error) {
+	fingerprints := map[string]map[int]fingerprintPort{}
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return fingerprints, nil
+		}
+		return nil, fmt.Errorf("fingerprint-dir: %w", err)
+	}
+
+	for _, entry := range entries {
+		if entry.IsDir() || filepath.Ext(entry.Name()) != ".json" {
+			continue
+		}
+		path := filepath.Join(dir, entry.Name())
+		data, err := os.ReadFile(path)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "warning: %s: %v, skipping\n", path, err)
+			continue
+		}
+		var fp hostFingerprint
+		if err := json.Unmarshal(data, &fp); err != nil {
+			fmt.Fprintf(os.Stderr, "warning: %s: malformed fingerprint, skipping: %v\n", path, err)
+			continue
+		}
+		ports := make(map[int]fingerprintPort, len(fp.Ports))
+		for _, p := range fp.Ports {
+			ports[p.Port] = p
+		}
+		fingerprints[fp.Host] = ports
+	}
+	return fingerprints, nil
+}
+
+// fingerprintUnchanged reports whether addr's port was seen in the same
+// state on a previous scan recorded in prior, returning that prior
+// fingerprintPort so the caller can reuse its service/version/banner
+// instead of re-probing for them.
+func fingerprintUnchanged(prior map[string]map[int]fingerprintPort, addr Addr, state portState) (fingerprintPort, bool) {
+	ports, ok := prior[addr.Display()]
+	if !ok {
+		return fingerprintPort{}, false
+	}
+	p, ok := ports[addr.Port]
+	if !ok || p.State != string(state) {
+		return fingerprintPort{}, false
+	}
+	return p, true
+}
+
+// recordFingerprint folds one result into fingerprints, the running store
+// -fingerprint-dir writes out once the scan finishes. Callers touching
+// fingerprints from more than one goroutine must serialize their own calls;
+// recordFingerprint itself does no locking.
+func recordFingerprint(fingerprints map[string]map[int]fingerprintPort, host string, port fingerprintPort) {
+	ports, ok := fingerprints[host]
+	if !ok {
+		ports = map[int]fingerprintPort{}
+		fingerprints[host] = ports
+	}
+	ports[port.Port] = port
+}
+
+// saveFingerprints writes one <fingerprint-dir>/<host>.json file per host in
+// fingerprints, creating dir (and any missing parents) first.
+func saveFingerprints(dir string, fingerprints map[string]map[int]fingerprintPort) error {
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return fmt.Errorf("fingerprint-dir: %w", err)
+	}
+	for host, ports := range fingerprints {
+		fp := hostFingerprint{Host: host, Ports: make([]fingerprintPort, 0, len(ports))}
+		for _, p := range ports {
+			fp.Ports = append(fp.Ports, p)
+		}
+		data, err := json.MarshalIndent(fp, "", "  ")
+		if err != nil {
+			return fmt.Errorf("fingerprint-dir: %w", err)
+		}
+		if err := os.WriteFile(fingerprintPath(dir, host), data, 0o644); err != nil {
+			return fmt.Errorf("fingerprint-dir: %w", err)
+		}
+	}
+	return nil
+}
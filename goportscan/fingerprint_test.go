@@ -0,0 +1,94 @@
+package main
+
+import (
+	"net"
+	"path/filepath"
+	"testing"
+)
+
+func TestLoadFingerprints_MissingDirIsTreatedAsEmpty(t *testing.T) {
+	fingerprints, err := loadFingerprints(filepath.Join(t.TempDir(), "does-not-exist"))
+	if err != nil {
+		t.Fatalf("loadFingerprints: %v", err)
+	}
+	if len(fingerprints) != 0 {
+		t.Errorf("got %d host(s), want 0 for a missing dir", len(fingerprints))
+	}
+}
+
+func TestSaveAndLoadFingerprints_RoundTrips(t *testing.T) {
+	dir := t.TempDir()
+	fingerprints := map[string]map[int]fingerprintPort{
+		"10.0.0.1": {22: {Port: 22, State: "open", Service: "ssh", Version: "OpenSSH_9.0"}},
+	}
+	if err := saveFingerprints(dir, fingerprints); err != nil {
+		t.Fatalf("saveFingerprints: %v", err)
+	}
+
+	loaded, err := loadFingerprints(dir)
+	if err != nil {
+		t.Fatalf("loadFingerprints: %v", err)
+	}
+	got, ok := loaded["10.0.0.1"][22]
+	if !ok {
+		t.Fatal("loaded fingerprint missing 10.0.0.1:22")
+	}
+	if got.State != "open" || got.Service != "ssh" || got.Version != "OpenSSH_9.0" {
+		t.Errorf("loaded port = %+v, want the saved values round-tripped", got)
+	}
+}
+
+// TestFingerprintUnchanged_NewHostNeedsFullScan covers the "new host" case:
+// a host absent from the prior fingerprints always gets a full scan, since
+// there's nothing to compare its current state against.
+func TestFingerprintUnchanged_NewHostNeedsFullScan(t *testing.T) {
+	prior := map[string]map[int]fingerprintPort{}
+	addr := Addr{IP: net.ParseIP("10.0.0.9"), Port: 22}
+
+	if _, ok := fingerprintUnchanged(prior, addr, portOpen); ok {
+		t.Error("fingerprintUnchanged = true for a host with no prior fingerprint, want false")
+	}
+}
+
+// TestFingerprintUnchanged_ChangedPortNeedsFullScan covers the "changed
+// port" case: a port whose state differs from its last recorded
+// fingerprint must not be treated as unchanged, even though the host
+// itself was seen before.
+func TestFingerprintUnchanged_ChangedPortNeedsFullScan(t *testing.T) {
+	prior := map[string]map[int]fingerprintPort{
+		"10.0.0.1": {22: {Port: 22, State: "closed"}},
+	}
+	addr := Addr{IP: net.ParseIP("10.0.0.1"), Port: 22}
+
+	if _, ok := fingerprintUnchanged(prior, addr, portOpen); ok {
+		t.Error("fingerprintUnchanged = true for a port whose state changed since the prior scan, want false")
+	}
+}
+
+func TestFingerprintUnchanged_SameStateReusesPriorServiceInfo(t *testing.T) {
+	prior := map[string]map[int]fingerprintPort{
+		"10.0.0.1": {22: {Port: 22, State: "open", Service: "ssh", Version: "OpenSSH_9.0", Banner: "SSH-2.0-OpenSSH_9.0"}},
+	}
+	addr := Addr{IP: net.ParseIP("10.0.0.1"), Port: 22}
+
+	got, ok := fingerprintUnchanged(prior, addr, portOpen)
+	if !ok {
+		t.Fatal("fingerprintUnchanged = false for a port whose state matches the prior scan, want true")
+	}
+	if got.Service != "ssh" || got.Version != "OpenSSH_9.0" || got.Banner != "SSH-2.0-OpenSSH_9.0" {
+		t.Errorf("got %+v, want the prior scan's service info", got)
+	}
+}
+
+func TestRecordFingerprint_CreatesHostEntryOnFirstUse(t *testing.T) {
+	fingerprints := map[string]map[int]fingerprintPort{}
+	recordFingerprint(fingerprints, "10.0.0.1", fingerprintPort{Port: 80, State: "open"})
+	recordFingerprint(fingerprints, "10.0.0.1", fingerprintPort{Port: 443, State: "closed"})
+
+	if len(fingerprints["10.0.0.1"]) != 2 {
+		t.Errorf("got %d port(s) for 10.0.0.1, want 2", len(fingerprints["10.0.0.1"]))
+	}
+	if fingerprints["10.0.0.1"][80].State != "open" {
+		t.Errorf("port 80 state = %q, want open", fingerprints["10.0.0.1"][80].State)
+	}
+}
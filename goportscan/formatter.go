@@ -0,0 +1,152 @@
+package main
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"io"
+	"sort"
+)
+
+// ScanReport bundles a finished scan's results and timing into the single
+// value a Formatter renders in one call, as opposed to OutputSink's
+// Open/Emit/Finish/Close lifecycle, which streams one result at a time as
+// the scan progresses.
+type ScanReport struct {
+	Results []ScanResult `json:"results"`
+	Meta    ScanMeta     `json:"meta"`
+}
+
+// Formatter renders a complete ScanReport to w in one call. It's the
+// extension point for a library user embedding goportscan who wants their
+// own reporting format -- see RegisterFormatter -- without reimplementing
+// OutputSink's streaming lifecycle or forking this package.
+type Formatter interface {
+	WriteResults(w io.Writer, scan ScanReport) error
+}
+
+// formatterRegistry holds every Formatter available by name, seeded with
+// the built-in text/json/csv/xml formatters below. newOutputSink's -o (and
+// -out-format) still take priority for these same names, since the
+// streaming OutputSink implementations are what -o/-out-file have always
+// used; the registry is consulted as a fallback for a name none of them
+// recognize, so a RegisterFormatter call with a new name becomes selectable
+// the same way.
+var formatterRegistry = map[string]Formatter{
+	"text": textFormatter{},
+	"json": jsonFormatter{},
+	"csv":  csvFormatter{},
+	"xml":  xmlFormatter{},
+}
+
+// RegisterFormatter adds or replaces name in the formatter registry, so a
+// library user embedding goportscan can plug in their own reporting format
+// -- -out-format falls back to it for any name that isn't one of the
+// built-in streaming OutputSink formats -- without forking this package.
+func RegisterFormatter(name string, f Formatter) {
+	formatterRegistry[name] = f
+}
+
+// formatterNames returns the registry's keys, sorted, for -out-format's
+// -help text.
+func formatterNames() []string {
+	names := make([]string, 0, len(formatterRegistry))
+	for name := range formatterRegistry {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// newFormatterSink adapts a registered Formatter to OutputSink, so
+// newOutputSink's fallback can hand it the same Open/Emit/Finish/Close
+// lifecycle every other sink gets: every Emit is buffered and the
+// Formatter only runs once, in Close, once the full ScanReport is known.
+func newFormatterSink(w io.Writer, f Formatter) *formatterSink {
+	return &formatterSink{w: w, formatter: f}
+}
+
+type formatterSink struct {
+	w         io.Writer
+	formatter Formatter
+	results   []ScanResult
+	meta      ScanMeta
+}
+
+func (s *formatterSink) Open() error { return nil }
+
+func (s *formatterSink) Emit(r ScanResult) error {
+	s.results = append(s.results, r)
+	return nil
+}
+
+func (s *formatterSink) Finish(meta ScanMeta) error {
+	s.meta = meta
+	return nil
+}
+
+func (s *formatterSink) Close() error {
+	return s.formatter.WriteResults(s.w, ScanReport{Results: s.results, Meta: s.meta})
+}
+
+// textFormatter renders a ScanReport the same way textSink streams it,
+// just in one pass over the already-collected results.
+type textFormatter struct{}
+
+func (textFormatter) WriteResults(w io.Writer, scan ScanReport) error {
+	sink := &textSink{w: w}
+	for _, r := range scan.Results {
+		if err := sink.Emit(r); err != nil {
+			return err
+		}
+	}
+	return sink.Finish(scan.Meta)
+}
+
+// jsonFormatter renders a ScanReport as a single JSON object. Unlike the
+// "json"/"jsonl" OutputSink formats, which stream one object per result as
+// the scan progresses, a Formatter only ever sees the finished report, so
+// one encode call is the natural shape rather than replaying jsonSink's
+// line-at-a-time API.
+type jsonFormatter struct{}
+
+func (jsonFormatter) WriteResults(w io.Writer, scan ScanReport) error {
+	return json.NewEncoder(w).Encode(scan)
+}
+
+// csvFormatter renders a ScanReport's results as CSV rows, reusing
+// csvSink's column layout so a -out-format csv archive and a library
+// caller's csvFormatter output line up.
+type csvFormatter struct{}
+
+func (csvFormatter) WriteResults(w io.Writer, scan ScanReport) error {
+	cw := csv.NewWriter(w)
+	sink := &csvSink{w: cw}
+	if err := sink.Open(); err != nil {
+		return err
+	}
+	for _, r := range scan.Results {
+		if err := sink.Emit(r); err != nil {
+			return err
+		}
+	}
+	if err := sink.Finish(scan.Meta); err != nil {
+		return err
+	}
+	cw.Flush()
+	return cw.Error()
+}
+
+// xmlFormatter renders a ScanReport as a single nmap-style XML document,
+// reusing xmlSink's Close, which already does exactly this once every
+// result has been buffered.
+type xmlFormatter struct{}
+
+func (xmlFormatter) WriteResults(w io.Writer, scan ScanReport) error {
+	sink := &xmlSink{w: w, meta: scan.Meta}
+	for _, r := range scan.Results {
+		if err := sink.Emit(r); err != nil {
+			return err
+		}
+	}
+	return sink.Close()
+}
@@ -0,0 +1,130 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"encoding/xml"
+	"io"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestJSONFormatter_EncodesResultsAndMetaAsOneObject(t *testing.T) {
+	started := time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC)
+	scan := ScanReport{
+		Results: []ScanResult{{Host: "10.0.0.1", Port: 22, State: "open"}},
+		Meta:    newScanMeta(started, started.Add(time.Second)),
+	}
+
+	var buf bytes.Buffer
+	if err := (jsonFormatter{}).WriteResults(&buf, scan); err != nil {
+		t.Fatalf("WriteResults: %v", err)
+	}
+
+	var decoded ScanReport
+	if err := json.Unmarshal(buf.Bytes(), &decoded); err != nil {
+		t.Fatalf("unmarshal: %v", err)
+	}
+	if len(decoded.Results) != 1 || decoded.Results[0].Host != "10.0.0.1" {
+		t.Errorf("decoded.Results = %+v, want one result for 10.0.0.1", decoded.Results)
+	}
+}
+
+func TestCSVFormatter_WritesHeaderAndRows(t *testing.T) {
+	scan := ScanReport{Results: []ScanResult{{Host: "10.0.0.1", Port: 80, State: "open", Service: "http"}}}
+
+	var buf bytes.Buffer
+	if err := (csvFormatter{}).WriteResults(&buf, scan); err != nil {
+		t.Fatalf("WriteResults: %v", err)
+	}
+
+	out := buf.String()
+	if !strings.Contains(out, "host,port,protocol,state,service") {
+		t.Errorf("output %q missing the csvSink header row", out)
+	}
+	if !strings.Contains(out, "10.0.0.1,80,tcp,open,http") {
+		t.Errorf("output %q missing the expected result row", out)
+	}
+}
+
+func TestXMLFormatter_RendersNmapCompatibleDocument(t *testing.T) {
+	scan := ScanReport{Results: []ScanResult{{Host: "10.0.0.1", Port: 22, Service: "ssh"}}}
+
+	var buf bytes.Buffer
+	if err := (xmlFormatter{}).WriteResults(&buf, scan); err != nil {
+		t.Fatalf("WriteResults: %v", err)
+	}
+
+	var run nmapRun
+	if err := xml.Unmarshal(buf.Bytes(), &run); err != nil {
+		t.Fatalf("unmarshal xml: %v\n%s", err, buf.String())
+	}
+	if len(run.Hosts) != 1 || run.Hosts[0].Address.Addr != "10.0.0.1" {
+		t.Errorf("got hosts %+v, want one host 10.0.0.1", run.Hosts)
+	}
+}
+
+func TestTextFormatter_WritesOneLinePerResult(t *testing.T) {
+	scan := ScanReport{Results: []ScanResult{{Host: "10.0.0.1", Port: 22, State: "open"}}}
+
+	var buf bytes.Buffer
+	if err := (textFormatter{}).WriteResults(&buf, scan); err != nil {
+		t.Fatalf("WriteResults: %v", err)
+	}
+
+	if !strings.Contains(buf.String(), "10.0.0.1:22/tcp") {
+		t.Errorf("output missing the expected result line, got %q", buf.String())
+	}
+}
+
+func TestRegisterFormatter_MakesANewOutFormatNameSelectable(t *testing.T) {
+	RegisterFormatter("test-custom", upperHostFormatter{})
+	defer delete(formatterRegistry, "test-custom")
+
+	sink, err := newOutputSink("test-custom", &bytes.Buffer{}, "", false, 0)
+	if err != nil {
+		t.Fatalf("newOutputSink: %v", err)
+	}
+	if err := sink.Open(); err != nil {
+		t.Fatalf("Open: %v", err)
+	}
+	if err := sink.Emit(ScanResult{Host: "10.0.0.1", Port: 22, State: "open"}); err != nil {
+		t.Fatalf("Emit: %v", err)
+	}
+	if err := sink.Finish(ScanMeta{}); err != nil {
+		t.Fatalf("Finish: %v", err)
+	}
+
+	var buf bytes.Buffer
+	fs, ok := sink.(*formatterSink)
+	if !ok {
+		t.Fatalf("newOutputSink returned %T, want *formatterSink", sink)
+	}
+	fs.w = &buf
+	if err := fs.Close(); err != nil {
+		t.Fatalf("Close: %v", err)
+	}
+	if buf.String() != "10.0.0.1" {
+		t.Errorf("Close output = %q, want the upperHostFormatter's rendering", buf.String())
+	}
+}
+
+func TestNewOutputSink_UnregisteredFormatStillErrors(t *testing.T) {
+	if _, err := newOutputSink("definitely-not-registered", &bytes.Buffer{}, "", false, 0); err == nil {
+		t.Error("newOutputSink(\"definitely-not-registered\", ...) expected an error, got nil")
+	}
+}
+
+// upperHostFormatter is a minimal stand-in for a library user's custom
+// Formatter, used to prove RegisterFormatter's registry is actually
+// consulted rather than just stored.
+type upperHostFormatter struct{}
+
+func (upperHostFormatter) WriteResults(w io.Writer, scan ScanReport) error {
+	if len(scan.Results) == 0 {
+		return nil
+	}
+	_, err := w.Write([]byte(scan.Results[0].Host))
+	return err
+}
@@ -0,0 +1,34 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+)
+
+// portHostGroups accumulates, for -horizontal mode, which hosts had a given
+// port open, so the scan summary can report it grouped by port ("Port 22
+// open on: host1, host2") instead of the usual per-host listing. Like
+// baselineSnapshot, it's mutated from a single call site per result and
+// isn't concurrency-safe on its own -- callers guard it the same way they
+// already guard recordOpenPort.
+type portHostGroups map[int][]string
+
+// recordOpenHost appends host to port's group, in the order results arrive.
+func recordOpenHost(groups portHostGroups, port int, host string) {
+	groups[port] = append(groups[port], host)
+}
+
+// printPortHostGroups writes groups' ports ascending, each with its hosts
+// in the order recordOpenHost saw them.
+func printPortHostGroups(w io.Writer, groups portHostGroups) {
+	ports := make([]int, 0, len(groups))
+	for p := range groups {
+		ports = append(ports, p)
+	}
+	sort.Ints(ports)
+	for _, p := range ports {
+		fmt.Fprintf(w, "Port %d open on: %s\n", p, strings.Join(groups[p], ", "))
+	}
+}
@@ -0,0 +1,34 @@
+package main
+
+import (
+	"bytes"
+	"testing"
+)
+
+func TestRecordOpenHost_GroupsByPortInArrivalOrder(t *testing.T) {
+	groups := portHostGroups{}
+	recordOpenHost(groups, 22, "10.0.0.2")
+	recordOpenHost(groups, 80, "10.0.0.1")
+	recordOpenHost(groups, 22, "10.0.0.1")
+
+	if got := groups[22]; len(got) != 2 || got[0] != "10.0.0.2" || got[1] != "10.0.0.1" {
+		t.Errorf("groups[22] = %v, want [10.0.0.2 10.0.0.1]", got)
+	}
+	if got := groups[80]; len(got) != 1 || got[0] != "10.0.0.1" {
+		t.Errorf("groups[80] = %v, want [10.0.0.1]", got)
+	}
+}
+
+func TestPrintPortHostGroups_SortsPortsAscending(t *testing.T) {
+	groups := portHostGroups{
+		443: {"10.0.0.1"},
+		22:  {"10.0.0.1", "10.0.0.2"},
+	}
+	var buf bytes.Buffer
+	printPortHostGroups(&buf, groups)
+
+	want := "Port 22 open on: 10.0.0.1, 10.0.0.2\nPort 443 open on: 10.0.0.1\n"
+	if buf.String() != want {
+		t.Errorf("printPortHostGroups = %q, want %q", buf.String(), want)
+	}
+}
@@ -0,0 +1,46 @@
+package main
+
+import (
+	"context"
+	"sync"
+)
+
+// runWithHostConcurrency runs each of feeders, at most concurrency of them
+// active at once, and returns once every feeder has finished or ctx is
+// done. It's the only thing -host-concurrency changes about dispatch: each
+// feeder still writes into the same addrs channel that the (separately
+// sized) -workers pool drains, so raising or lowering concurrency changes
+// how many hosts are queued up at once, never how many sockets are open at
+// once.
+func runWithHostConcurrency(ctx context.Context, concurrency int, feeders []func()) {
+	if concurrency <= 0 || concurrency > len(feeders) {
+		concurrency = len(feeders)
+	}
+	if concurrency < 1 {
+		concurrency = 1
+	}
+
+	sem := make(chan struct{}, concurrency)
+	var wg sync.WaitGroup
+	for _, feed := range feeders {
+		select {
+		case <-ctx.Done():
+			wg.Wait()
+			return
+		default:
+		}
+		select {
+		case sem <- struct{}{}:
+		case <-ctx.Done():
+			wg.Wait()
+			return
+		}
+		wg.Add(1)
+		go func(feed func()) {
+			defer wg.Done()
+			defer func() { <-sem }()
+			feed()
+		}(feed)
+	}
+	wg.Wait()
+}
@@ -0,0 +1,117 @@
+package main
+
+import (
+	"context"
+	"net"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// TestRunWithHostConcurrency_CapsActiveHostsButScansAll starts three real TCP
+// listeners on distinct loopback addresses, feeds one per host through
+// runWithHostConcurrency with a concurrency of 2, and checks both that no
+// more than 2 feeders ever ran at once and that all 3 hosts were still
+// reached.
+func TestRunWithHostConcurrency_CapsActiveHostsButScansAll(t *testing.T) {
+	loopbackIPs := []string{"127.0.0.1", "127.0.0.2", "127.0.0.3"}
+	var listeners []net.Listener
+	for _, ip := range loopbackIPs {
+		ln, err := net.Listen("tcp", net.JoinHostPort(ip, "0"))
+		if err != nil {
+			t.Skipf("listen on %s: %v (loopback aliasing unavailable in this environment)", ip, err)
+		}
+		defer ln.Close()
+		go func(ln net.Listener) {
+			for {
+				conn, err := ln.Accept()
+				if err != nil {
+					return
+				}
+				conn.Close()
+			}
+		}(ln)
+		listeners = append(listeners, ln)
+	}
+
+	var active, maxActive int32
+	var mu sync.Mutex
+	reached := map[string]bool{}
+
+	var feeders []func()
+	for _, ln := range listeners {
+		addr := ln.Addr().String()
+		feeders = append(feeders, func() {
+			n := atomic.AddInt32(&active, 1)
+			for {
+				old := atomic.LoadInt32(&maxActive)
+				if n <= old || atomic.CompareAndSwapInt32(&maxActive, old, n) {
+					break
+				}
+			}
+			defer atomic.AddInt32(&active, -1)
+
+			conn, err := net.DialTimeout("tcp", addr, time.Second)
+			if err == nil {
+				conn.Close()
+				mu.Lock()
+				reached[addr] = true
+				mu.Unlock()
+			}
+			// Hold the slot briefly so a concurrency-2 run can't accidentally
+			// look like concurrency-3 just because everything finished instantly.
+			time.Sleep(20 * time.Millisecond)
+		})
+	}
+
+	runWithHostConcurrency(context.Background(), 2, feeders)
+
+	if int(maxActive) > 2 {
+		t.Errorf("max concurrently active feeders = %d, want at most 2", maxActive)
+	}
+	if len(reached) != len(listeners) {
+		t.Errorf("reached %d/%d hosts, want all of them scanned despite the concurrency cap", len(reached), len(listeners))
+	}
+}
+
+func TestRunWithHostConcurrency_ZeroMeansUnlimited(t *testing.T) {
+	var active, maxActive int32
+	feeders := make([]func(), 5)
+	for i := range feeders {
+		feeders[i] = func() {
+			n := atomic.AddInt32(&active, 1)
+			defer atomic.AddInt32(&active, -1)
+			for {
+				old := atomic.LoadInt32(&maxActive)
+				if n <= old || atomic.CompareAndSwapInt32(&maxActive, old, n) {
+					break
+				}
+			}
+			time.Sleep(10 * time.Millisecond)
+		}
+	}
+
+	runWithHostConcurrency(context.Background(), 0, feeders)
+
+	if maxActive != 5 {
+		t.Errorf("max concurrently active feeders = %d, want all 5 to run at once when concurrency is 0 (unlimited)", maxActive)
+	}
+}
+
+func TestRunWithHostConcurrency_StopsEarlyWhenContextDone(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	var ran int32
+	feeders := make([]func(), 10)
+	for i := range feeders {
+		feeders[i] = func() { atomic.AddInt32(&ran, 1) }
+	}
+
+	runWithHostConcurrency(ctx, 1, feeders)
+
+	if ran == 10 {
+		t.Error("expected a cancelled context to stop dispatch before every feeder ran")
+	}
+}
@@ -0,0 +1,90 @@
+package main
+
+import (
+	"context"
+	"crypto/tls"
+	"io"
+	"net"
+	"net/http"
+	"regexp"
+	"strings"
+	"time"
+)
+
+// HTTPInfo is what probeHTTPTitle managed to learn from a GET / against an
+// HTTP(S) port: the response status and the page <title>, if any.
+type HTTPInfo struct {
+	StatusCode int
+	Title      string
+}
+
+// httpProbeBodyLimit caps how much of the response body probeHTTPTitle
+// reads looking for a <title>, so a port that serves a huge or infinite
+// response can't turn -http-probe into a download.
+const httpProbeBodyLimit = 64 * 1024
+
+var titleRE = regexp.MustCompile(`(?is)<title[^>]*>\s*(.*?)\s*</title>`)
+
+// httpProbeClient is a net/http client whose Transport dials through
+// dialTCP, so -http-probe honors -timeout and -proxy exactly like every
+// other probe in this package instead of opening its own direct
+// connections. CheckRedirect follows at most one hop: a title worth seeing
+// is rarely more than one redirect away, and an open-ended chain risks
+// turning a single port probe into a crawl.
+func httpProbeClient(timeout time.Duration) *http.Client {
+	return &http.Client{
+		Timeout: timeout,
+		Transport: &http.Transport{
+			DialContext: func(ctx context.Context, network, addr string) (net.Conn, error) {
+				return dialTCP(ctx, addr, timeout)
+			},
+			TLSClientConfig: &tls.Config{InsecureSkipVerify: true},
+		},
+		CheckRedirect: func(req *http.Request, via []*http.Request) error {
+			if len(via) >= 2 {
+				return http.ErrUseLastResponse
+			}
+			return nil
+		},
+	}
+}
+
+// probeHTTPTitle issues a GET / against addr over scheme ("http" or
+// "https") and extracts the status code and page title.
+func probeHTTPTitle(addr Addr, scheme string, timeout time.Duration) (HTTPInfo, bool) {
+	client := httpProbeClient(timeout)
+	resp, err := client.Get(scheme + "://" + addr.Dial() + "/")
+	if err != nil {
+		return HTTPInfo{}, false
+	}
+	defer resp.Body.Close()
+
+	body, err := io.ReadAll(io.LimitReader(resp.Body, httpProbeBodyLimit))
+	if err != nil {
+		return HTTPInfo{}, false
+	}
+
+	info := HTTPInfo{StatusCode: resp.StatusCode}
+	if m := titleRE.FindSubmatch(body); m != nil {
+		info.Title = strings.Join(strings.Fields(string(m[1])), " ")
+	}
+	return info, true
+}
+
+// looksLikeHTTP reports whether a ServiceInfo (and, with -tls, whether a
+// TLS handshake succeeded) indicates a port worth -http-probing, and which
+// scheme to probe it with.
+func looksLikeHTTP(info ServiceInfo, hasTLS bool) (scheme string, ok bool) {
+	switch info.Name {
+	case "http":
+		return "http", true
+	case "https":
+		return "https", true
+	case "ssl/tls":
+		return "https", true
+	}
+	if hasTLS {
+		return "https", true
+	}
+	return "", false
+}
@@ -0,0 +1,93 @@
+package main
+
+import (
+	"fmt"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func TestLooksLikeHTTP(t *testing.T) {
+	cases := []struct {
+		name       string
+		hasTLS     bool
+		wantScheme string
+		wantOK     bool
+	}{
+		{"http", false, "http", true},
+		{"https", false, "https", true},
+		{"ssl/tls", false, "https", true},
+		{"ftp", false, "", false},
+		{"", true, "https", true},
+	}
+	for _, c := range cases {
+		scheme, ok := looksLikeHTTP(ServiceInfo{Name: c.name}, c.hasTLS)
+		if scheme != c.wantScheme || ok != c.wantOK {
+			t.Errorf("looksLikeHTTP(%q, %v) = (%q, %v), want (%q, %v)", c.name, c.hasTLS, scheme, ok, c.wantScheme, c.wantOK)
+		}
+	}
+}
+
+func TestProbeHTTPTitle_ExtractsStatusAndTitle(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprint(w, "<html><head><title>Admin Login</title></head><body></body></html>")
+	}))
+	defer srv.Close()
+
+	host, port, _ := net.SplitHostPort(srv.Listener.Addr().String())
+	portNum := 0
+	fmt.Sscanf(port, "%d", &portNum)
+
+	info, ok := probeHTTPTitle(Addr{IP: net.ParseIP(host), Port: portNum}, "http", time.Second)
+	if !ok {
+		t.Fatal("probeHTTPTitle = false, want true")
+	}
+	if info.StatusCode != 200 {
+		t.Errorf("StatusCode = %d, want 200", info.StatusCode)
+	}
+	if info.Title != "Admin Login" {
+		t.Errorf("Title = %q, want %q", info.Title, "Admin Login")
+	}
+}
+
+func TestProbeHTTPTitle_FollowsAtMostOneRedirect(t *testing.T) {
+	redirectCount := 0
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		redirectCount++
+		http.Redirect(w, r, "/", http.StatusFound)
+	}))
+	defer srv.Close()
+
+	host, port, _ := net.SplitHostPort(srv.Listener.Addr().String())
+	portNum := 0
+	fmt.Sscanf(port, "%d", &portNum)
+
+	probeHTTPTitle(Addr{IP: net.ParseIP(host), Port: portNum}, "http", time.Second)
+	if redirectCount != 2 {
+		t.Errorf("server was hit %d times, want exactly 2 (initial request + one followed redirect)", redirectCount)
+	}
+}
+
+func TestProbeHTTPTitle_CapsBodyRead(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprint(w, "<title>ok</title>")
+		for i := 0; i < httpProbeBodyLimit*2; i++ {
+			fmt.Fprint(w, "x")
+		}
+	}))
+	defer srv.Close()
+
+	host, port, _ := net.SplitHostPort(srv.Listener.Addr().String())
+	portNum := 0
+	fmt.Sscanf(port, "%d", &portNum)
+
+	info, ok := probeHTTPTitle(Addr{IP: net.ParseIP(host), Port: portNum}, "http", time.Second)
+	if !ok {
+		t.Fatal("probeHTTPTitle = false, want true")
+	}
+	if info.Title != "ok" {
+		t.Errorf("Title = %q, want %q", info.Title, "ok")
+	}
+}
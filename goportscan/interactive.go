@@ -0,0 +1,155 @@
+package main
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"net"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// runInteractive drops into a simple bufio-based prompt for triaging a
+// completed scan's results -- -interactive, TTY-gated by the caller. It
+// reads commands from in and writes to out until "quit" or EOF, then
+// returns so main's usual exit-code logic runs next. timeout, confirmOpen,
+// and openGrace are the same probe settings the scan itself used, so
+// "reprobe" re-checks a port the identical way.
+func runInteractive(in io.Reader, out io.Writer, results []ScanResult, timeout time.Duration, confirmOpen bool, openGrace time.Duration) {
+	fmt.Fprintf(out, "\nEntering interactive mode (%d result(s)). Commands: filter host|port|service|state <value>, show host <host>, reprobe <host> <port>, quit\n", len(results))
+	scanner := bufio.NewScanner(in)
+	for {
+		fmt.Fprint(out, "goportscan> ")
+		if !scanner.Scan() {
+			fmt.Fprintln(out)
+			return
+		}
+		fields := strings.Fields(scanner.Text())
+		if len(fields) == 0 {
+			continue
+		}
+		switch fields[0] {
+		case "quit", "exit":
+			return
+		case "filter":
+			if len(fields) != 3 {
+				fmt.Fprintln(out, "usage: filter host|port|service|state <value>")
+				continue
+			}
+			matches, err := filterResults(results, fields[1], fields[2])
+			if err != nil {
+				fmt.Fprintln(out, "error:", err)
+				continue
+			}
+			printResultLines(out, matches)
+			fmt.Fprintf(out, "%d result(s)\n", len(matches))
+		case "show":
+			if len(fields) != 3 || fields[1] != "host" {
+				fmt.Fprintln(out, "usage: show host <host>")
+				continue
+			}
+			showHost(out, results, fields[2])
+		case "reprobe":
+			if len(fields) != 3 {
+				fmt.Fprintln(out, "usage: reprobe <host> <port>")
+				continue
+			}
+			port, err := strconv.Atoi(fields[2])
+			if err != nil {
+				fmt.Fprintln(out, "error: invalid port:", fields[2])
+				continue
+			}
+			reprobe(out, fields[1], port, timeout, confirmOpen, openGrace)
+		default:
+			fmt.Fprintf(out, "unknown command %q\n", fields[0])
+		}
+	}
+}
+
+// filterResults narrows results to those whose host/port/service/state
+// equals value, service matched case-insensitively since that's how it's
+// identified and displayed everywhere else.
+func filterResults(results []ScanResult, field, value string) ([]ScanResult, error) {
+	var match func(r ScanResult) bool
+	switch field {
+	case "host":
+		match = func(r ScanResult) bool { return r.Host == value }
+	case "port":
+		port, err := strconv.Atoi(value)
+		if err != nil {
+			return nil, fmt.Errorf("invalid port %q", value)
+		}
+		match = func(r ScanResult) bool { return r.Port == port }
+	case "service":
+		match = func(r ScanResult) bool { return strings.EqualFold(r.Service, value) }
+	case "state":
+		match = func(r ScanResult) bool { return r.State == value }
+	default:
+		return nil, fmt.Errorf("unknown filter field %q (want host, port, service, or state)", field)
+	}
+	var matches []ScanResult
+	for _, r := range results {
+		if match(r) {
+			matches = append(matches, r)
+		}
+	}
+	return matches, nil
+}
+
+func printResultLines(out io.Writer, results []ScanResult) {
+	for _, r := range results {
+		fmt.Fprintln(out, formatResultLine(r))
+	}
+}
+
+// formatResultLine is textSink.Emit's line, minus coloring, so the
+// interactive browser's output reads the same as the scanner's usual text
+// format.
+func formatResultLine(r ScanResult) string {
+	state := r.State
+	if state == "" {
+		state = "open"
+	}
+	line := fmt.Sprintf("%s:%d/tcp %-13s %s", hostDisplay(r.Host), r.Port, state, formatService(ServiceInfo{Name: r.Service, Version: r.Version, Banner: r.Banner}))
+	if r.Note != "" {
+		line += fmt.Sprintf(" (%s)", r.Note)
+	}
+	return line
+}
+
+func showHost(out io.Writer, results []ScanResult, host string) {
+	matches, _ := filterResults(results, "host", host)
+	if len(matches) == 0 {
+		fmt.Fprintf(out, "no results for host %s\n", host)
+		return
+	}
+	sort.Slice(matches, func(i, j int) bool { return matches[i].Port < matches[j].Port })
+	fmt.Fprintf(out, "%s: %d result(s)\n", host, len(matches))
+	printResultLines(out, matches)
+}
+
+// reprobe re-checks a single host:port on demand, using the same connect
+// probe (plus -confirm-open/-open-grace, if the scan used them) the main
+// scan loop used, rather than some separate one-off dial.
+func reprobe(out io.Writer, host string, port int, timeout time.Duration, confirmOpen bool, openGrace time.Duration) {
+	addr := Addr{Port: port}
+	if ip := net.ParseIP(host); ip != nil {
+		addr.IP = ip
+	} else {
+		addr.Host = host
+	}
+	var state portState
+	var err error
+	if confirmOpen {
+		state, err = connectProbeConfirmed(addr, timeout, openGrace)
+	} else {
+		state, err = connectProbe(addr, timeout, openGrace)
+	}
+	line := fmt.Sprintf("%s:%d/tcp %-13s", hostDisplay(host), port, state)
+	if err != nil {
+		line += fmt.Sprintf(" (%v)", err)
+	}
+	fmt.Fprintln(out, line)
+}
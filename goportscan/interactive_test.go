@@ -0,0 +1,102 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"net"
+	"strings"
+	"syscall"
+	"testing"
+)
+
+func TestFilterResults(t *testing.T) {
+	results := []ScanResult{
+		{Host: "10.0.0.1", Port: 22, State: "open", Service: "ssh"},
+		{Host: "10.0.0.1", Port: 80, State: "closed", Service: "http"},
+		{Host: "10.0.0.2", Port: 443, State: "open", Service: "HTTP"},
+	}
+
+	cases := []struct {
+		field, value string
+		want         int
+	}{
+		{"host", "10.0.0.1", 2},
+		{"host", "10.0.0.9", 0},
+		{"port", "443", 1},
+		{"service", "http", 2}, // case-insensitive: matches "http" and "HTTP"
+		{"state", "open", 2},
+	}
+	for _, c := range cases {
+		got, err := filterResults(results, c.field, c.value)
+		if err != nil {
+			t.Errorf("filterResults(%q, %q): unexpected error: %v", c.field, c.value, err)
+			continue
+		}
+		if len(got) != c.want {
+			t.Errorf("filterResults(%q, %q) = %d result(s), want %d", c.field, c.value, len(got), c.want)
+		}
+	}
+}
+
+func TestFilterResults_UnknownField(t *testing.T) {
+	if _, err := filterResults(nil, "bogus", "x"); err == nil {
+		t.Fatal("filterResults: expected an error for an unknown field, got nil")
+	}
+}
+
+func TestFilterResults_InvalidPort(t *testing.T) {
+	if _, err := filterResults(nil, "port", "not-a-port"); err == nil {
+		t.Fatal("filterResults: expected an error for a non-numeric port, got nil")
+	}
+}
+
+func TestFormatResultLine(t *testing.T) {
+	line := formatResultLine(ScanResult{Host: "10.0.0.1", Port: 22, State: "open", Service: "ssh", Version: "OpenSSH_8.9p1"})
+	for _, want := range []string{"10.0.0.1:22/tcp", "open", "ssh", "OpenSSH_8.9p1"} {
+		if !strings.Contains(line, want) {
+			t.Errorf("formatResultLine: %q missing %q", line, want)
+		}
+	}
+}
+
+// TestReprobe_UsesPackageDialer confirms reprobe routes its dial through
+// the shared package-level dialer (same path as the original scan, so it
+// picks up -proxy too) rather than dialing directly.
+func TestReprobe_UsesPackageDialer(t *testing.T) {
+	original := dialer
+	defer func() { dialer = original }()
+
+	var dialedAddress string
+	dialer = func(ctx context.Context, network, address string) (net.Conn, error) {
+		dialedAddress = address
+		return nil, syscall.ECONNREFUSED
+	}
+
+	var out bytes.Buffer
+	reprobe(&out, "10.0.0.1", 22, 0, false, 0)
+
+	if dialedAddress != "10.0.0.1:22" {
+		t.Errorf("reprobe dialed %q, want 10.0.0.1:22", dialedAddress)
+	}
+	if !strings.Contains(out.String(), "closed") {
+		t.Errorf("reprobe output %q: expected it to report the port closed", out.String())
+	}
+}
+
+func TestRunInteractive_FilterAndQuit(t *testing.T) {
+	results := []ScanResult{
+		{Host: "10.0.0.1", Port: 22, State: "open", Service: "ssh"},
+		{Host: "10.0.0.1", Port: 80, State: "closed", Service: "http"},
+	}
+	in := strings.NewReader("filter port 22\nquit\n")
+	var out bytes.Buffer
+	runInteractive(in, &out, results, 0, false, 0)
+
+	got := out.String()
+	if !strings.Contains(got, "10.0.0.1:22/tcp") {
+		t.Errorf("runInteractive output missing the filtered result:\n%s", got)
+	}
+	if strings.Contains(got, "10.0.0.1:80/tcp") {
+		t.Errorf("runInteractive output %q: filter port 22 should have excluded port 80", got)
+	}
+}
@@ -0,0 +1,40 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"os/signal"
+)
+
+// newInterruptContext returns a context cancelled on the first SIGINT, the
+// same way signal.NotifyContext would, plus one extra stage: if a second
+// SIGINT arrives before the process has exited on its own, it force-quits
+// immediately. That covers a scan whose first Ctrl-C cancelled the dialing
+// but is then stuck printing partial results -- a huge -o json archive
+// write, say -- with no way back to a shell short of SIGKILL.
+func newInterruptContext() (context.Context, func()) {
+	ctx, cancel := context.WithCancel(context.Background())
+	sigCh := make(chan os.Signal, 2)
+	signal.Notify(sigCh, os.Interrupt)
+
+	go func() {
+		if _, ok := <-sigCh; !ok {
+			return
+		}
+		fmt.Fprintln(os.Stderr, "\nInterrupted; finishing up with partial results (press Ctrl-C again to force quit)")
+		cancel()
+
+		if _, ok := <-sigCh; !ok {
+			return
+		}
+		fmt.Fprintln(os.Stderr, "\nForce quitting")
+		os.Exit(130)
+	}()
+
+	return ctx, func() {
+		signal.Stop(sigCh)
+		close(sigCh)
+		cancel()
+	}
+}
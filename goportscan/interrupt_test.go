@@ -0,0 +1,45 @@
+package main
+
+import (
+	"os"
+	"syscall"
+	"testing"
+	"time"
+)
+
+// TestNewInterruptContext_FirstSignalCancelsContext checks the first half of
+// the two-stage handling: a single SIGINT cancels the returned context, the
+// same way signal.NotifyContext would. The second-SIGINT force-quit isn't
+// covered here since it calls os.Exit, which would kill the test binary.
+func TestNewInterruptContext_FirstSignalCancelsContext(t *testing.T) {
+	ctx, stop := newInterruptContext()
+	defer stop()
+
+	if err := syscall.Kill(os.Getpid(), syscall.SIGINT); err != nil {
+		t.Fatalf("sending SIGINT to self: %v", err)
+	}
+
+	select {
+	case <-ctx.Done():
+	case <-time.After(time.Second):
+		t.Fatal("context was not cancelled within 1s of the first SIGINT")
+	}
+}
+
+// TestNewInterruptContext_StopUnblocksHandlerGoroutine makes sure stop()
+// doesn't leave the internal signal-handling goroutine parked forever on a
+// channel receive once the caller is done with it.
+func TestNewInterruptContext_StopUnblocksHandlerGoroutine(t *testing.T) {
+	_, stop := newInterruptContext()
+	done := make(chan struct{})
+	go func() {
+		stop()
+		close(done)
+	}()
+
+	select {
+	case <-done:
+	case <-time.After(time.Second):
+		t.Fatal("stop() did not return within 1s")
+	}
+}
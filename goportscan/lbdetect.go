@@ -0,0 +1,84 @@
+package main
+
+import (
+	"context"
+	"crypto/sha256"
+	"crypto/tls"
+	"encoding/hex"
+	"sort"
+	"time"
+)
+
+// LBDetectResult is -lb-detect's report for one open port: the distinct
+// banners (and, with -tls, leaf certificate fingerprints) seen across
+// Probes independent reconnects, and whether they actually differed --
+// the signature of a VIP front-ending backends that don't all answer
+// identically.
+type LBDetectResult struct {
+	Probes      int      `json:"probes"`
+	Banners     []string `json:"banners,omitempty"`
+	CertSHA256s []string `json:"certSha256s,omitempty"`
+	Differs     bool     `json:"differs"`
+}
+
+// detectLoadBalancing reconnects to addr n times, each bounded by timeout,
+// and collects the distinct banners seen (the same crude first-bytes read
+// probeFallback uses, since any protocol-aware parsing would have to be
+// redone per service) plus, when withTLS is set, the SHA-256 fingerprint
+// of whichever leaf certificate each handshake presented. Differs is true
+// as soon as more than one distinct value shows up in either set: a load
+// balancer can terminate TLS once in front of backends that then differ in
+// the plaintext they send, or reuse one cert in front of backends that
+// differ in neither -- either is worth flagging independently.
+//
+// A dial or handshake failure on any individual attempt is simply skipped
+// (it contributes no sample) rather than aborting the whole probe, since a
+// single dropped connection out of n shouldn't hide a genuine difference
+// among the rest.
+func detectLoadBalancing(addr Addr, n int, timeout time.Duration, withTLS bool, serverName string) LBDetectResult {
+	result := LBDetectResult{Probes: n}
+	banners := map[string]bool{}
+	certs := map[string]bool{}
+	for i := 0; i < n; i++ {
+		conn, err := dialTCP(context.Background(), addr.Dial(), timeout)
+		if err != nil {
+			continue
+		}
+		if withTLS {
+			tlsConn := tls.Client(conn, &tls.Config{
+				InsecureSkipVerify: true,
+				ServerName:         serverName,
+			})
+			tlsConn.SetDeadline(time.Now().Add(timeout))
+			if err := tlsConn.Handshake(); err == nil {
+				if leaf := tlsConn.ConnectionState().PeerCertificates; len(leaf) > 0 {
+					sum := sha256.Sum256(leaf[0].Raw)
+					certs[hex.EncodeToString(sum[:])] = true
+				}
+			}
+			conn = tlsConn
+		}
+		conn.SetReadDeadline(time.Now().Add(timeout))
+		buf := make([]byte, 256)
+		if read, err := conn.Read(buf); err == nil && read > 0 {
+			banners[string(buf[:read])] = true
+		}
+		conn.Close()
+	}
+	result.Banners = sortedSet(banners)
+	result.CertSHA256s = sortedSet(certs)
+	result.Differs = len(result.Banners) > 1 || len(result.CertSHA256s) > 1
+	return result
+}
+
+// sortedSet returns the keys of a string set in ascending order, so
+// LBDetectResult's banner/cert lists come out deterministic instead of in
+// random map iteration order.
+func sortedSet(set map[string]bool) []string {
+	keys := make([]string, 0, len(set))
+	for k := range set {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
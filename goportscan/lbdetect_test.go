@@ -0,0 +1,79 @@
+package main
+
+import (
+	"net"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// startBannerServer listens on loopback and, for every accepted connection,
+// writes banners[i%len(banners)] before closing it -- standing in for a VIP
+// whose backends don't all answer identically.
+func startBannerServer(t *testing.T, banners []string) int {
+	t.Helper()
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("listen: %v", err)
+	}
+	t.Cleanup(func() { ln.Close() })
+
+	var i int64
+	go func() {
+		for {
+			conn, err := ln.Accept()
+			if err != nil {
+				return
+			}
+			n := atomic.AddInt64(&i, 1) - 1
+			banner := banners[int(n)%len(banners)]
+			go func(c net.Conn, b string) {
+				defer c.Close()
+				c.Write([]byte(b))
+			}(conn, banner)
+		}
+	}()
+	return ln.Addr().(*net.TCPAddr).Port
+}
+
+func TestDetectLoadBalancing_FlagsDifferingBanners(t *testing.T) {
+	port := startBannerServer(t, []string{"backend-A", "backend-B"})
+	addr := Addr{IP: net.ParseIP("127.0.0.1"), Port: port}
+
+	result := detectLoadBalancing(addr, 6, time.Second, false, "")
+	if !result.Differs {
+		t.Errorf("Differs = false, want true across banners %v", result.Banners)
+	}
+	if len(result.Banners) != 2 {
+		t.Errorf("Banners = %v, want exactly 2 distinct values", result.Banners)
+	}
+	if result.Probes != 6 {
+		t.Errorf("Probes = %d, want 6", result.Probes)
+	}
+}
+
+func TestDetectLoadBalancing_NoDiffWhenBannerIsIdentical(t *testing.T) {
+	port := startBannerServer(t, []string{"same-backend"})
+	addr := Addr{IP: net.ParseIP("127.0.0.1"), Port: port}
+
+	result := detectLoadBalancing(addr, 4, time.Second, false, "")
+	if result.Differs {
+		t.Errorf("Differs = true, want false: every connect saw the same banner %v", result.Banners)
+	}
+	if len(result.Banners) != 1 {
+		t.Errorf("Banners = %v, want exactly 1 distinct value", result.Banners)
+	}
+}
+
+func TestSortedSet(t *testing.T) {
+	got := sortedSet(map[string]bool{"b": true, "a": true, "c": true})
+	want := []string{"a", "b", "c"}
+	if len(got) != len(want) {
+		t.Fatalf("sortedSet = %v, want %v", got, want)
+	}
+	for i := range want {
+		if got[i] != want[i] {
+			t.Errorf("sortedSet = %v, want %v", got, want)
+		}
+	}
+}
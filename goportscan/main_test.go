@@ -0,0 +1,200 @@
+package main
+
+import (
+	"bytes"
+	"math/rand"
+	"sort"
+	"strings"
+	"testing"
+	"time"
+)
+
+// TestStaggerDelay_ScalesWithWorkerIndex confirms -stagger's delay grows
+// linearly with worker index and that worker 0 never waits, so -stagger 0
+// (the default) leaves the very first worker's startup behavior unchanged.
+// The worker loop itself lives inline in main() and isn't separately
+// callable -- the same reason -delay/-jitter, the two existing per-dial
+// timing knobs in that same loop, have no test of their own either -- so
+// this covers the one pure, extractable piece of the feature: the delay
+// calculation a test actually can drive directly.
+func TestStaggerDelay_ScalesWithWorkerIndex(t *testing.T) {
+	if got := staggerDelay(0, 50*time.Millisecond); got != 0 {
+		t.Errorf("staggerDelay(0, 50ms) = %v, want 0", got)
+	}
+	if got := staggerDelay(3, 50*time.Millisecond); got != 150*time.Millisecond {
+		t.Errorf("staggerDelay(3, 50ms) = %v, want 150ms", got)
+	}
+	if got := staggerDelay(5, 0); got != 0 {
+		t.Errorf("staggerDelay(5, 0) = %v, want 0: -stagger 0 disables staggering regardless of worker index", got)
+	}
+}
+
+func TestShouldReport_UnreachableIsAlwaysReported(t *testing.T) {
+	if !shouldReport(portUnreachable, false, false) {
+		t.Error("shouldReport(portUnreachable, false, false) = false, want true: a short-circuited host is always worth reporting")
+	}
+}
+
+func TestShufflePorts_ReordersButKeepsTheSameSet(t *testing.T) {
+	ports := make([]int, 100)
+	for i := range ports {
+		ports[i] = i + 1
+	}
+	original := append([]int(nil), ports...)
+
+	shufflePorts(ports, rand.New(rand.NewSource(1)))
+
+	if sameInts(ports, original) {
+		t.Error("shufflePorts left the order unchanged; want it shuffled (vanishingly unlikely by chance with 100 elements)")
+	}
+
+	sorted := append([]int(nil), ports...)
+	sort.Ints(sorted)
+	if !sameInts(sorted, original) {
+		t.Errorf("shufflePorts changed the set of ports: got %v, want a permutation of %v", sorted, original)
+	}
+}
+
+func TestReorderPriorityPorts_MovesPriorityToFrontInGivenOrder(t *testing.T) {
+	ports := []int{1, 22, 80, 443, 3389, 8080}
+
+	ordered, n := reorderPriorityPorts(ports, []int{443, 22, 3389})
+
+	want := []int{443, 22, 3389, 1, 80, 8080}
+	if !sameInts(ordered, want) {
+		t.Errorf("reorderPriorityPorts = %v, want %v", ordered, want)
+	}
+	if n != 3 {
+		t.Errorf("priority count = %d, want 3", n)
+	}
+}
+
+func TestReorderPriorityPorts_IgnoresPriorityPortsNotInRange(t *testing.T) {
+	ports := []int{1, 2, 3}
+
+	ordered, n := reorderPriorityPorts(ports, []int{443, 2, 9999})
+
+	want := []int{2, 1, 3}
+	if !sameInts(ordered, want) {
+		t.Errorf("reorderPriorityPorts = %v, want %v", ordered, want)
+	}
+	if n != 1 {
+		t.Errorf("priority count = %d, want 1 (only port 2 is in range)", n)
+	}
+}
+
+func TestReorderPriorityPorts_NoPriorityLeavesPortsUntouched(t *testing.T) {
+	ports := []int{5, 3, 1}
+
+	ordered, n := reorderPriorityPorts(ports, nil)
+
+	if !sameInts(ordered, ports) {
+		t.Errorf("reorderPriorityPorts = %v, want unchanged %v", ordered, ports)
+	}
+	if n != 0 {
+		t.Errorf("priority count = %d, want 0", n)
+	}
+}
+
+func sameInts(a, b []int) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if a[i] != b[i] {
+			return false
+		}
+	}
+	return true
+}
+
+func TestApplyTimingTemplate_SetsAllFourKnobsFromTheTemplate(t *testing.T) {
+	workers, timeout, maxConcurrency, retries := 999, time.Second, 999, 999
+	if err := applyTimingTemplate(0, map[string]bool{}, &workers, &timeout, &maxConcurrency, &retries); err != nil {
+		t.Fatalf("applyTimingTemplate: %v", err)
+	}
+	want := timingTemplates[0]
+	if workers != want.workers || timeout != want.timeout || maxConcurrency != want.maxConcurrency || retries != want.retries {
+		t.Errorf("got (%d, %s, %d, %d), want %+v", workers, timeout, maxConcurrency, retries, want)
+	}
+}
+
+func TestApplyTimingTemplate_ExplicitFlagsWinOverTheTemplate(t *testing.T) {
+	workers, timeout, maxConcurrency, retries := 42, time.Second, 7, 3
+	explicit := map[string]bool{"workers": true, "retries": true}
+	if err := applyTimingTemplate(5, explicit, &workers, &timeout, &maxConcurrency, &retries); err != nil {
+		t.Fatalf("applyTimingTemplate: %v", err)
+	}
+	if workers != 42 {
+		t.Errorf("workers = %d, want the explicitly-passed 42 left alone", workers)
+	}
+	if retries != 3 {
+		t.Errorf("retries = %d, want the explicitly-passed 3 left alone", retries)
+	}
+	want := timingTemplates[5]
+	if timeout != want.timeout || maxConcurrency != want.maxConcurrency {
+		t.Errorf("timeout/max-concurrency weren't overridden explicitly, so the template should have set them: got (%s, %d), want (%s, %d)",
+			timeout, maxConcurrency, want.timeout, want.maxConcurrency)
+	}
+}
+
+func TestApplyTimingTemplate_RejectsOutOfRangeLevel(t *testing.T) {
+	workers, timeout, maxConcurrency, retries := 1, time.Second, 1, 1
+	if err := applyTimingTemplate(6, map[string]bool{}, &workers, &timeout, &maxConcurrency, &retries); err == nil {
+		t.Error("applyTimingTemplate(6, ...): expected an error, got nil")
+	}
+}
+
+func TestCapWorkers_LeavesRequestedAloneWhenUnderLimit(t *testing.T) {
+	var buf bytes.Buffer
+	got := capWorkers(100, 4096, false, &buf)
+	if got != 100 {
+		t.Errorf("capWorkers = %d, want 100 (well under the fd limit)", got)
+	}
+	if buf.Len() != 0 {
+		t.Errorf("expected no warning, got %q", buf.String())
+	}
+}
+
+func TestCapWorkers_ClampsAndWarnsWhenOverLimit(t *testing.T) {
+	var buf bytes.Buffer
+	got := capWorkers(1000, 256, false, &buf)
+	if want := 64; got != want { // 256 / fdPerWorkerReserve(4)
+		t.Errorf("capWorkers = %d, want %d", got, want)
+	}
+	if !strings.Contains(buf.String(), "max-workers-override") {
+		t.Errorf("expected a warning mentioning the escape hatch, got %q", buf.String())
+	}
+}
+
+func TestCapWorkers_OverrideSkipsTheClamp(t *testing.T) {
+	var buf bytes.Buffer
+	got := capWorkers(1000, 256, true, &buf)
+	if got != 1000 {
+		t.Errorf("capWorkers = %d, want 1000 (override requested)", got)
+	}
+	if buf.Len() != 0 {
+		t.Errorf("expected no warning when overridden, got %q", buf.String())
+	}
+}
+
+func TestResolveRateCeiling_DefersToMaxConcurrencyWhenAutoRateMaxNotSet(t *testing.T) {
+	if got := resolveRateCeiling(500, 0, false); got != 500 {
+		t.Errorf("resolveRateCeiling = %d, want 500 (-max-concurrency)", got)
+	}
+}
+
+func TestResolveRateCeiling_UsesAutoRateMaxWhenSetExplicitly(t *testing.T) {
+	if got := resolveRateCeiling(500, 200, true); got != 200 {
+		t.Errorf("resolveRateCeiling = %d, want 200 (-auto-rate-max)", got)
+	}
+}
+
+func TestResolveRateCeiling_ExplicitZeroStillDefersToMaxConcurrency(t *testing.T) {
+	// -auto-rate-max 0 is documented to defer to -max-concurrency, not to
+	// mean a literal 0 ceiling: a 0 ceiling makes adaptiveController.Acquire
+	// block forever since inFlight can never exceed it.
+	if got := resolveRateCeiling(500, 0, true); got != 500 {
+		t.Errorf("resolveRateCeiling = %d, want 500 (explicit 0 must still defer)", got)
+	}
+}
@@ -0,0 +1,77 @@
+package main
+
+import (
+	"io"
+	"net"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestScanMetrics_ServeExposesCountersOnMetricsEndpoint(t *testing.T) {
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("listen: %v", err)
+	}
+	addr := ln.Addr().String()
+	ln.Close()
+
+	m := newScanMetrics()
+	m.portsScanned.Add(3)
+	m.openFound.Inc()
+	m.scanErrors.Inc()
+
+	if err := m.Serve(addr); err != nil {
+		t.Fatalf("Serve: %v", err)
+	}
+	defer m.Shutdown()
+
+	var resp *http.Response
+	for i := 0; i < 20; i++ {
+		resp, err = http.Get("http://" + addr + "/metrics")
+		if err == nil {
+			break
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	if err != nil {
+		t.Fatalf("GET /metrics: %v", err)
+	}
+	defer resp.Body.Close()
+
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		t.Fatalf("reading body: %v", err)
+	}
+	out := string(body)
+	for _, want := range []string{
+		"goportscan_ports_scanned_total 3",
+		"goportscan_open_ports_total 1",
+		"goportscan_scan_errors_total 1",
+		"goportscan_dials_in_flight 0",
+	} {
+		if !strings.Contains(out, want) {
+			t.Errorf("/metrics output missing %q, got:\n%s", want, out)
+		}
+	}
+}
+
+func TestScanMetrics_ShutdownStopsTheServer(t *testing.T) {
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("listen: %v", err)
+	}
+	addr := ln.Addr().String()
+	ln.Close()
+
+	m := newScanMetrics()
+	if err := m.Serve(addr); err != nil {
+		t.Fatalf("Serve: %v", err)
+	}
+	m.Shutdown()
+
+	if _, err := http.Get("http://" + addr + "/metrics"); err == nil {
+		t.Error("expected GET /metrics to fail once the server has shut down")
+	}
+}
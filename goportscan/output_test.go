@@ -0,0 +1,730 @@
+package main
+
+import (
+	"bytes"
+	"encoding/csv"
+	"encoding/json"
+	"encoding/xml"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestTextSink_AppendsHTTPStatusAndTitle(t *testing.T) {
+	var buf bytes.Buffer
+	sink := &textSink{w: &buf}
+
+	if err := sink.Emit(ScanResult{Host: "10.0.0.1", Port: 8080, Service: "http", HTTP: &HTTPCheckResult{StatusCode: 200, Title: "Admin Login"}}); err != nil {
+		t.Fatalf("Emit: %v", err)
+	}
+	if err := sink.Emit(ScanResult{Host: "10.0.0.1", Port: 22, Service: "ssh"}); err != nil {
+		t.Fatalf("Emit: %v", err)
+	}
+
+	lines := strings.Split(strings.TrimRight(buf.String(), "\n"), "\n")
+	if !strings.Contains(lines[0], `[200] "Admin Login"`) {
+		t.Errorf("line = %q, want it to contain the HTTP status and title", lines[0])
+	}
+	if strings.Contains(lines[1], "[") {
+		t.Errorf("line = %q, want no HTTP suffix for a non-HTTP result", lines[1])
+	}
+}
+
+func TestTextSink_ColorsStateWhenEnabled(t *testing.T) {
+	var buf bytes.Buffer
+	sink := &textSink{w: &buf, color: true}
+
+	if err := sink.Emit(ScanResult{Host: "10.0.0.1", Port: 22, State: "open"}); err != nil {
+		t.Fatalf("Emit: %v", err)
+	}
+	if err := sink.Emit(ScanResult{Host: "10.0.0.1", Port: 23, State: "closed"}); err != nil {
+		t.Fatalf("Emit: %v", err)
+	}
+	if err := sink.Emit(ScanResult{Host: "10.0.0.1", Port: 24, State: "filtered"}); err != nil {
+		t.Fatalf("Emit: %v", err)
+	}
+
+	lines := strings.Split(strings.TrimRight(buf.String(), "\n"), "\n")
+	if !strings.Contains(lines[0], "\033[32m") {
+		t.Errorf("open line = %q, want a green escape code", lines[0])
+	}
+	if !strings.Contains(lines[1], "\033[2m") {
+		t.Errorf("closed line = %q, want a dim escape code", lines[1])
+	}
+	if !strings.Contains(lines[2], "\033[33m") {
+		t.Errorf("filtered line = %q, want a yellow escape code", lines[2])
+	}
+}
+
+func TestTextSink_NoEscapeCodesWhenColorDisabled(t *testing.T) {
+	var buf bytes.Buffer
+	sink := &textSink{w: &buf}
+
+	if err := sink.Emit(ScanResult{Host: "10.0.0.1", Port: 22, State: "open"}); err != nil {
+		t.Fatalf("Emit: %v", err)
+	}
+
+	if strings.Contains(buf.String(), "\033[") {
+		t.Errorf("line = %q, want no ANSI escape codes with color disabled", buf.String())
+	}
+}
+
+func TestJSONSink_EmitsTLSObjectWhenPresent(t *testing.T) {
+	var buf bytes.Buffer
+	sink := &jsonSink{enc: json.NewEncoder(&buf)}
+
+	notAfter := time.Date(2030, 1, 1, 0, 0, 0, 0, time.UTC)
+	if err := sink.Emit(ScanResult{
+		Host: "10.0.0.1", Port: 443, Service: "https",
+		TLS: &TLSCertResult{CommonName: "example.com", SANs: []string{"example.com", "www.example.com"}, NotAfter: notAfter},
+	}); err != nil {
+		t.Fatalf("Emit: %v", err)
+	}
+	if err := sink.Emit(ScanResult{Host: "10.0.0.1", Port: 80}); err != nil {
+		t.Fatalf("Emit: %v", err)
+	}
+
+	lines := strings.Split(strings.TrimRight(buf.String(), "\n"), "\n")
+	var withTLS, withoutTLS ScanResult
+	if err := json.Unmarshal([]byte(lines[0]), &withTLS); err != nil {
+		t.Fatalf("unmarshal first line: %v", err)
+	}
+	if err := json.Unmarshal([]byte(lines[1]), &withoutTLS); err != nil {
+		t.Fatalf("unmarshal second line: %v", err)
+	}
+
+	if withTLS.TLS == nil || withTLS.TLS.CommonName != "example.com" || !withTLS.TLS.NotAfter.Equal(notAfter) {
+		t.Errorf("tls object = %+v, want CN/NotAfter preserved", withTLS.TLS)
+	}
+	if !strings.Contains(lines[0], `"tls"`) {
+		t.Errorf("expected a tls key in %q", lines[0])
+	}
+	if withoutTLS.TLS != nil {
+		t.Errorf("TLS = %+v, want nil (omitempty) for a port that wasn't TLS-probed", withoutTLS.TLS)
+	}
+	if strings.Contains(lines[1], `"tls"`) {
+		t.Errorf("expected no tls key in %q", lines[1])
+	}
+}
+
+func TestJSONSink_EmitsOneObjectPerLine(t *testing.T) {
+	var buf bytes.Buffer
+	sink := &jsonSink{enc: json.NewEncoder(&buf)}
+
+	if err := sink.Emit(ScanResult{Host: "10.0.0.1", Port: 22, Service: "ssh", Version: "OpenSSH_8.9p1"}); err != nil {
+		t.Fatalf("Emit: %v", err)
+	}
+	if err := sink.Emit(ScanResult{Host: "10.0.0.1", Port: 80}); err != nil {
+		t.Fatalf("Emit: %v", err)
+	}
+
+	lines := strings.Split(strings.TrimRight(buf.String(), "\n"), "\n")
+	if len(lines) != 2 {
+		t.Fatalf("got %d lines, want 2: %q", len(lines), buf.String())
+	}
+
+	var r ScanResult
+	if err := json.Unmarshal([]byte(lines[0]), &r); err != nil {
+		t.Fatalf("unmarshal first line: %v", err)
+	}
+	if r.Host != "10.0.0.1" || r.Port != 22 || r.Service != "ssh" || r.Version != "OpenSSH_8.9p1" {
+		t.Errorf("first result = %+v, want host/port/service/version preserved", r)
+	}
+	if strings.Contains(lines[1], `"service"`) {
+		t.Errorf("second line should omit empty service field (omitempty): %s", lines[1])
+	}
+}
+
+// TestJSONLSink_KeepsStdoutPureResultLines checks that Summary and Finish
+// write to the sink's separate stderr encoder rather than the writer Emit
+// uses, so a stream of jsonlSink.Emit output never needs a consumer to
+// special-case a stats or meta line showing up among the Result objects.
+func TestJSONLSink_KeepsStdoutPureResultLines(t *testing.T) {
+	var out, errOut bytes.Buffer
+	sink := &jsonlSink{enc: json.NewEncoder(&out), stderrEnc: json.NewEncoder(&errOut)}
+
+	if err := sink.Emit(ScanResult{Host: "10.0.0.1", Port: 22, Service: "ssh"}); err != nil {
+		t.Fatalf("Emit: %v", err)
+	}
+	if err := sink.Emit(ScanResult{Host: "10.0.0.1", Port: 80}); err != nil {
+		t.Fatalf("Emit: %v", err)
+	}
+	if err := sink.Summary(ScanStats{Open: 2, TotalDials: 10}); err != nil {
+		t.Fatalf("Summary: %v", err)
+	}
+	if err := sink.Errors(ErrorStats{Timeout: 1}); err != nil {
+		t.Fatalf("Errors: %v", err)
+	}
+	if err := sink.Finish(newScanMeta(time.Unix(0, 0), time.Unix(1, 0))); err != nil {
+		t.Fatalf("Finish: %v", err)
+	}
+
+	lines := strings.Split(strings.TrimRight(out.String(), "\n"), "\n")
+	if len(lines) != 2 {
+		t.Fatalf("stdout got %d lines, want exactly the 2 emitted results: %q", len(lines), out.String())
+	}
+	for i, line := range lines {
+		var r ScanResult
+		if err := json.Unmarshal([]byte(line), &r); err != nil {
+			t.Fatalf("line %d did not unmarshal as a ScanResult: %v (%q)", i, err, line)
+		}
+	}
+
+	if !strings.Contains(errOut.String(), `"stats"`) {
+		t.Errorf("stderr missing the stats summary: %q", errOut.String())
+	}
+	if !strings.Contains(errOut.String(), `"meta"`) {
+		t.Errorf("stderr missing the meta line: %q", errOut.String())
+	}
+	if !strings.Contains(errOut.String(), `"errors"`) {
+		t.Errorf("stderr missing the errors line: %q", errOut.String())
+	}
+}
+
+func TestHostJSONLSink_EmitsOneSummaryPerHostOnceComplete(t *testing.T) {
+	var out, errOut bytes.Buffer
+	sink := newHostJSONLSink(&out, 3) // 3 ports per host
+	sink.stderrEnc = json.NewEncoder(&errOut)
+
+	for _, r := range []ScanResult{
+		{Host: "10.0.0.1", Port: 22, State: string(portOpen), Service: "ssh"},
+		{Host: "10.0.0.1", Port: 80, State: string(portClosed)},
+		{Host: "10.0.0.2", Port: 22, State: string(portFiltered)},
+	} {
+		if err := sink.Emit(r); err != nil {
+			t.Fatalf("Emit: %v", err)
+		}
+	}
+	if out.Len() != 0 {
+		t.Fatalf("a summary was written before either host reported on all 3 ports: %q", out.String())
+	}
+
+	if err := sink.Emit(ScanResult{Host: "10.0.0.1", Port: 443, State: string(portOpen)}); err != nil {
+		t.Fatalf("Emit: %v", err)
+	}
+
+	lines := strings.Split(strings.TrimRight(out.String(), "\n"), "\n")
+	if len(lines) != 1 {
+		t.Fatalf("got %d summary line(s) after 10.0.0.1's 3rd result, want exactly 1: %q", len(lines), out.String())
+	}
+	var summary hostSummary
+	if err := json.Unmarshal([]byte(lines[0]), &summary); err != nil {
+		t.Fatalf("summary line did not unmarshal: %v (%q)", err, lines[0])
+	}
+	if summary.Host != "10.0.0.1" {
+		t.Errorf("summary.Host = %q, want 10.0.0.1", summary.Host)
+	}
+	if len(summary.OpenPorts) != 2 {
+		t.Errorf("summary.OpenPorts has %d entries, want 2 (22 and 443)", len(summary.OpenPorts))
+	}
+	if summary.Stats != (hostPortStats{Total: 3, Open: 2, Closed: 1}) {
+		t.Errorf("summary.Stats = %+v, want {Total:3 Open:2 Closed:1}", summary.Stats)
+	}
+
+	if err := sink.Emit(ScanResult{Host: "10.0.0.2", Port: 80, State: string(portClosed)}); err != nil {
+		t.Fatalf("Emit: %v", err)
+	}
+	if err := sink.Emit(ScanResult{Host: "10.0.0.2", Port: 443, State: string(portOpen)}); err != nil {
+		t.Fatalf("Emit: %v", err)
+	}
+	lines = strings.Split(strings.TrimRight(out.String(), "\n"), "\n")
+	if len(lines) != 2 {
+		t.Fatalf("got %d summary line(s) after both hosts completed, want 2: %q", len(lines), out.String())
+	}
+}
+
+// TestHostJSONLSink_SummaryCarriesSchemaVersion confirms the host-level
+// document -o hostjsonl writes (not just each nested ScanResult in
+// openPorts) is stamped with ResultSchemaVersion.
+func TestHostJSONLSink_SummaryCarriesSchemaVersion(t *testing.T) {
+	var out bytes.Buffer
+	sink := newHostJSONLSink(&out, 1)
+	sink.stderrEnc = json.NewEncoder(&bytes.Buffer{})
+
+	if err := sink.Emit(ScanResult{Host: "10.0.0.1", Port: 22, State: string(portOpen)}); err != nil {
+		t.Fatalf("Emit: %v", err)
+	}
+
+	var decoded map[string]any
+	if err := json.Unmarshal(out.Bytes(), &decoded); err != nil {
+		t.Fatalf("unmarshal: %v", err)
+	}
+	if decoded["schema_version"] != ResultSchemaVersion {
+		t.Errorf(`decoded["schema_version"] = %v, want %q`, decoded["schema_version"], ResultSchemaVersion)
+	}
+}
+
+func TestHostJSONLSink_UnreachableResultFlushesHostEarly(t *testing.T) {
+	var out bytes.Buffer
+	sink := newHostJSONLSink(&out, 1000) // far more ports than will ever arrive
+	sink.stderrEnc = json.NewEncoder(&bytes.Buffer{})
+
+	if err := sink.Emit(ScanResult{Host: "10.0.0.1", Port: 22, State: string(portFiltered)}); err != nil {
+		t.Fatalf("Emit: %v", err)
+	}
+	if err := sink.Emit(ScanResult{Host: "10.0.0.1", Port: 23, State: string(portUnreachable), Note: "host unreachable"}); err != nil {
+		t.Fatalf("Emit: %v", err)
+	}
+
+	if out.Len() == 0 {
+		t.Fatal("portUnreachable should flush the host's summary immediately, without waiting for the full port count")
+	}
+	var summary hostSummary
+	if err := json.Unmarshal([]byte(strings.TrimSpace(out.String())), &summary); err != nil {
+		t.Fatalf("summary line did not unmarshal: %v", err)
+	}
+	if summary.Note == "" {
+		t.Error("summary.Note is empty, want the unreachable result's Note carried through")
+	}
+}
+
+func TestHostJSONLSink_FinishFlushesIncompleteHosts(t *testing.T) {
+	var out bytes.Buffer
+	sink := newHostJSONLSink(&out, 3) // expects 3 ports per host, only 1 will arrive
+	sink.stderrEnc = json.NewEncoder(&bytes.Buffer{})
+
+	if err := sink.Emit(ScanResult{Host: "10.0.0.1", Port: 22, State: string(portOpen)}); err != nil {
+		t.Fatalf("Emit: %v", err)
+	}
+	if out.Len() != 0 {
+		t.Fatalf("summary written before the host's 3 expected ports arrived: %q", out.String())
+	}
+
+	if err := sink.Finish(newScanMeta(time.Unix(0, 0), time.Unix(1, 0))); err != nil {
+		t.Fatalf("Finish: %v", err)
+	}
+	if out.Len() == 0 {
+		t.Fatal("Finish should flush the still-incomplete host rather than dropping its results")
+	}
+}
+
+func TestXMLSink_RendersNmapCompatibleDocument(t *testing.T) {
+	var buf bytes.Buffer
+	sink := &xmlSink{w: &buf}
+
+	sink.Emit(ScanResult{Host: "10.0.0.1", Port: 22, Service: "ssh", Version: "OpenSSH_8.9p1"})
+	sink.Emit(ScanResult{Host: "10.0.0.1", Port: 80})
+	sink.Emit(ScanResult{Host: "10.0.0.2", Port: 443})
+
+	if err := sink.Close(); err != nil {
+		t.Fatalf("Close: %v", err)
+	}
+
+	var run nmapRun
+	if err := xml.Unmarshal(buf.Bytes(), &run); err != nil {
+		t.Fatalf("unmarshal xml: %v\n%s", err, buf.String())
+	}
+	if len(run.Hosts) != 2 {
+		t.Fatalf("got %d hosts, want 2 (grouped by address): %+v", len(run.Hosts), run.Hosts)
+	}
+	if run.Hosts[0].Address.Addr != "10.0.0.1" || len(run.Hosts[0].Ports) != 2 {
+		t.Errorf("first host = %+v, want 10.0.0.1 with 2 ports", run.Hosts[0])
+	}
+	if run.Hosts[0].Ports[0].Service == nil || run.Hosts[0].Ports[0].Service.Name != "ssh" {
+		t.Errorf("port 22 service = %+v, want name=ssh", run.Hosts[0].Ports[0].Service)
+	}
+	if run.Hosts[0].Ports[1].Service != nil {
+		t.Errorf("port 80 service = %+v, want nil (no service identified)", run.Hosts[0].Ports[1].Service)
+	}
+	if !strings.Contains(buf.String(), xml.Header) {
+		t.Error("output missing XML header")
+	}
+}
+
+func TestXMLSink_FinishIncludesRunstats(t *testing.T) {
+	var buf bytes.Buffer
+	sink := &xmlSink{w: &buf}
+
+	sink.Emit(ScanResult{Host: "10.0.0.1", Port: 22, Service: "ssh"})
+	started := time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC)
+	finished := started.Add(1500 * time.Millisecond)
+	if err := sink.Finish(newScanMeta(started, finished)); err != nil {
+		t.Fatalf("Finish: %v", err)
+	}
+	if err := sink.Close(); err != nil {
+		t.Fatalf("Close: %v", err)
+	}
+
+	var run nmapRun
+	if err := xml.Unmarshal(buf.Bytes(), &run); err != nil {
+		t.Fatalf("unmarshal xml: %v\n%s", err, buf.String())
+	}
+	if run.Start != started.Format(time.RFC3339) {
+		t.Errorf("run.Start = %q, want %q", run.Start, started.Format(time.RFC3339))
+	}
+	if run.Runstats.Finished.Time != finished.Format(time.RFC3339) {
+		t.Errorf("run.Runstats.Finished.Time = %q, want %q", run.Runstats.Finished.Time, finished.Format(time.RFC3339))
+	}
+	if run.Runstats.Finished.Elapsed != "1.50" {
+		t.Errorf("run.Runstats.Finished.Elapsed = %q, want %q", run.Runstats.Finished.Elapsed, "1.50")
+	}
+}
+
+func TestNewOutputSink_JSONFormat(t *testing.T) {
+	var buf bytes.Buffer
+	sink, err := newOutputSink("json", &buf, "", false, 0)
+	if err != nil {
+		t.Fatalf("newOutputSink: %v", err)
+	}
+	if _, ok := sink.(*jsonSink); !ok {
+		t.Fatalf("newOutputSink(%q) = %T, want *jsonSink", "json", sink)
+	}
+
+	if err := sink.Open(); err != nil {
+		t.Fatalf("Open: %v", err)
+	}
+	if err := sink.Emit(ScanResult{Host: "10.0.0.1", Port: 443, Service: "https"}); err != nil {
+		t.Fatalf("Emit: %v", err)
+	}
+	if err := sink.Close(); err != nil {
+		t.Fatalf("Close: %v", err)
+	}
+
+	var r ScanResult
+	if err := json.Unmarshal(buf.Bytes(), &r); err != nil {
+		t.Fatalf("unmarshal: %v\n%s", err, buf.String())
+	}
+	if r.Host != "10.0.0.1" || r.Port != 443 || r.Service != "https" {
+		t.Errorf("got %+v, want host=10.0.0.1 port=443 service=https", r)
+	}
+}
+
+func TestNewOutputSink_HostJSONLFormat(t *testing.T) {
+	var buf bytes.Buffer
+	sink, err := newOutputSink("hostjsonl", &buf, "", false, 1)
+	if err != nil {
+		t.Fatalf("newOutputSink: %v", err)
+	}
+	if _, ok := sink.(*hostJSONLSink); !ok {
+		t.Fatalf("newOutputSink(%q) = %T, want *hostJSONLSink", "hostjsonl", sink)
+	}
+}
+
+func TestNewOutputSink_UnknownFormat(t *testing.T) {
+	if _, err := newOutputSink("yaml", &bytes.Buffer{}, "", false, 0); err == nil {
+		t.Error("newOutputSink(\"yaml\", ...) expected an error, got nil")
+	}
+}
+
+func TestCSVSink_WritesHeaderAndRows(t *testing.T) {
+	var buf bytes.Buffer
+	sink := &csvSink{w: csv.NewWriter(&buf)}
+
+	if err := sink.Open(); err != nil {
+		t.Fatalf("Open: %v", err)
+	}
+	if err := sink.Emit(ScanResult{Host: "10.0.0.1", Port: 22, State: "open", Service: "ssh"}); err != nil {
+		t.Fatalf("Emit: %v", err)
+	}
+	if err := sink.Close(); err != nil {
+		t.Fatalf("Close: %v", err)
+	}
+
+	rows, err := csv.NewReader(strings.NewReader(buf.String())).ReadAll()
+	if err != nil {
+		t.Fatalf("reading back csv: %v\n%s", err, buf.String())
+	}
+	if len(rows) != 2 {
+		t.Fatalf("got %d rows, want 2 (header + 1 result): %v", len(rows), rows)
+	}
+	if want := []string{"host", "port", "protocol", "state", "service"}; !reflect.DeepEqual(rows[0], want) {
+		t.Errorf("header = %v, want %v", rows[0], want)
+	}
+	if want := []string{"10.0.0.1", "22", "tcp", "open", "ssh"}; !reflect.DeepEqual(rows[1], want) {
+		t.Errorf("row = %v, want %v", rows[1], want)
+	}
+}
+
+func TestCSVSink_DefaultsEmptyStateToOpen(t *testing.T) {
+	var buf bytes.Buffer
+	sink := &csvSink{w: csv.NewWriter(&buf)}
+	sink.Open()
+	sink.Emit(ScanResult{Host: "10.0.0.1", Port: 80})
+	sink.Close()
+
+	if !strings.Contains(buf.String(), ",open,") {
+		t.Errorf("got %q, want state column defaulted to open", buf.String())
+	}
+}
+
+func TestGrepableSink_MatchesNmapStyle(t *testing.T) {
+	var buf bytes.Buffer
+	sink := &grepableSink{w: &buf}
+
+	if err := sink.Emit(ScanResult{Host: "10.0.0.1", Port: 22, State: "open", Service: "ssh"}); err != nil {
+		t.Fatalf("Emit: %v", err)
+	}
+
+	want := "Host: 10.0.0.1 Ports: 22/open/tcp//ssh/\n"
+	if buf.String() != want {
+		t.Errorf("got %q, want %q", buf.String(), want)
+	}
+}
+
+func TestNewOutputSink_CSVAndGrepableFormats(t *testing.T) {
+	if sink, err := newOutputSink("csv", &bytes.Buffer{}, "", false, 0); err != nil {
+		t.Fatalf("newOutputSink(csv): %v", err)
+	} else if _, ok := sink.(*csvSink); !ok {
+		t.Errorf("newOutputSink(csv) = %T, want *csvSink", sink)
+	}
+	if sink, err := newOutputSink("grepable", &bytes.Buffer{}, "", false, 0); err != nil {
+		t.Fatalf("newOutputSink(grepable): %v", err)
+	} else if _, ok := sink.(*grepableSink); !ok {
+		t.Errorf("newOutputSink(grepable) = %T, want *grepableSink", sink)
+	}
+}
+
+// TestNewOutputSink_XMLFormat checks -out-format (and -o) accept "xml" the
+// same way they already accept csv/grepable, so archiving a scan to an XML
+// file doesn't require going through -o on stdout first.
+func TestNewOutputSink_XMLFormat(t *testing.T) {
+	sink, err := newOutputSink("xml", &bytes.Buffer{}, "", false, 0)
+	if err != nil {
+		t.Fatalf("newOutputSink(xml): %v", err)
+	}
+	if _, ok := sink.(*xmlSink); !ok {
+		t.Errorf("newOutputSink(xml) = %T, want *xmlSink", sink)
+	}
+}
+
+func TestMultiSink_FansOutToEverySink(t *testing.T) {
+	var bufA, bufB bytes.Buffer
+	sink := &multiSink{sinks: []OutputSink{
+		&jsonSink{enc: json.NewEncoder(&bufA)},
+		&csvSink{w: csv.NewWriter(&bufB)},
+	}}
+
+	if err := sink.Open(); err != nil {
+		t.Fatalf("Open: %v", err)
+	}
+	if err := sink.Emit(ScanResult{Host: "10.0.0.1", Port: 22, Service: "ssh"}); err != nil {
+		t.Fatalf("Emit: %v", err)
+	}
+	if err := sink.Close(); err != nil {
+		t.Fatalf("Close: %v", err)
+	}
+
+	if bufA.Len() == 0 {
+		t.Error("first sink received nothing")
+	}
+	if bufB.Len() == 0 {
+		t.Error("second sink received nothing")
+	}
+}
+
+func TestJSONSink_SummaryEmitsStatsObject(t *testing.T) {
+	var buf bytes.Buffer
+	sink := &jsonSink{enc: json.NewEncoder(&buf)}
+
+	if err := sink.Summary(ScanStats{Open: 2, Closed: 3, TotalDials: 5, AvgRTTMillis: 12.5, DialsPerSecond: 100}); err != nil {
+		t.Fatalf("Summary: %v", err)
+	}
+
+	var got struct {
+		Stats ScanStats `json:"stats"`
+	}
+	if err := json.Unmarshal(buf.Bytes(), &got); err != nil {
+		t.Fatalf("unmarshal: %v", err)
+	}
+	if got.Stats.Open != 2 || got.Stats.Closed != 3 || got.Stats.TotalDials != 5 {
+		t.Errorf("stats = %+v, want Open/Closed/TotalDials preserved", got.Stats)
+	}
+}
+
+func TestMultiSink_SummaryForwardsOnlyToSinksThatSupportIt(t *testing.T) {
+	var bufA, bufB bytes.Buffer
+	sink := &multiSink{sinks: []OutputSink{
+		&jsonSink{enc: json.NewEncoder(&bufA)},
+		&csvSink{w: csv.NewWriter(&bufB)},
+	}}
+
+	if err := sink.Summary(ScanStats{TotalDials: 7}); err != nil {
+		t.Fatalf("Summary: %v", err)
+	}
+
+	if !strings.Contains(bufA.String(), `"stats"`) {
+		t.Errorf("jsonSink should have received the stats object, buf = %q", bufA.String())
+	}
+	if bufB.Len() != 0 {
+		t.Errorf("csvSink has no Summary method, should have received nothing, buf = %q", bufB.String())
+	}
+}
+
+func TestTextSink_FinishPrintsTimestampsAndDuration(t *testing.T) {
+	var buf bytes.Buffer
+	sink := &textSink{w: &buf}
+
+	started := time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC)
+	finished := started.Add(1500 * time.Millisecond)
+	if err := sink.Finish(newScanMeta(started, finished)); err != nil {
+		t.Fatalf("Finish: %v", err)
+	}
+
+	out := buf.String()
+	if !strings.Contains(out, started.Format(time.RFC3339)) || !strings.Contains(out, finished.Format(time.RFC3339)) {
+		t.Errorf("Finish output = %q, want both timestamps", out)
+	}
+	if !strings.Contains(out, "1500ms") {
+		t.Errorf("Finish output = %q, want duration 1500ms", out)
+	}
+}
+
+func TestJSONSink_FinishEmitsMetaObject(t *testing.T) {
+	var buf bytes.Buffer
+	sink := &jsonSink{enc: json.NewEncoder(&buf)}
+
+	started := time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC)
+	finished := started.Add(2 * time.Second)
+	if err := sink.Finish(newScanMeta(started, finished)); err != nil {
+		t.Fatalf("Finish: %v", err)
+	}
+
+	var got struct {
+		Meta ScanMeta `json:"meta"`
+	}
+	if err := json.Unmarshal(buf.Bytes(), &got); err != nil {
+		t.Fatalf("unmarshal: %v", err)
+	}
+	if !got.Meta.StartedAt.Equal(started) || !got.Meta.FinishedAt.Equal(finished) || got.Meta.DurationMs != 2000 {
+		t.Errorf("meta = %+v, want started/finished/duration preserved", got.Meta)
+	}
+}
+
+func TestCSVSink_FinishWritesMetaRow(t *testing.T) {
+	var buf bytes.Buffer
+	w := csv.NewWriter(&buf)
+	sink := &csvSink{w: w}
+
+	started := time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC)
+	finished := started.Add(3 * time.Second)
+	if err := sink.Finish(newScanMeta(started, finished)); err != nil {
+		t.Fatalf("Finish: %v", err)
+	}
+	w.Flush()
+
+	rows, err := csv.NewReader(strings.NewReader(buf.String())).ReadAll()
+	if err != nil {
+		t.Fatalf("ReadAll: %v", err)
+	}
+	if len(rows) != 1 || len(rows[0]) != 5 {
+		t.Fatalf("rows = %v, want one 5-column row", rows)
+	}
+	if rows[0][0] != "#meta" || rows[0][3] != "3000" {
+		t.Errorf("row = %v, want #meta sentinel and duration 3000", rows[0])
+	}
+}
+
+func TestGrepableSink_FinishWritesCommentLine(t *testing.T) {
+	var buf bytes.Buffer
+	sink := &grepableSink{w: &buf}
+
+	started := time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC)
+	finished := started.Add(time.Second)
+	if err := sink.Finish(newScanMeta(started, finished)); err != nil {
+		t.Fatalf("Finish: %v", err)
+	}
+
+	if !strings.HasPrefix(buf.String(), "# ") {
+		t.Errorf("Finish output = %q, want it to start with a # comment", buf.String())
+	}
+}
+
+func TestMultiSink_FinishFansOutToEverySink(t *testing.T) {
+	var bufA, bufB bytes.Buffer
+	sink := &multiSink{sinks: []OutputSink{
+		&jsonSink{enc: json.NewEncoder(&bufA)},
+		&grepableSink{w: &bufB},
+	}}
+
+	started := time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC)
+	finished := started.Add(time.Second)
+	if err := sink.Finish(newScanMeta(started, finished)); err != nil {
+		t.Fatalf("Finish: %v", err)
+	}
+
+	if bufA.Len() == 0 {
+		t.Error("jsonSink received nothing")
+	}
+	if bufB.Len() == 0 {
+		t.Error("grepableSink received nothing")
+	}
+}
+
+func TestSanitizeFilename_ReplacesPathUnsafeCharacters(t *testing.T) {
+	cases := map[string]string{
+		"10.0.0.1":     "10.0.0.1",
+		"::1":          "__1",
+		"fe80::1%eth0": "fe80__1%eth0",
+		"a/b":          "a_b",
+	}
+	for in, want := range cases {
+		if got := sanitizeFilename(in); got != want {
+			t.Errorf("sanitizeFilename(%q) = %q, want %q", in, got, want)
+		}
+	}
+}
+
+func TestOutFileExt_MapsFormatsToExtensions(t *testing.T) {
+	cases := map[string]string{
+		"":         "txt",
+		"text":     "txt",
+		"grepable": "gnmap",
+		"json":     "json",
+		"xml":      "xml",
+	}
+	for format, want := range cases {
+		if got := outFileExt(format); got != want {
+			t.Errorf("outFileExt(%q) = %q, want %q", format, got, want)
+		}
+	}
+}
+
+func TestPerHostSink_WritesOneFilePerHost(t *testing.T) {
+	dir := filepath.Join(t.TempDir(), "results")
+	sink, err := newPerHostSink(dir, "json", 0)
+	if err != nil {
+		t.Fatalf("newPerHostSink: %v", err)
+	}
+	if err := sink.Open(); err != nil {
+		t.Fatalf("Open: %v", err)
+	}
+	if err := sink.Emit(ScanResult{Host: "10.0.0.1", Port: 22, Service: "ssh"}); err != nil {
+		t.Fatalf("Emit: %v", err)
+	}
+	if err := sink.Emit(ScanResult{Host: "::1", Port: 80, Service: "http"}); err != nil {
+		t.Fatalf("Emit: %v", err)
+	}
+	started := time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC)
+	if err := sink.Finish(newScanMeta(started, started.Add(time.Second))); err != nil {
+		t.Fatalf("Finish: %v", err)
+	}
+	if err := sink.Close(); err != nil {
+		t.Fatalf("Close: %v", err)
+	}
+
+	for host, name := range map[string]string{"10.0.0.1": "10.0.0.1.json", "::1": "__1.json"} {
+		data, err := os.ReadFile(filepath.Join(dir, name))
+		if err != nil {
+			t.Fatalf("reading %s's file: %v", host, err)
+		}
+		if !strings.Contains(string(data), host) {
+			t.Errorf("%s's file doesn't mention its own host: %s", host, data)
+		}
+	}
+}
+
+func TestPerHostSink_CreatesOutDirIfMissing(t *testing.T) {
+	dir := filepath.Join(t.TempDir(), "nested", "results")
+	if _, err := os.Stat(dir); !os.IsNotExist(err) {
+		t.Fatalf("precondition: %s already exists", dir)
+	}
+	if _, err := newPerHostSink(dir, "text", 0); err != nil {
+		t.Fatalf("newPerHostSink: %v", err)
+	}
+	if info, err := os.Stat(dir); err != nil || !info.IsDir() {
+		t.Errorf("newPerHostSink did not create %s", dir)
+	}
+}
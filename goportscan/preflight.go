@@ -0,0 +1,52 @@
+package main
+
+import (
+	"net"
+	"sync"
+	"time"
+)
+
+// preflightSampleSize caps how many candidate hosts preflightCheck actually
+// dials. A handful answering (or refusing) is enough to know the target is
+// reachable from here; dialing every host in a /16 just to confirm that
+// would defeat the point of a *quick* check.
+const preflightSampleSize = 16
+
+// preflightTimeout bounds each dial preflightCheck makes. It's independent
+// of -timeout: the preflight is asking "does anything answer at all", not
+// measuring this link's real RTT, so it can afford to be generous without
+// turning into its own slow scan.
+const preflightTimeout = time.Second
+
+// preflightCheck reports whether any TCP response -- open or refused, it
+// doesn't matter which -- comes back from a sample of hosts. It reuses
+// tcpProbeAlive, the same "try a few commonly open/closed ports" probe
+// host discovery's TCP-ping fallback uses, since that's exactly the signal
+// a preflight needs: proof the target is reachable at all before the main
+// scan commits to it.
+func preflightCheck(hosts []net.IP) bool {
+	sample := hosts
+	if len(sample) > preflightSampleSize {
+		sample = sample[:preflightSampleSize]
+	}
+
+	var (
+		wg        sync.WaitGroup
+		mu        sync.Mutex
+		reachable bool
+	)
+	for _, h := range sample {
+		wg.Add(1)
+		go func(ip net.IP) {
+			defer wg.Done()
+			if !tcpProbeAlive(ip, preflightTimeout) {
+				return
+			}
+			mu.Lock()
+			reachable = true
+			mu.Unlock()
+		}(h)
+	}
+	wg.Wait()
+	return reachable
+}
@@ -0,0 +1,22 @@
+package main
+
+import (
+	"net"
+	"testing"
+)
+
+// TestPreflightCheck_TrueWhenAnySampledHostResponds mirrors
+// TestTCPProbeAlive_RefusedPortStillCountsAsUp: a refusal is as good as an
+// accept for preflightCheck, since all it's confirming is that *something*
+// answered before committing to the main scan.
+func TestPreflightCheck_TrueWhenAnySampledHostResponds(t *testing.T) {
+	ln, err := net.Listen("tcp", "127.0.0.1:445")
+	if err != nil {
+		t.Skipf("port 445 unavailable for this test: %v", err)
+	}
+	ln.Close() // closed immediately: the next dial should be refused, not accepted
+
+	if !preflightCheck([]net.IP{net.ParseIP("127.0.0.1")}) {
+		t.Error("preflightCheck([127.0.0.1]) = false, want true: a refusal on 445 still counts as reachable")
+	}
+}
@@ -0,0 +1,153 @@
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/base64"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/url"
+	"time"
+
+	"golang.org/x/net/proxy"
+)
+
+// dialer is what dialTCP actually uses to open a connection. It defaults to
+// dialing directly and is swapped for a SOCKS5- or HTTP-CONNECT-backed
+// implementation by setProxy, so every caller that already goes through
+// dialTCP (connectProbe, identifyService, ScanPort) transparently tunnels
+// through the proxy without needing to know it exists.
+var dialer dialerFunc = (&net.Dialer{}).DialContext
+
+// dialerFunc matches the method net.Dialer and golang.org/x/net/proxy's
+// ContextDialer both already implement, so either can be assigned directly
+// to dialer without an adapter.
+type dialerFunc func(ctx context.Context, network, address string) (net.Conn, error)
+
+// setProxy points dialer at a SOCKS5 or HTTP CONNECT proxy for the rest of
+// the process's lifetime, e.g. from a -proxy socks5://[user:pass@]host:port
+// or -proxy http://host:port flag. proxyAuth is -proxy-auth's user:pass, for
+// the http scheme only (a socks5 URL carries its own credentials in the
+// userinfo instead). The proxy, not this process, resolves any hostname
+// passed to dialTCP from then on, which is the point: it's how a scan
+// avoids leaking DNS queries to whatever network it's pivoting from.
+func setProxy(proxyURL, proxyAuth string) error {
+	u, err := url.Parse(proxyURL)
+	if err != nil {
+		return fmt.Errorf("invalid -proxy URL %q: %w", proxyURL, err)
+	}
+	if u.Host == "" {
+		return fmt.Errorf("-proxy %q is missing a host:port", proxyURL)
+	}
+
+	switch u.Scheme {
+	case "socks5":
+		var auth *proxy.Auth
+		if u.User != nil {
+			auth = &proxy.Auth{User: u.User.Username()}
+			auth.Password, _ = u.User.Password()
+		}
+
+		d, err := proxy.SOCKS5("tcp", u.Host, auth, proxy.Direct)
+		if err != nil {
+			return fmt.Errorf("socks5 proxy %q: %w", u.Host, err)
+		}
+		cd, ok := d.(proxy.ContextDialer)
+		if !ok {
+			// Every SOCKS5 dialer golang.org/x/net/proxy builds implements
+			// ContextDialer; this only trips if that ever changes upstream.
+			return fmt.Errorf("socks5 proxy %q: dialer doesn't support context cancellation", u.Host)
+		}
+		dialer = cd.DialContext
+		return nil
+	case "http":
+		dialer = newHTTPConnectDialer(u.Host, proxyAuth)
+		return nil
+	default:
+		return fmt.Errorf("unsupported -proxy scheme %q (socks5 and http are supported)", u.Scheme)
+	}
+}
+
+// newHTTPConnectDialer builds a dialerFunc that reaches every address by
+// asking proxyAddr to CONNECT to it, the HTTP equivalent of the SOCKS5 path
+// above for networks that only permit outbound traffic via a web proxy. auth
+// is -proxy-auth's "user:pass", sent as HTTP Basic in a
+// Proxy-Authorization header, or "" to send none.
+func newHTTPConnectDialer(proxyAddr, auth string) dialerFunc {
+	var authHeader string
+	if auth != "" {
+		authHeader = "Basic " + base64.StdEncoding.EncodeToString([]byte(auth))
+	}
+	return func(ctx context.Context, network, address string) (net.Conn, error) {
+		var d net.Dialer
+		conn, err := d.DialContext(ctx, network, proxyAddr)
+		if err != nil {
+			return nil, fmt.Errorf("http proxy %q: %w", proxyAddr, err)
+		}
+
+		req := &http.Request{
+			Method: "CONNECT",
+			URL:    &url.URL{Opaque: address},
+			Host:   address,
+			Header: make(http.Header),
+		}
+		if authHeader != "" {
+			req.Header.Set("Proxy-Authorization", authHeader)
+		}
+		if err := req.Write(conn); err != nil {
+			conn.Close()
+			return nil, fmt.Errorf("http proxy %q: CONNECT %s: %w", proxyAddr, address, err)
+		}
+
+		br := bufio.NewReader(conn)
+		resp, err := http.ReadResponse(br, req)
+		if err != nil {
+			conn.Close()
+			return nil, fmt.Errorf("http proxy %q: CONNECT %s: %w", proxyAddr, address, err)
+		}
+		resp.Body.Close()
+		if resp.StatusCode != http.StatusOK {
+			conn.Close()
+			return nil, fmt.Errorf("http proxy %q: CONNECT %s: %s", proxyAddr, address, resp.Status)
+		}
+
+		// http.ReadResponse's bufio.Reader may have buffered bytes past the
+		// blank line ending the CONNECT response -- the tunnel's own traffic,
+		// already arrived. Replay those before reading more from conn, or
+		// they'd be lost.
+		if n := br.Buffered(); n > 0 {
+			leftover, _ := br.Peek(n)
+			return &connWithLeftover{Conn: conn, r: io.MultiReader(bytes.NewReader(leftover), conn)}, nil
+		}
+		return conn, nil
+	}
+}
+
+// connWithLeftover is a net.Conn whose first reads come from r (the bytes
+// http.ReadResponse had already buffered from Conn) before falling through
+// to Conn itself.
+type connWithLeftover struct {
+	net.Conn
+	r io.Reader
+}
+
+func (c *connWithLeftover) Read(p []byte) (int, error) { return c.r.Read(p) }
+
+// dialTCP opens a TCP connection to address through dialer (direct, or a
+// SOCKS5/HTTP CONNECT proxy set by setProxy), the single dial path shared by
+// connectProbe, identifyService, and the exported ScanPort so there's
+// exactly one place that knows how to open a TCP socket against a scan
+// target. timeout bounds the dial regardless of which dialer is active: a
+// proxied dial wraps ctx in its own deadline since neither proxy dialer has
+// a separate timeout knob of its own.
+func dialTCP(ctx context.Context, address string, timeout time.Duration) (net.Conn, error) {
+	if timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, timeout)
+		defer cancel()
+	}
+	return dialer(ctx, "tcp", address)
+}
@@ -0,0 +1,389 @@
+package main
+
+import (
+	"bufio"
+	"context"
+	"encoding/base64"
+	"encoding/binary"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"testing"
+	"time"
+)
+
+// startFakeSOCKS5Server starts a minimal SOCKS5 server (no-auth only, CONNECT
+// only) that relays every accepted connection to whatever address the
+// client's request names, resolving domain-name requests itself -- the
+// behavior setProxy relies on to keep DNS off the scanning host. It's just
+// enough of RFC 1928 to exercise dialTCP against a real proxy; it is not a
+// general-purpose SOCKS5 implementation.
+func startFakeSOCKS5Server(t *testing.T) string {
+	t.Helper()
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("listen: %v", err)
+	}
+	t.Cleanup(func() { ln.Close() })
+
+	go func() {
+		for {
+			conn, err := ln.Accept()
+			if err != nil {
+				return
+			}
+			go serveFakeSOCKS5Conn(conn)
+		}
+	}()
+	return ln.Addr().String()
+}
+
+func serveFakeSOCKS5Conn(client net.Conn) {
+	defer client.Close()
+	r := bufio.NewReader(client)
+
+	// Greeting: VER NMETHODS METHODS...
+	hdr := make([]byte, 2)
+	if _, err := io.ReadFull(r, hdr); err != nil {
+		return
+	}
+	methods := make([]byte, hdr[1])
+	if _, err := io.ReadFull(r, methods); err != nil {
+		return
+	}
+	if _, err := client.Write([]byte{0x05, 0x00}); err != nil { // no auth required
+		return
+	}
+
+	// Request: VER CMD RSV ATYP ...
+	req := make([]byte, 4)
+	if _, err := io.ReadFull(r, req); err != nil {
+		return
+	}
+	if req[0] != 0x05 || req[1] != 0x01 { // only CONNECT is supported
+		client.Write([]byte{0x05, 0x07, 0x00, 0x01, 0, 0, 0, 0, 0, 0})
+		return
+	}
+
+	var host string
+	switch req[3] {
+	case 0x01: // IPv4
+		addr := make([]byte, 4)
+		if _, err := io.ReadFull(r, addr); err != nil {
+			return
+		}
+		host = net.IP(addr).String()
+	case 0x03: // domain name
+		l := make([]byte, 1)
+		if _, err := io.ReadFull(r, l); err != nil {
+			return
+		}
+		name := make([]byte, l[0])
+		if _, err := io.ReadFull(r, name); err != nil {
+			return
+		}
+		host = string(name)
+	default:
+		client.Write([]byte{0x05, 0x08, 0x00, 0x01, 0, 0, 0, 0, 0, 0})
+		return
+	}
+	portBytes := make([]byte, 2)
+	if _, err := io.ReadFull(r, portBytes); err != nil {
+		return
+	}
+	port := binary.BigEndian.Uint16(portBytes)
+
+	upstream, err := net.Dial("tcp", net.JoinHostPort(host, fmt.Sprint(port)))
+	if err != nil {
+		client.Write([]byte{0x05, 0x05, 0x00, 0x01, 0, 0, 0, 0, 0, 0})
+		return
+	}
+	defer upstream.Close()
+
+	client.Write([]byte{0x05, 0x00, 0x00, 0x01, 0, 0, 0, 0, 0, 0})
+
+	done := make(chan struct{}, 2)
+	go func() { io.Copy(upstream, r); done <- struct{}{} }()
+	go func() { io.Copy(client, upstream); done <- struct{}{} }()
+	<-done
+}
+
+// withProxy points dialer at addr for the duration of the test and restores
+// the direct dialer on cleanup, since dialer is a package-level var shared
+// with every other test in this package.
+func withProxy(t *testing.T, proxyURL string) {
+	t.Helper()
+	original := dialer
+	if err := setProxy(proxyURL, ""); err != nil {
+		t.Fatalf("setProxy: %v", err)
+	}
+	t.Cleanup(func() { dialer = original })
+}
+
+func TestDialTCP_RoutesThroughSOCKS5Proxy(t *testing.T) {
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("listen: %v", err)
+	}
+	defer ln.Close()
+	go func() {
+		conn, err := ln.Accept()
+		if err != nil {
+			return
+		}
+		defer conn.Close()
+		conn.Write([]byte("hello"))
+	}()
+
+	proxyAddr := startFakeSOCKS5Server(t)
+	withProxy(t, "socks5://"+proxyAddr)
+
+	conn, err := dialTCP(context.Background(), ln.Addr().String(), time.Second)
+	if err != nil {
+		t.Fatalf("dialTCP through proxy: %v", err)
+	}
+	defer conn.Close()
+
+	buf := make([]byte, 5)
+	conn.SetReadDeadline(time.Now().Add(time.Second))
+	if _, err := io.ReadFull(conn, buf); err != nil {
+		t.Fatalf("reading through proxy: %v", err)
+	}
+	if string(buf) != "hello" {
+		t.Errorf("got %q, want %q", buf, "hello")
+	}
+}
+
+func TestDialTCP_RoutesHostnameThroughProxyByName(t *testing.T) {
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("listen: %v", err)
+	}
+	defer ln.Close()
+	_, port, _ := net.SplitHostPort(ln.Addr().String())
+	go func() {
+		conn, err := ln.Accept()
+		if err != nil {
+			return
+		}
+		defer conn.Close()
+		conn.Write([]byte("hi"))
+	}()
+
+	proxyAddr := startFakeSOCKS5Server(t)
+	withProxy(t, "socks5://"+proxyAddr)
+
+	// "localhost" instead of "127.0.0.1": the fake proxy, not this process,
+	// has to resolve it, exercising the domain-name (ATYP 0x03) path.
+	conn, err := dialTCP(context.Background(), net.JoinHostPort("localhost", port), time.Second)
+	if err != nil {
+		t.Fatalf("dialTCP by hostname through proxy: %v", err)
+	}
+	defer conn.Close()
+
+	buf := make([]byte, 2)
+	conn.SetReadDeadline(time.Now().Add(time.Second))
+	if _, err := io.ReadFull(conn, buf); err != nil {
+		t.Fatalf("reading through proxy: %v", err)
+	}
+	if string(buf) != "hi" {
+		t.Errorf("got %q, want %q", buf, "hi")
+	}
+}
+
+func TestSetProxy_RejectsUnsupportedScheme(t *testing.T) {
+	original := dialer
+	defer func() { dialer = original }()
+
+	if err := setProxy("socks4://10.0.0.1:1080", ""); err == nil {
+		t.Error("setProxy with a socks4:// scheme: expected an error, got nil")
+	}
+}
+
+// startFakeCONNECTProxy starts a minimal HTTP proxy that understands only
+// CONNECT: it checks wantAuth (if non-empty) against the request's
+// Proxy-Authorization header and, once satisfied, relays the tunnel to
+// whatever address the client asked for. Like startFakeSOCKS5Server, it's
+// just enough of RFC 7231 to exercise newHTTPConnectDialer against a real
+// proxy, not a general-purpose one.
+func startFakeCONNECTProxy(t *testing.T, wantAuth string) string {
+	t.Helper()
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("listen: %v", err)
+	}
+	t.Cleanup(func() { ln.Close() })
+
+	go func() {
+		for {
+			conn, err := ln.Accept()
+			if err != nil {
+				return
+			}
+			go serveFakeCONNECTConn(conn, wantAuth)
+		}
+	}()
+	return ln.Addr().String()
+}
+
+func serveFakeCONNECTConn(client net.Conn, wantAuth string) {
+	defer client.Close()
+	br := bufio.NewReader(client)
+	req, err := http.ReadRequest(br)
+	if err != nil || req.Method != http.MethodConnect {
+		client.Write([]byte("HTTP/1.1 400 Bad Request\r\n\r\n"))
+		return
+	}
+
+	if wantAuth != "" {
+		want := "Basic " + base64.StdEncoding.EncodeToString([]byte(wantAuth))
+		if req.Header.Get("Proxy-Authorization") != want {
+			client.Write([]byte("HTTP/1.1 407 Proxy Authentication Required\r\n\r\n"))
+			return
+		}
+	}
+
+	upstream, err := net.Dial("tcp", req.Host)
+	if err != nil {
+		client.Write([]byte("HTTP/1.1 502 Bad Gateway\r\n\r\n"))
+		return
+	}
+	defer upstream.Close()
+
+	client.Write([]byte("HTTP/1.1 200 Connection Established\r\n\r\n"))
+
+	done := make(chan struct{}, 2)
+	go func() { io.Copy(upstream, br); done <- struct{}{} }()
+	go func() { io.Copy(client, upstream); done <- struct{}{} }()
+	<-done
+}
+
+func TestDialTCP_RoutesThroughHTTPConnectProxy(t *testing.T) {
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("listen: %v", err)
+	}
+	defer ln.Close()
+	go func() {
+		conn, err := ln.Accept()
+		if err != nil {
+			return
+		}
+		defer conn.Close()
+		conn.Write([]byte("hello"))
+	}()
+
+	proxyAddr := startFakeCONNECTProxy(t, "")
+	original := dialer
+	defer func() { dialer = original }()
+	if err := setProxy("http://"+proxyAddr, ""); err != nil {
+		t.Fatalf("setProxy: %v", err)
+	}
+
+	conn, err := dialTCP(context.Background(), ln.Addr().String(), time.Second)
+	if err != nil {
+		t.Fatalf("dialTCP through HTTP CONNECT proxy: %v", err)
+	}
+	defer conn.Close()
+
+	buf := make([]byte, 5)
+	conn.SetReadDeadline(time.Now().Add(time.Second))
+	if _, err := io.ReadFull(conn, buf); err != nil {
+		t.Fatalf("reading through proxy: %v", err)
+	}
+	if string(buf) != "hello" {
+		t.Errorf("got %q, want %q", buf, "hello")
+	}
+}
+
+func TestDialTCP_SendsProxyAuthToHTTPConnectProxy(t *testing.T) {
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("listen: %v", err)
+	}
+	defer ln.Close()
+	go func() {
+		conn, err := ln.Accept()
+		if err != nil {
+			return
+		}
+		defer conn.Close()
+		conn.Write([]byte("ok"))
+	}()
+
+	proxyAddr := startFakeCONNECTProxy(t, "scanner:hunter2")
+	original := dialer
+	defer func() { dialer = original }()
+
+	if err := setProxy("http://"+proxyAddr, ""); err != nil {
+		t.Fatalf("setProxy: %v", err)
+	}
+	if _, err := dialTCP(context.Background(), ln.Addr().String(), time.Second); err == nil {
+		t.Fatal("dialTCP without -proxy-auth against an auth-requiring proxy: expected an error, got nil")
+	}
+
+	if err := setProxy("http://"+proxyAddr, "scanner:hunter2"); err != nil {
+		t.Fatalf("setProxy: %v", err)
+	}
+	conn, err := dialTCP(context.Background(), ln.Addr().String(), time.Second)
+	if err != nil {
+		t.Fatalf("dialTCP with -proxy-auth: %v", err)
+	}
+	defer conn.Close()
+
+	buf := make([]byte, 2)
+	conn.SetReadDeadline(time.Now().Add(time.Second))
+	if _, err := io.ReadFull(conn, buf); err != nil {
+		t.Fatalf("reading through proxy: %v", err)
+	}
+	if string(buf) != "ok" {
+		t.Errorf("got %q, want %q", buf, "ok")
+	}
+}
+
+// TestDialTCP_RespectsContextDeadline exercises the mechanism -deadline
+// relies on to report partial results promptly: a dial against a host that
+// never answers should still return as soon as the context passed in is
+// done, not after some much longer per-dial timeout. dialer is swapped for a
+// stand-in that hangs until ctx is cancelled, the way a real dial to an
+// unreachable host hangs until the kernel (or here, the deadline) gives up.
+func TestDialTCP_RespectsContextDeadline(t *testing.T) {
+	original := dialer
+	defer func() { dialer = original }()
+	dialer = func(ctx context.Context, network, address string) (net.Conn, error) {
+		<-ctx.Done()
+		return nil, ctx.Err()
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+
+	start := time.Now()
+	_, err := dialTCP(ctx, "203.0.113.1:9", 10*time.Second)
+	elapsed := time.Since(start)
+
+	if err == nil {
+		t.Fatal("dialTCP: expected an error against an unreachable host, got nil")
+	}
+	if elapsed > time.Second {
+		t.Errorf("dialTCP took %s to return, want it to stop at the context deadline (~50ms), well short of the 10s per-dial timeout", elapsed)
+	}
+}
+
+func TestIsBareHostname(t *testing.T) {
+	cases := map[string]bool{
+		"example.internal": true,
+		"web-01.internal":  true,
+		"10.0.0.5":         false,
+		"::1":              false,
+		"10.0.0.0/24":      false,
+		"10.0.0.1-254":     false,
+		"10.0.0-2.1-254":   false,
+	}
+	for spec, want := range cases {
+		if got := isBareHostname(spec); got != want {
+			t.Errorf("isBareHostname(%q) = %v, want %v", spec, got, want)
+		}
+	}
+}
@@ -0,0 +1,82 @@
+package main
+
+import (
+	"net"
+	"regexp"
+	"sync"
+)
+
+// ptrLookupConcurrency bounds how many reverse lookups filterByPTR runs at
+// once, the same fan-out shape discoverLiveHosts uses for its own host
+// probes.
+const ptrLookupConcurrency = 256
+
+var (
+	ptrCacheMu sync.Mutex
+	ptrCache   = map[string][]string{}
+)
+
+// cachedLookupAddr wraps lookupAddr with a process-lifetime cache keyed by
+// IP, so a host named more than once across a run's targets (or a
+// -ptr-filter re-applied in a future call) only ever triggers one reverse
+// lookup.
+func cachedLookupAddr(ip net.IP) ([]string, error) {
+	key := ip.String()
+
+	ptrCacheMu.Lock()
+	if names, ok := ptrCache[key]; ok {
+		ptrCacheMu.Unlock()
+		return names, nil
+	}
+	ptrCacheMu.Unlock()
+
+	names, err := lookupAddr(key)
+	if err != nil {
+		return nil, err
+	}
+
+	ptrCacheMu.Lock()
+	ptrCache[key] = names
+	ptrCacheMu.Unlock()
+	return names, nil
+}
+
+// filterByPTR keeps only the hosts whose PTR record matches pattern,
+// preserving hosts' relative order. A host with no PTR record (the lookup
+// fails, or succeeds with zero names) is dropped unless includeNoPTR is
+// set. Lookups run concurrently since net.LookupAddr is a blocking DNS
+// round trip and a large CIDR can mean thousands of them.
+func filterByPTR(hosts []net.IP, pattern *regexp.Regexp, includeNoPTR bool) []net.IP {
+	matched := make([]bool, len(hosts))
+
+	var wg sync.WaitGroup
+	sem := make(chan struct{}, ptrLookupConcurrency)
+	for i, h := range hosts {
+		wg.Add(1)
+		sem <- struct{}{}
+		go func(i int, ip net.IP) {
+			defer wg.Done()
+			defer func() { <-sem }()
+			names, err := cachedLookupAddr(ip)
+			if err != nil || len(names) == 0 {
+				matched[i] = includeNoPTR
+				return
+			}
+			for _, name := range names {
+				if pattern.MatchString(name) {
+					matched[i] = true
+					return
+				}
+			}
+		}(i, h)
+	}
+	wg.Wait()
+
+	var kept []net.IP
+	for i, h := range hosts {
+		if matched[i] {
+			kept = append(kept, h)
+		}
+	}
+	return kept
+}
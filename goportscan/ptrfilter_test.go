@@ -0,0 +1,106 @@
+package main
+
+import (
+	"fmt"
+	"net"
+	"regexp"
+	"sort"
+	"sync"
+	"testing"
+)
+
+// stubLookupAddr swaps lookupAddr for the scope of a test, restoring it (and
+// clearing ptrCache, since cachedLookupAddr persists across tests
+// otherwise) on cleanup.
+func stubLookupAddr(t *testing.T, ptr map[string][]string) *int32Counter {
+	t.Helper()
+	calls := &int32Counter{}
+	orig := lookupAddr
+	lookupAddr = func(addr string) ([]string, error) {
+		calls.Add(1)
+		names, ok := ptr[addr]
+		if !ok {
+			return nil, fmt.Errorf("no PTR record for %s", addr)
+		}
+		return names, nil
+	}
+	t.Cleanup(func() {
+		lookupAddr = orig
+		ptrCacheMu.Lock()
+		ptrCache = map[string][]string{}
+		ptrCacheMu.Unlock()
+	})
+	return calls
+}
+
+type int32Counter struct {
+	mu sync.Mutex
+	n  int
+}
+
+func (c *int32Counter) Add(d int) {
+	c.mu.Lock()
+	c.n += d
+	c.mu.Unlock()
+}
+
+func (c *int32Counter) Value() int {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.n
+}
+
+func TestFilterByPTR_KeepsOnlyMatchingHosts(t *testing.T) {
+	stubLookupAddr(t, map[string][]string{
+		"10.0.0.1": {"db1.db.internal."},
+		"10.0.0.2": {"web1.web.internal."},
+		"10.0.0.3": {"db2.db.internal."},
+	})
+
+	hosts := []net.IP{net.ParseIP("10.0.0.1"), net.ParseIP("10.0.0.2"), net.ParseIP("10.0.0.3")}
+	kept := filterByPTR(hosts, regexp.MustCompile(`\.db\.internal\.$`), false)
+
+	var gotStrs []string
+	for _, ip := range kept {
+		gotStrs = append(gotStrs, ip.String())
+	}
+	sort.Strings(gotStrs)
+	want := []string{"10.0.0.1", "10.0.0.3"}
+	if fmt.Sprint(gotStrs) != fmt.Sprint(want) {
+		t.Errorf("kept = %v, want %v", gotStrs, want)
+	}
+}
+
+func TestFilterByPTR_DropsNoPTRUnlessIncluded(t *testing.T) {
+	stubLookupAddr(t, map[string][]string{
+		"10.0.0.1": {"db1.db.internal."},
+	})
+
+	hosts := []net.IP{net.ParseIP("10.0.0.1"), net.ParseIP("10.0.0.2")}
+	pattern := regexp.MustCompile(`\.db\.internal\.$`)
+
+	kept := filterByPTR(hosts, pattern, false)
+	if len(kept) != 1 || kept[0].String() != "10.0.0.1" {
+		t.Errorf("kept = %v, want only 10.0.0.1 with includeNoPTR=false", kept)
+	}
+
+	kept = filterByPTR(hosts, pattern, true)
+	if len(kept) != 2 {
+		t.Errorf("kept = %v, want both hosts with includeNoPTR=true", kept)
+	}
+}
+
+func TestCachedLookupAddr_OnlyResolvesEachHostOnce(t *testing.T) {
+	calls := stubLookupAddr(t, map[string][]string{
+		"10.0.0.1": {"db1.db.internal."},
+	})
+
+	for i := 0; i < 5; i++ {
+		if _, err := cachedLookupAddr(net.ParseIP("10.0.0.1")); err != nil {
+			t.Fatalf("cachedLookupAddr: %v", err)
+		}
+	}
+	if n := calls.Value(); n != 1 {
+		t.Errorf("lookupAddr called %d times, want 1 (cached after the first)", n)
+	}
+}
@@ -0,0 +1,307 @@
+package main
+
+import (
+	"fmt"
+	"sort"
+	"sync"
+	"time"
+)
+
+const (
+	minInFlight      = 20
+	maxInFlight      = 1000
+	minTimeout       = 50 * time.Millisecond
+	maxTimeout       = 3 * time.Second
+	sampleWindow     = 50 // number of recent dials considered per recalibration
+	recalibrateEvery = 25
+)
+
+// adaptiveController tunes the scanner's concurrency and per-dial timeout on
+// the fly, based on a sliding window of recent dial outcomes. It grows the
+// timeout and shrinks concurrency (additive-increase/multiplicative-decrease)
+// when timeouts or descriptor exhaustion spike, and shrinks the timeout
+// toward the observed p95 latency when the host is clearly alive and simply
+// refusing connections quickly. Besides keeping the scan fast without
+// exhausting file descriptors, this is the scanner's rate limiter: a flat,
+// un-throttled flood of connection attempts is exactly the pattern IDS/IPS
+// signatures key on, so backing off under error pressure doubles as evasion.
+type adaptiveController struct {
+	mu sync.Mutex
+
+	timeout  time.Duration
+	limit    int
+	ceiling  int
+	inFlight int
+	notFull  *sync.Cond
+
+	samples     []sample
+	sinceRecalc int
+
+	adaptiveTimeout bool
+	adaptiveRate    bool
+	ewmaRTT         time.Duration
+
+	onAdjust func(oldLimit, newLimit int, reason string)
+}
+
+// ewmaAlpha is the smoothing factor for ewmaRTT, following the same weight
+// TCP's RTO estimator gives new samples (RFC 6298's 1/8): fast enough to
+// track a real RTT swing within a handful of dials, slow enough that one
+// outlier dial doesn't whipsaw the estimate.
+const ewmaAlpha = 0.125
+
+type sample struct {
+	latency time.Duration
+	kind    outcomeKind
+}
+
+type outcomeKind int
+
+const (
+	outcomeOK outcomeKind = iota
+	outcomeRefused
+	outcomeTimeout
+	outcomeExhausted
+)
+
+func newAdaptiveController() *adaptiveController {
+	c := &adaptiveController{
+		timeout:         500 * time.Millisecond,
+		limit:           500,
+		ceiling:         maxInFlight,
+		adaptiveTimeout: true,
+		adaptiveRate:    true,
+	}
+	c.notFull = sync.NewCond(&c.mu)
+	return c
+}
+
+// SetAdaptiveTimeout controls whether recalibrate is allowed to adjust the
+// dial timeout at all, e.g. from a user-supplied -adaptive flag. Concurrency
+// (limit) still adapts either way, since that's also this controller's rate
+// limiter; only the timeout, which -timeout lets a user pin to a known-good
+// value for a link they've already characterized, is gated.
+func (c *adaptiveController) SetAdaptiveTimeout(enabled bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.adaptiveTimeout = enabled
+}
+
+// SetAdaptiveRate controls whether recalibrate is allowed to adjust
+// concurrency (limit) at all, e.g. from a user-supplied -auto-rate flag.
+// The timeout still adapts either way, gated separately by
+// SetAdaptiveTimeout; disabling this pins concurrency wherever it happened
+// to be when the flag took effect.
+func (c *adaptiveController) SetAdaptiveRate(enabled bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.adaptiveRate = enabled
+}
+
+// SetAdjustLogger registers a callback invoked every time recalibrate
+// actually changes the concurrency limit, e.g. from a user-supplied
+// -verbose flag. oldLimit and newLimit are the concurrency before and
+// after the adjustment; reason is a short human-readable explanation of
+// why. A nil logger (the default) disables this entirely.
+func (c *adaptiveController) SetAdjustLogger(f func(oldLimit, newLimit int, reason string)) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.onAdjust = f
+}
+
+// Timeout reports the controller's current per-dial timeout, e.g. for
+// -verbose logging once a scan finishes.
+func (c *adaptiveController) Timeout() time.Duration {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.timeout
+}
+
+// SmoothedRTT reports the exponentially-weighted average round-trip time
+// observed across successful and refused dials (the two outcomes where
+// latency reflects the wire, not a timeout firing), e.g. for -verbose
+// logging. It's zero until at least one such dial has completed.
+func (c *adaptiveController) SmoothedRTT() time.Duration {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.ewmaRTT
+}
+
+// SeedTimeout overrides the controller's starting per-dial timeout, e.g. from
+// a user-supplied -timeout flag. Recalibration still adjusts it from there as
+// the scan observes real latencies.
+func (c *adaptiveController) SeedTimeout(d time.Duration) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.timeout = d
+}
+
+// SetCeiling caps how far recalibration is allowed to grow concurrency,
+// e.g. from a user-supplied -max-concurrency flag for scans that need to
+// stay under a fixed rate regardless of how quiet the target looks.
+func (c *adaptiveController) SetCeiling(n int) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.ceiling = n
+	if c.limit > c.ceiling {
+		c.limit = c.ceiling
+	}
+}
+
+// Acquire blocks until a dial slot is available and returns the timeout that
+// should be used for the next dial attempt.
+func (c *adaptiveController) Acquire() time.Duration {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	for c.inFlight >= c.limit {
+		c.notFull.Wait()
+	}
+	c.inFlight++
+	return c.timeout
+}
+
+// Release frees a dial slot and records the outcome of the dial that held
+// it, recalibrating the controller every recalibrateEvery samples.
+func (c *adaptiveController) Release(latency time.Duration, err error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.inFlight--
+	c.notFull.Signal()
+
+	kind := classify(err)
+	if kind == outcomeOK || kind == outcomeRefused {
+		if c.ewmaRTT == 0 {
+			c.ewmaRTT = latency
+		} else {
+			c.ewmaRTT += time.Duration(ewmaAlpha * float64(latency-c.ewmaRTT))
+		}
+	}
+
+	c.samples = append(c.samples, sample{latency: latency, kind: kind})
+	if len(c.samples) > sampleWindow {
+		c.samples = c.samples[len(c.samples)-sampleWindow:]
+	}
+
+	c.sinceRecalc++
+	if c.sinceRecalc >= recalibrateEvery && len(c.samples) >= sampleWindow/2 {
+		c.sinceRecalc = 0
+		c.recalibrate()
+	}
+}
+
+func classify(err error) outcomeKind {
+	switch {
+	case err == nil:
+		return outcomeOK
+	case isTooManyOpenFiles(err):
+		return outcomeExhausted
+	case isTimeout(err):
+		return outcomeTimeout
+	case isConnRefused(err):
+		return outcomeRefused
+	default:
+		return outcomeTimeout
+	}
+}
+
+// recalibrate adjusts limit and timeout based on the recent sample window.
+// Caller must hold c.mu.
+func (c *adaptiveController) recalibrate() {
+	var timeouts, refused, exhausted int
+	latencies := make([]time.Duration, 0, len(c.samples))
+	for _, s := range c.samples {
+		switch s.kind {
+		case outcomeTimeout:
+			timeouts++
+		case outcomeRefused:
+			refused++
+			latencies = append(latencies, s.latency)
+		case outcomeExhausted:
+			exhausted++
+		case outcomeOK:
+			latencies = append(latencies, s.latency)
+		}
+	}
+
+	n := len(c.samples)
+	timeoutRatio := float64(timeouts) / float64(n)
+	refusedRatio := float64(refused) / float64(n)
+
+	oldLimit := c.limit
+	var reason string
+
+	switch {
+	case exhausted > 0 || timeoutRatio > 0.3:
+		// Too-fast-for-the-wire or descriptor pressure: back off hard.
+		reason = fmt.Sprintf("%d%% timeouts/exhaustion over last %d dials", int(timeoutRatio*100), n)
+		if c.adaptiveRate {
+			c.limit = max(c.limit/2, minInFlight)
+		}
+		if c.adaptiveTimeout {
+			c.timeout = minDur(c.timeout+100*time.Millisecond, maxTimeout)
+		}
+
+	case refusedRatio > 0.5 && len(latencies) > 0:
+		// Host is alive and responding quickly: shrink the timeout toward
+		// the observed p95 latency and allow a little more concurrency.
+		reason = "host responding quickly, ramping up"
+		if c.adaptiveTimeout {
+			p95 := percentile(latencies, 0.95)
+			target := p95 + p95/2
+			c.timeout = clampDur(target, minTimeout, c.timeout)
+		}
+		if c.adaptiveRate {
+			c.limit = min(c.limit+c.limit/10+1, c.ceiling)
+		}
+
+	default:
+		// Steady state: creep concurrency up.
+		reason = "steady state, creeping up"
+		if c.adaptiveRate {
+			c.limit = min(c.limit+c.limit/20+1, c.ceiling)
+		}
+	}
+
+	if c.onAdjust != nil && c.limit != oldLimit {
+		c.onAdjust(oldLimit, c.limit, reason)
+	}
+}
+
+func percentile(d []time.Duration, p float64) time.Duration {
+	sorted := append([]time.Duration(nil), d...)
+	sort.Slice(sorted, func(i, j int) bool { return sorted[i] < sorted[j] })
+	idx := int(p * float64(len(sorted)-1))
+	return sorted[idx]
+}
+
+func minDur(a, b time.Duration) time.Duration {
+	if a < b {
+		return a
+	}
+	return b
+}
+
+func clampDur(v, lo, hi time.Duration) time.Duration {
+	if v < lo {
+		return lo
+	}
+	if v > hi {
+		return hi
+	}
+	return v
+}
+
+func max(a, b int) int {
+	if a > b {
+		return a
+	}
+	return b
+}
+
+func min(a, b int) int {
+	if a < b {
+		return a
+	}
+	return b
+}
@@ -0,0 +1,248 @@
+package main
+
+import (
+	"testing"
+	"time"
+)
+
+// fillSamples drives recalibrate by feeding it exactly sampleWindow samples
+// of the given kind and latency, mirroring what Release does internally.
+func fillSamples(c *adaptiveController, kind outcomeKind, latency time.Duration) {
+	for i := 0; i < sampleWindow; i++ {
+		c.samples = append(c.samples, sample{latency: latency, kind: kind})
+	}
+}
+
+func TestRecalibrate_TimeoutsBackOffHard(t *testing.T) {
+	c := newAdaptiveController()
+	c.limit = 500
+	c.timeout = 500 * time.Millisecond
+	fillSamples(c, outcomeTimeout, 500*time.Millisecond)
+
+	c.recalibrate()
+
+	if want := 250; c.limit != want {
+		t.Errorf("limit = %d, want %d (halved)", c.limit, want)
+	}
+	if want := 600 * time.Millisecond; c.timeout != want {
+		t.Errorf("timeout = %s, want %s (grown)", c.timeout, want)
+	}
+}
+
+func TestRecalibrate_ExhaustionBacksOffEvenWithoutTimeouts(t *testing.T) {
+	c := newAdaptiveController()
+	c.limit = 500
+	c.timeout = 500 * time.Millisecond
+	fillSamples(c, outcomeExhausted, 500*time.Millisecond)
+
+	c.recalibrate()
+
+	if want := 250; c.limit != want {
+		t.Errorf("limit = %d, want %d (halved)", c.limit, want)
+	}
+}
+
+func TestRecalibrate_LimitNeverDropsBelowMinimum(t *testing.T) {
+	c := newAdaptiveController()
+	c.limit = minInFlight + 5
+	c.timeout = 500 * time.Millisecond
+	fillSamples(c, outcomeTimeout, 500*time.Millisecond)
+
+	c.recalibrate()
+
+	if c.limit != minInFlight {
+		t.Errorf("limit = %d, want clamped to minInFlight (%d)", c.limit, minInFlight)
+	}
+}
+
+func TestRecalibrate_RefusedShrinksTimeoutTowardP95(t *testing.T) {
+	c := newAdaptiveController()
+	c.limit = 500
+	c.timeout = 500 * time.Millisecond
+	fillSamples(c, outcomeRefused, 10*time.Millisecond)
+
+	c.recalibrate()
+
+	// p95 (10ms) + p95/2 = 15ms, but clampDur floors it at minTimeout (50ms).
+	want := minTimeout
+	if c.timeout != want {
+		t.Errorf("timeout = %s, want %s", c.timeout, want)
+	}
+	if c.limit <= 500 {
+		t.Errorf("limit = %d, want grown above 500", c.limit)
+	}
+}
+
+func TestRecalibrate_TimeoutNeverShrinksBelowMinimum(t *testing.T) {
+	c := newAdaptiveController()
+	c.limit = 500
+	c.timeout = minTimeout
+	fillSamples(c, outcomeRefused, 1*time.Millisecond)
+
+	c.recalibrate()
+
+	if c.timeout != minTimeout {
+		t.Errorf("timeout = %s, want clamped to minTimeout (%s)", c.timeout, minTimeout)
+	}
+}
+
+func TestRecalibrate_SteadyStateCreepsConcurrencyUp(t *testing.T) {
+	c := newAdaptiveController()
+	c.limit = 500
+	c.timeout = 500 * time.Millisecond
+	fillSamples(c, outcomeOK, 10*time.Millisecond)
+
+	c.recalibrate()
+
+	if want := 526; c.limit != want { // 500 + 500/20 + 1
+		t.Errorf("limit = %d, want %d", c.limit, want)
+	}
+	if c.timeout != 500*time.Millisecond {
+		t.Errorf("timeout = %s, want unchanged at 500ms", c.timeout)
+	}
+}
+
+func TestRecalibrate_LimitNeverExceedsMaximum(t *testing.T) {
+	c := newAdaptiveController()
+	c.limit = maxInFlight
+	c.timeout = 500 * time.Millisecond
+	fillSamples(c, outcomeOK, 10*time.Millisecond)
+
+	c.recalibrate()
+
+	if c.limit != maxInFlight {
+		t.Errorf("limit = %d, want clamped to maxInFlight (%d)", c.limit, maxInFlight)
+	}
+}
+
+func TestSetCeiling_CapsRecalibrateGrowth(t *testing.T) {
+	c := newAdaptiveController()
+	c.SetCeiling(100)
+	c.limit = 100
+	c.timeout = 500 * time.Millisecond
+	fillSamples(c, outcomeOK, 10*time.Millisecond)
+
+	c.recalibrate()
+
+	if c.limit != 100 {
+		t.Errorf("limit = %d, want clamped to the 100 ceiling", c.limit)
+	}
+}
+
+func TestSetCeiling_ClampsCurrentLimitImmediately(t *testing.T) {
+	c := newAdaptiveController()
+	c.limit = 500
+
+	c.SetCeiling(50)
+
+	if c.limit != 50 {
+		t.Errorf("limit = %d, want immediately clamped to the new ceiling of 50", c.limit)
+	}
+}
+
+func TestRecalibrate_AdaptiveTimeoutDisabledLeavesTimeoutAlone(t *testing.T) {
+	c := newAdaptiveController()
+	c.SetAdaptiveTimeout(false)
+	c.limit = 500
+	c.timeout = 500 * time.Millisecond
+	fillSamples(c, outcomeTimeout, 500*time.Millisecond)
+
+	c.recalibrate()
+
+	if c.timeout != 500*time.Millisecond {
+		t.Errorf("timeout = %s, want unchanged at 500ms with adaptive timeout disabled", c.timeout)
+	}
+	if want := 250; c.limit != want {
+		t.Errorf("limit = %d, want %d (concurrency still adapts)", c.limit, want)
+	}
+}
+
+func TestRecalibrate_AdaptiveRateDisabledLeavesLimitAlone(t *testing.T) {
+	c := newAdaptiveController()
+	c.SetAdaptiveRate(false)
+	c.limit = 500
+	c.timeout = 500 * time.Millisecond
+	fillSamples(c, outcomeTimeout, 500*time.Millisecond)
+
+	c.recalibrate()
+
+	if c.limit != 500 {
+		t.Errorf("limit = %d, want unchanged at 500 with -auto-rate disabled", c.limit)
+	}
+	if want := 600 * time.Millisecond; c.timeout != want {
+		t.Errorf("timeout = %s, want %s (timeout still adapts)", c.timeout, want)
+	}
+}
+
+func TestRecalibrate_CallsAdjustLoggerOnChange(t *testing.T) {
+	c := newAdaptiveController()
+	c.limit = 500
+	c.timeout = 500 * time.Millisecond
+	var oldSeen, newSeen int
+	var reasonSeen string
+	calls := 0
+	c.SetAdjustLogger(func(oldLimit, newLimit int, reason string) {
+		calls++
+		oldSeen, newSeen, reasonSeen = oldLimit, newLimit, reason
+	})
+	fillSamples(c, outcomeTimeout, 500*time.Millisecond)
+
+	c.recalibrate()
+
+	if calls != 1 {
+		t.Fatalf("onAdjust called %d times, want 1", calls)
+	}
+	if oldSeen != 500 || newSeen != 250 {
+		t.Errorf("onAdjust(%d, %d, ...), want (500, 250, ...)", oldSeen, newSeen)
+	}
+	if reasonSeen == "" {
+		t.Error("onAdjust reason was empty")
+	}
+}
+
+func TestRecalibrate_SkipsAdjustLoggerWhenLimitUnchanged(t *testing.T) {
+	c := newAdaptiveController()
+	c.SetAdaptiveRate(false)
+	c.limit = 500
+	c.timeout = 500 * time.Millisecond
+	calls := 0
+	c.SetAdjustLogger(func(int, int, string) { calls++ })
+	fillSamples(c, outcomeTimeout, 500*time.Millisecond)
+
+	c.recalibrate()
+
+	if calls != 0 {
+		t.Errorf("onAdjust called %d times, want 0 (limit didn't change)", calls)
+	}
+}
+
+func TestRelease_TracksSmoothedRTT(t *testing.T) {
+	c := newAdaptiveController()
+	c.Acquire()
+	c.Release(100*time.Millisecond, nil)
+	if got := c.SmoothedRTT(); got != 100*time.Millisecond {
+		t.Errorf("SmoothedRTT after one sample = %s, want %s (seeded to first latency)", got, 100*time.Millisecond)
+	}
+
+	c.Acquire()
+	c.Release(200*time.Millisecond, nil)
+	if got := c.SmoothedRTT(); got <= 100*time.Millisecond || got >= 200*time.Millisecond {
+		t.Errorf("SmoothedRTT after second sample = %s, want strictly between 100ms and 200ms", got)
+	}
+}
+
+func TestPercentile(t *testing.T) {
+	d := []time.Duration{
+		10 * time.Millisecond,
+		50 * time.Millisecond,
+		20 * time.Millisecond,
+		40 * time.Millisecond,
+		30 * time.Millisecond,
+	}
+	if got, want := percentile(d, 0.95), 40*time.Millisecond; got != want {
+		t.Errorf("percentile(d, 0.95) = %s, want %s", got, want)
+	}
+	if got, want := percentile(d, 0), 10*time.Millisecond; got != want {
+		t.Errorf("percentile(d, 0) = %s, want %s", got, want)
+	}
+}
@@ -0,0 +1,77 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"time"
+)
+
+// recoverySyncInterval bounds how much of a -out-file's recovery journal can
+// be lost to a crash: a result is already on disk as soon as Emit writes it
+// (recoverySink never buffers in a bufio.Writer), but without a forced fsync
+// it may still only be sitting in the OS page cache when the machine loses
+// power, not yet on the platter/flash itself.
+const recoverySyncInterval = 2 * time.Second
+
+// recoverySinkSuffix is appended to -out-file's path to name its recovery
+// journal, e.g. scan.xml -> scan.xml.partial.
+const recoverySinkSuffix = ".partial"
+
+// recoverySink tees every result reaching -out-file to a sibling journal
+// file as newline-delimited JSON, fsynced every recoverySyncInterval, and
+// exists purely as a crash-safety net. It's not needed for every -out-format:
+// json, jsonl, hostjsonl, csv, and grepable already write each result
+// straight to -out-file as it's found, so a kill -9 or power loss mid-scan
+// already leaves that file usable on its own. xml is the exception -- it can
+// only produce a well-formed document once every result is known, so it
+// buffers everything in memory and writes it all at once in Close -- and
+// that's what recoverySink's journal recovers: a crash before Close leaves
+// -out-file itself empty or truncated, but the journal next to it still
+// parses as one ScanResult per line. main removes the journal once the scan
+// (and -out-file) finishes cleanly, since the real file is the complete
+// record from then on.
+type recoverySink struct {
+	path     string
+	f        *os.File
+	enc      *json.Encoder
+	lastSync time.Time
+}
+
+// recoveryPath is the journal path for a given -out-file path.
+func recoveryPath(outFilePath string) string {
+	return outFilePath + recoverySinkSuffix
+}
+
+func newRecoverySink(outFilePath string) (*recoverySink, error) {
+	path := recoveryPath(outFilePath)
+	f, err := os.OpenFile(path, os.O_CREATE|os.O_WRONLY|os.O_TRUNC, 0o644)
+	if err != nil {
+		return nil, fmt.Errorf("recovery journal: %w", err)
+	}
+	return &recoverySink{path: path, f: f, enc: json.NewEncoder(f)}, nil
+}
+
+func (s *recoverySink) Open() error { return nil }
+
+func (s *recoverySink) Emit(r ScanResult) error {
+	if err := s.enc.Encode(r); err != nil {
+		return err
+	}
+	if time.Since(s.lastSync) < recoverySyncInterval {
+		return nil
+	}
+	s.lastSync = time.Now()
+	return s.f.Sync()
+}
+
+func (s *recoverySink) Finish(ScanMeta) error { return nil }
+
+// Close just closes the journal's file handle; it does not remove the
+// journal. Whether the journal should be removed depends on whether the
+// scan's real -out-file finished writing without error, which this sink has
+// no way to know about its sibling sinks -- so main removes it explicitly
+// once it's confirmed that.
+func (s *recoverySink) Close() error {
+	return s.f.Close()
+}
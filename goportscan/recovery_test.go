@@ -0,0 +1,113 @@
+package main
+
+import (
+	"bufio"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestRecoverySink_JournalParsesAsNDJSON(t *testing.T) {
+	outFile := filepath.Join(t.TempDir(), "scan.xml")
+	s, err := newRecoverySink(outFile)
+	if err != nil {
+		t.Fatalf("newRecoverySink: %v", err)
+	}
+
+	results := []ScanResult{
+		{Host: "10.0.0.1", Port: 22, State: "open"},
+		{Host: "10.0.0.1", Port: 80, State: "closed"},
+	}
+	for _, r := range results {
+		if err := s.Emit(r); err != nil {
+			t.Fatalf("Emit: %v", err)
+		}
+	}
+	if err := s.Close(); err != nil {
+		t.Fatalf("Close: %v", err)
+	}
+
+	got := readNDJSON(t, recoveryPath(outFile))
+	if len(got) != len(results) {
+		t.Fatalf("journal has %d result(s), want %d", len(got), len(results))
+	}
+	for i, r := range results {
+		if got[i].Host != r.Host || got[i].Port != r.Port || got[i].State != r.State {
+			t.Errorf("journal entry %d = %+v, want %+v", i, got[i], r)
+		}
+	}
+}
+
+// TestRecoverySink_SurvivesACrashBeforeCloseOrFinish simulates a kill -9
+// mid-scan: Emit a few results, then -- unlike every other test, which
+// calls Close -- stop there, the same as a process that never gets to run
+// its deferred cleanup. The journal file on disk, read back by a fresh
+// process, must still parse.
+func TestRecoverySink_SurvivesACrashBeforeCloseOrFinish(t *testing.T) {
+	outFile := filepath.Join(t.TempDir(), "scan.xml")
+	s, err := newRecoverySink(outFile)
+	if err != nil {
+		t.Fatalf("newRecoverySink: %v", err)
+	}
+
+	if err := s.Emit(ScanResult{Host: "10.0.0.1", Port: 22, State: "open"}); err != nil {
+		t.Fatalf("Emit: %v", err)
+	}
+	if err := s.Emit(ScanResult{Host: "10.0.0.1", Port: 443, State: "open"}); err != nil {
+		t.Fatalf("Emit: %v", err)
+	}
+	// No Close, no Finish: the scan "crashes" here.
+
+	got := readNDJSON(t, recoveryPath(outFile))
+	if len(got) != 2 {
+		t.Fatalf("journal has %d result(s) after simulated crash, want 2", len(got))
+	}
+	if got[0].Port != 22 || got[1].Port != 443 {
+		t.Errorf("journal entries = %+v, want ports 22 then 443", got)
+	}
+}
+
+func TestRecoverySink_CloseDoesNotRemoveTheJournal(t *testing.T) {
+	outFile := filepath.Join(t.TempDir(), "scan.xml")
+	s, err := newRecoverySink(outFile)
+	if err != nil {
+		t.Fatalf("newRecoverySink: %v", err)
+	}
+	if err := s.Emit(ScanResult{Host: "10.0.0.1", Port: 22, State: "open"}); err != nil {
+		t.Fatalf("Emit: %v", err)
+	}
+	if err := s.Close(); err != nil {
+		t.Fatalf("Close: %v", err)
+	}
+
+	if _, err := os.Stat(recoveryPath(outFile)); err != nil {
+		t.Errorf("journal should still exist after Close (only main removes it on a clean finish): %v", err)
+	}
+}
+
+// readNDJSON reads path as newline-delimited ScanResult JSON, failing the
+// test on any malformed line -- a journal this test wrote should never have
+// one.
+func readNDJSON(t *testing.T, path string) []ScanResult {
+	t.Helper()
+	f, err := os.Open(path)
+	if err != nil {
+		t.Fatalf("open journal: %v", err)
+	}
+	defer f.Close()
+
+	var results []ScanResult
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		var r ScanResult
+		if err := json.Unmarshal(scanner.Bytes(), &r); err != nil {
+			t.Fatalf("journal line %q did not parse: %v", scanner.Text(), err)
+		}
+		results = append(results, r)
+	}
+	if err := scanner.Err(); err != nil {
+		t.Fatalf("scanning journal: %v", err)
+	}
+	return results
+}
@@ -0,0 +1,103 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"net"
+	"time"
+)
+
+// dnsLookuper is the subset of *net.Resolver that resolveIPs and lookupAddr
+// need; tests substitute a stub implementation so neither depends on real
+// DNS, the same role lookupAddr's swappable func var played before.
+type dnsLookuper interface {
+	LookupIPAddr(ctx context.Context, host string) ([]net.IPAddr, error)
+	LookupAddr(ctx context.Context, addr string) ([]string, error)
+}
+
+// dnsResolver is the resolver every hostname and PTR lookup in this package
+// goes through. It defaults to net.DefaultResolver, the system resolver;
+// -dns-server points it at a specific server instead, for a pivot where the
+// local resolver doesn't know about internal names.
+var dnsResolver dnsLookuper = net.DefaultResolver
+
+// dnsRetries and dnsRetryDelay bound how hard resolveIPs and lookupAddr
+// work before giving up on a transient DNS failure -- a dropped UDP
+// packet or a momentarily unreachable server, not an authoritative
+// NXDOMAIN, which always returns immediately regardless.
+const (
+	dnsRetries    = 2
+	dnsRetryDelay = 100 * time.Millisecond
+)
+
+// configureDNSResolver points dnsResolver at server (host:port, e.g.
+// "1.1.1.1:53") instead of the system resolver. PreferGo is forced because
+// Dial is only honored by the pure-Go resolver -- the platform's cgo
+// resolver ignores it and would silently keep using /etc/resolv.conf.
+func configureDNSResolver(server string) {
+	dnsResolver = &net.Resolver{
+		PreferGo: true,
+		Dial: func(ctx context.Context, network, _ string) (net.Conn, error) {
+			var d net.Dialer
+			return d.DialContext(ctx, network, server)
+		},
+	}
+}
+
+// resolveIPs looks up host's IP addresses through dnsResolver, retrying a
+// transient failure a couple of times before giving up.
+func resolveIPs(host string) ([]net.IP, error) {
+	var lastErr error
+	for attempt := 0; attempt <= dnsRetries; attempt++ {
+		if attempt > 0 {
+			time.Sleep(dnsRetryDelay)
+		}
+		addrs, err := dnsResolver.LookupIPAddr(context.Background(), host)
+		if err == nil {
+			ips := make([]net.IP, len(addrs))
+			for i, a := range addrs {
+				ips[i] = a.IP
+			}
+			return ips, nil
+		}
+		lastErr = err
+		if !isTransientDNSError(err) {
+			return nil, err
+		}
+	}
+	return nil, lastErr
+}
+
+// lookupAddr is net.LookupAddr-shaped (and swappable, the same way it
+// always has been) so filterByPTR doesn't depend on real DNS in tests. By
+// default it resolves through dnsResolver with the same retry-on-transient-
+// failure behavior as resolveIPs.
+var lookupAddr = func(addr string) ([]string, error) {
+	var lastErr error
+	for attempt := 0; attempt <= dnsRetries; attempt++ {
+		if attempt > 0 {
+			time.Sleep(dnsRetryDelay)
+		}
+		names, err := dnsResolver.LookupAddr(context.Background(), addr)
+		if err == nil {
+			return names, nil
+		}
+		lastErr = err
+		if !isTransientDNSError(err) {
+			return nil, err
+		}
+	}
+	return nil, lastErr
+}
+
+// isTransientDNSError reports whether err looks worth retrying -- a
+// timeout or other temporary failure talking to the resolver -- as
+// opposed to an authoritative "no such host" that retrying won't change.
+func isTransientDNSError(err error) bool {
+	var dnsErr *net.DNSError
+	if errors.As(err, &dnsErr) {
+		return dnsErr.IsTimeout || dnsErr.IsTemporary
+	}
+	var netErr net.Error
+	return errors.As(err, &netErr) && netErr.Timeout()
+}
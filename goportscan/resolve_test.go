@@ -0,0 +1,145 @@
+package main
+
+import (
+	"context"
+	"net"
+	"testing"
+)
+
+// stubResolver is a dnsLookuper a test can script: each call to
+// LookupIPAddr/LookupAddr pops the next scripted response, so a test can
+// make the first N attempts fail transiently before succeeding.
+type stubResolver struct {
+	ipAddrResponses []stubIPAddrResponse
+	addrResponses   []stubAddrResponse
+	ipAddrCalls     int
+	addrCalls       int
+}
+
+type stubIPAddrResponse struct {
+	addrs []net.IPAddr
+	err   error
+}
+
+type stubAddrResponse struct {
+	names []string
+	err   error
+}
+
+func (s *stubResolver) LookupIPAddr(ctx context.Context, host string) ([]net.IPAddr, error) {
+	resp := s.ipAddrResponses[s.ipAddrCalls]
+	s.ipAddrCalls++
+	return resp.addrs, resp.err
+}
+
+func (s *stubResolver) LookupAddr(ctx context.Context, addr string) ([]string, error) {
+	resp := s.addrResponses[s.addrCalls]
+	s.addrCalls++
+	return resp.names, resp.err
+}
+
+// withStubResolver swaps dnsResolver for stub, restoring it on cleanup.
+func withStubResolver(t *testing.T, stub *stubResolver) {
+	t.Helper()
+	orig := dnsResolver
+	dnsResolver = stub
+	t.Cleanup(func() { dnsResolver = orig })
+}
+
+func TestResolveIPs_RetriesTransientFailureThenSucceeds(t *testing.T) {
+	timeoutErr := &net.DNSError{Err: "timeout", IsTimeout: true}
+	stub := &stubResolver{
+		ipAddrResponses: []stubIPAddrResponse{
+			{err: timeoutErr},
+			{addrs: []net.IPAddr{{IP: net.ParseIP("10.0.0.5")}}},
+		},
+	}
+	withStubResolver(t, stub)
+
+	ips, err := resolveIPs("internal.example")
+	if err != nil {
+		t.Fatalf("resolveIPs: %v", err)
+	}
+	if len(ips) != 1 || !ips[0].Equal(net.ParseIP("10.0.0.5")) {
+		t.Errorf("ips = %v, want [10.0.0.5]", ips)
+	}
+	if stub.ipAddrCalls != 2 {
+		t.Errorf("LookupIPAddr called %d times, want 2 (one failure, one retry)", stub.ipAddrCalls)
+	}
+}
+
+func TestResolveIPs_GivesUpAfterDnsRetriesTransientFailures(t *testing.T) {
+	timeoutErr := &net.DNSError{Err: "timeout", IsTimeout: true}
+	responses := make([]stubIPAddrResponse, dnsRetries+1)
+	for i := range responses {
+		responses[i] = stubIPAddrResponse{err: timeoutErr}
+	}
+	stub := &stubResolver{ipAddrResponses: responses}
+	withStubResolver(t, stub)
+
+	if _, err := resolveIPs("internal.example"); err == nil {
+		t.Fatal("resolveIPs = nil error, want the last transient failure")
+	}
+	if stub.ipAddrCalls != dnsRetries+1 {
+		t.Errorf("LookupIPAddr called %d times, want %d (initial attempt plus %d retries)", stub.ipAddrCalls, dnsRetries+1, dnsRetries)
+	}
+}
+
+func TestResolveIPs_DoesNotRetryAuthoritativeNotFound(t *testing.T) {
+	notFoundErr := &net.DNSError{Err: "no such host", IsNotFound: true}
+	stub := &stubResolver{ipAddrResponses: []stubIPAddrResponse{{err: notFoundErr}}}
+	withStubResolver(t, stub)
+
+	if _, err := resolveIPs("nosuchhost.example"); err == nil {
+		t.Fatal("resolveIPs = nil error, want the not-found failure")
+	}
+	if stub.ipAddrCalls != 1 {
+		t.Errorf("LookupIPAddr called %d times, want 1 (no retry on a non-transient failure)", stub.ipAddrCalls)
+	}
+}
+
+func TestLookupAddr_RetriesTransientFailureThenSucceeds(t *testing.T) {
+	timeoutErr := &net.DNSError{Err: "timeout", IsTimeout: true}
+	stub := &stubResolver{
+		addrResponses: []stubAddrResponse{
+			{err: timeoutErr},
+			{names: []string{"db1.db.internal."}},
+		},
+	}
+	withStubResolver(t, stub)
+
+	names, err := lookupAddr("10.0.0.1")
+	if err != nil {
+		t.Fatalf("lookupAddr: %v", err)
+	}
+	if len(names) != 1 || names[0] != "db1.db.internal." {
+		t.Errorf("names = %v, want [db1.db.internal.]", names)
+	}
+	if stub.addrCalls != 2 {
+		t.Errorf("LookupAddr called %d times, want 2 (one failure, one retry)", stub.addrCalls)
+	}
+}
+
+func TestIsTransientDNSError(t *testing.T) {
+	tests := []struct {
+		name string
+		err  error
+		want bool
+	}{
+		{"timeout", &net.DNSError{IsTimeout: true}, true},
+		{"temporary", &net.DNSError{IsTemporary: true}, true},
+		{"not found", &net.DNSError{IsNotFound: true}, false},
+		{"plain error", errStub("boom"), false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := isTransientDNSError(tt.err); got != tt.want {
+				t.Errorf("isTransientDNSError(%v) = %v, want %v", tt.err, got, tt.want)
+			}
+		})
+	}
+}
+
+type errStub string
+
+func (e errStub) Error() string { return string(e) }
@@ -0,0 +1,29 @@
+//go:build !windows
+
+package main
+
+import (
+	"syscall"
+
+	"golang.org/x/sys/unix"
+)
+
+// reuseAddrControl is a net.Dialer.Control used when -reuse-addr is set
+// (the default): SO_REUSEADDR lets a new dial bind over a local
+// address:port pair still held in TIME_WAIT, and a zero SO_LINGER makes
+// Close send an immediate RST instead of lingering in TIME_WAIT at all.
+// Both matter for a scanner that can dial the same target thousands of
+// times a minute and would otherwise exhaust the ephemeral port range
+// mid-scan.
+func reuseAddrControl(network, address string, c syscall.RawConn) error {
+	var sockErr error
+	if err := c.Control(func(fd uintptr) {
+		if sockErr = unix.SetsockoptInt(int(fd), unix.SOL_SOCKET, unix.SO_REUSEADDR, 1); sockErr != nil {
+			return
+		}
+		sockErr = unix.SetsockoptLinger(int(fd), unix.SOL_SOCKET, unix.SO_LINGER, &unix.Linger{Onoff: 1, Linger: 0})
+	}); err != nil {
+		return err
+	}
+	return sockErr
+}
@@ -0,0 +1,13 @@
+//go:build windows
+
+package main
+
+import "syscall"
+
+// reuseAddrControl is a no-op on Windows: SO_REUSEADDR has unsafe,
+// different semantics there (it allows silently hijacking another
+// process's bound port rather than just reusing a TIME_WAIT entry), so
+// -reuse-addr has no effect on this platform.
+func reuseAddrControl(network, address string, c syscall.RawConn) error {
+	return nil
+}
@@ -0,0 +1,223 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"time"
+)
+
+// scanMode selects how a port is probed.
+type scanMode string
+
+const (
+	scanModeConnect scanMode = "connect"
+	scanModeSYN     scanMode = "syn"
+	scanModeUDP     scanMode = "udp"
+)
+
+func parseScanMode(s string) (scanMode, error) {
+	switch scanMode(s) {
+	case "", scanModeConnect:
+		return scanModeConnect, nil
+	case scanModeSYN:
+		return scanModeSYN, nil
+	case scanModeUDP:
+		return scanModeUDP, nil
+	default:
+		return "", fmt.Errorf("unknown scan type %q (want connect, syn, or udp)", s)
+	}
+}
+
+// portState is nmap's three-way (plus ambiguous) classification of a probed
+// port, distinct from the plain open/not-open bool the rate limiter cares
+// about: a RST (closed) and a silent drop (filtered) both mean "not open",
+// but they imply very different things about the network path to the host.
+type portState string
+
+const (
+	portOpen         portState = "open"
+	portClosed       portState = "closed"
+	portFiltered     portState = "filtered"
+	portOpenFiltered portState = "open|filtered"
+
+	// portUnreachable isn't a per-port probe outcome: it's the single
+	// synthetic result hostShortCircuit emits for a whole host once enough
+	// of its dials come back ENETUNREACH/EHOSTUNREACH, marking that the
+	// rest of its ports were skipped rather than dutifully timed out on.
+	portUnreachable portState = "unreachable"
+
+	// portNotScanned, like portUnreachable, is a synthetic whole-host result
+	// rather than a per-port probe outcome: it's what hostTimeoutAbort emits
+	// once a host racks up enough consecutive silent-drop timeouts in a row,
+	// the softer sibling of portUnreachable's explicit ENETUNREACH/EHOSTUNREACH
+	// check -- see -max-consecutive-timeouts in main.
+	portNotScanned portState = "not-scanned"
+)
+
+// synProber is implemented by the platform-specific half-open scanner. On
+// platforms/privilege levels where raw sockets aren't available,
+// newSYNProber returns a nil synProber and the caller falls back to a
+// connect scan.
+type synProber interface {
+	// Probe sends a SYN to addr and classifies the reply: a SYN-ACK is open,
+	// an RST is closed, and no reply within timeout is filtered.
+	Probe(addr Addr, timeout time.Duration) (state portState, err error)
+	Close() error
+}
+
+// connectProbe is the original full TCP handshake probe, reused as the
+// "connect" scan mode and as the fallback for syn/udp when raw sockets
+// aren't available. openGrace is -open-grace's budget for a second attempt
+// if the first dial times out; see dialWithGrace.
+func connectProbe(addr Addr, timeout, openGrace time.Duration) (portState, error) {
+	conn, err := dialWithGrace(addr, timeout, openGrace)
+	if err == nil {
+		conn.Close()
+		return portOpen, nil
+	}
+	// err is always returned alongside the classification (rather than
+	// swallowed for the closed/refused case) so the adaptive rate limiter's
+	// classify(err) still sees the real dial error.
+	if isConnRefused(err) {
+		return portClosed, err
+	}
+	return portFiltered, err
+}
+
+// dialWithGrace dials addr within timeout and, if that attempt specifically
+// times out (a refusal is never retried -- the far side already answered,
+// definitively, with "no") and grace is positive, makes one more attempt
+// bounded by grace before giving up. This is -open-grace: a loaded service
+// that completes its handshake just past the first deadline would otherwise
+// be misclassified as filtered rather than open. The tradeoff is that every
+// truly filtered port now costs up to timeout+grace instead of just
+// timeout, so grace defaults to 0 (no retry, today's behavior).
+func dialWithGrace(addr Addr, timeout, grace time.Duration) (net.Conn, error) {
+	conn, err := dialTCP(context.Background(), addr.Dial(), timeout)
+	if err == nil || grace <= 0 || !isTimeout(err) {
+		return conn, err
+	}
+	return dialTCP(context.Background(), addr.Dial(), grace)
+}
+
+// confirmOpenWindow is how long connectProbeConfirmed waits after a
+// successful handshake to see whether the connection stays established (or
+// the far side sends something) before trusting it as a real open port --
+// see -confirm-open.
+const confirmOpenWindow = 200 * time.Millisecond
+
+// connectProbeConfirmed is connectProbe plus a brief liveness check: some
+// middleboxes complete the TCP handshake and then immediately RST, which
+// connectProbe alone reports as a clean open. After connecting, it sets a
+// short read deadline and tries a read -- data, or a timeout (the far side
+// just isn't talking first), both still count as a genuine open, but an
+// immediate reset downgrades the result to filtered, since a real service
+// wouldn't tear down the connection it just accepted. It's opt-in (-confirm-open)
+// since the extra read costs up to confirmOpenWindow on every open port.
+// openGrace is -open-grace's budget for a second dial attempt; see
+// dialWithGrace.
+func connectProbeConfirmed(addr Addr, timeout, openGrace time.Duration) (portState, error) {
+	conn, err := dialWithGrace(addr, timeout, openGrace)
+	if err != nil {
+		if isConnRefused(err) {
+			return portClosed, err
+		}
+		return portFiltered, err
+	}
+	defer conn.Close()
+
+	conn.SetReadDeadline(time.Now().Add(confirmOpenWindow))
+	_, err = conn.Read(make([]byte, 1))
+	if err == nil || isTimeout(err) {
+		return portOpen, nil
+	}
+	if isConnReset(err) {
+		return portFiltered, err
+	}
+	// Any other read error (e.g. a graceful close) still counts as open:
+	// the handshake genuinely completed, and plenty of well-behaved services
+	// don't keep a freshly accepted connection open with nothing to say.
+	return portOpen, nil
+}
+
+// udpProbe sends a protocol-appropriate (or empty) UDP payload and
+// classifies the result: a reply means open, an ICMP port-unreachable means
+// closed, and silence means open|filtered, since plenty of UDP services
+// never answer an unsolicited probe and there's no RST equivalent to tell
+// the two apart.
+func udpProbe(addr Addr, timeout time.Duration) (portState, error) {
+	// UDP probes always dial directly: neither proxy protocol this scanner
+	// supports (SOCKS5, HTTP CONNECT) carries raw UDP, only TCP CONNECTs, so
+	// -proxy has no effect here regardless of addr.Host.
+	conn, err := net.DialTimeout("udp", addr.Dial(), timeout)
+	if err != nil {
+		return portFiltered, err
+	}
+	defer conn.Close()
+
+	payload := udpPayloadFor(addr.Port)
+	if _, err := conn.Write(payload); err != nil {
+		return portFiltered, err
+	}
+
+	conn.SetReadDeadline(time.Now().Add(timeout))
+	buf := make([]byte, 512)
+	_, err = conn.Read(buf)
+	if err == nil {
+		return portOpen, nil // got a reply: definitely open
+	}
+	if isICMPPortUnreachable(err) {
+		return portClosed, nil
+	}
+	return portOpenFiltered, nil // no reply within the deadline
+}
+
+// udpPayloadFor returns a protocol-specific probe payload for well-known
+// UDP services, or an empty payload otherwise.
+func udpPayloadFor(port int) []byte {
+	switch port {
+	case 53: // minimal DNS query for "."
+		return []byte{
+			0x00, 0x00, 0x01, 0x00, 0x00, 0x01, 0x00, 0x00,
+			0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x01, 0x00,
+			0x01,
+		}
+	case 161: // SNMP GetRequest for sysDescr.0, community "public"
+		return []byte{
+			0x30, 0x26, 0x02, 0x01, 0x00, 0x04, 0x06, 'p', 'u', 'b', 'l', 'i', 'c',
+			0xa0, 0x19, 0x02, 0x01, 0x01, 0x02, 0x01, 0x00, 0x02, 0x01, 0x00,
+			0x30, 0x0e, 0x30, 0x0c, 0x06, 0x08, 0x2b, 0x06, 0x01, 0x02, 0x01, 0x01, 0x01, 0x00, 0x05, 0x00,
+		}
+	default:
+		return []byte{}
+	}
+}
+
+// isICMPPortUnreachable reports whether err represents an ICMP destination
+// unreachable (port unreachable) response to a UDP datagram.
+func isICMPPortUnreachable(err error) bool {
+	return isConnRefused(err)
+}
+
+// probeWithRetry calls probe and, if it comes back portFiltered (no reply
+// within the deadline, as opposed to the definitive portClosed a refusal
+// gets classified as), retries up to retries more times before giving up.
+// A single timed-out dial on a congested network can look identical to a
+// genuinely filtered port; retrying trades a little time for fewer false
+// "filtered" calls on what's actually just a slow responder. A refusal is
+// never retried, since it's already a definitive answer.
+//
+// The delay before each retry comes from strategy(attempt, base), so -backoff
+// controls how that delay grows across attempts; sleep is called with the
+// result instead of probeWithRetry calling time.Sleep itself, so a test can
+// pass a fake clock and assert each strategy's exact delay sequence without
+// a single real-time sleep slowing it down.
+func probeWithRetry(probe func() (portState, error), retries int, base time.Duration, strategy backoffStrategy, sleep func(time.Duration)) (portState, error) {
+	state, err := probe()
+	for attempt := 0; attempt < retries && state == portFiltered; attempt++ {
+		sleep(strategy(attempt, base))
+		state, err = probe()
+	}
+	return state, err
+}
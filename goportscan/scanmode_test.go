@@ -0,0 +1,334 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"net"
+	"syscall"
+	"testing"
+	"time"
+)
+
+var errConnRefusedStub = errors.New("connection refused (stub)")
+
+func TestParseScanMode(t *testing.T) {
+	cases := []struct {
+		in      string
+		want    scanMode
+		wantErr bool
+	}{
+		{"", scanModeConnect, false},
+		{"connect", scanModeConnect, false},
+		{"syn", scanModeSYN, false},
+		{"udp", scanModeUDP, false},
+		{"bogus", "", true},
+	}
+	for _, c := range cases {
+		got, err := parseScanMode(c.in)
+		if c.wantErr {
+			if err == nil {
+				t.Errorf("parseScanMode(%q): expected error, got none", c.in)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("parseScanMode(%q): unexpected error: %v", c.in, err)
+			continue
+		}
+		if got != c.want {
+			t.Errorf("parseScanMode(%q) = %q, want %q", c.in, got, c.want)
+		}
+	}
+}
+
+func TestUDPPayloadFor(t *testing.T) {
+	if len(udpPayloadFor(53)) == 0 {
+		t.Error("udpPayloadFor(53): want a non-empty DNS query payload")
+	}
+	if len(udpPayloadFor(161)) == 0 {
+		t.Error("udpPayloadFor(161): want a non-empty SNMP payload")
+	}
+	if got := udpPayloadFor(12345); len(got) != 0 {
+		t.Errorf("udpPayloadFor(12345) = %v, want empty payload for an unrecognized port", got)
+	}
+}
+
+// TestConnectProbe_ClassifiesClosedPort confirms a refused connection is
+// reported as portClosed rather than the generic "not open" the scanner used
+// to collapse closed and filtered into.
+func TestConnectProbe_ClassifiesClosedPort(t *testing.T) {
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("listen: %v", err)
+	}
+	port := ln.Addr().(*net.TCPAddr).Port
+	ln.Close() // nothing listening now: the next dial should be refused
+
+	state, err := connectProbe(Addr{IP: net.ParseIP("127.0.0.1"), Port: port}, 200*time.Millisecond, 0)
+	if state != portClosed {
+		t.Errorf("connectProbe on a closed port = %q, want %q", state, portClosed)
+	}
+	if err == nil {
+		t.Error("connectProbe on a closed port: expected the dial error to still be returned for the rate limiter, got nil")
+	}
+}
+
+// TestConnectProbe_IPv6Loopback confirms connectProbe builds a valid
+// "[host]:port" address for IPv6 targets instead of the bare "host:port"
+// that net.JoinHostPort avoids; the old "%s:%d" formatting would have
+// produced "::1:<port>", which net.Dial parses as a different, bogus IPv6
+// address rather than host ::1 on a port.
+func TestConnectProbe_IPv6Loopback(t *testing.T) {
+	ln, err := net.Listen("tcp", "[::1]:0")
+	if err != nil {
+		t.Skipf("IPv6 loopback unavailable: %v", err)
+	}
+	defer ln.Close()
+	port := ln.Addr().(*net.TCPAddr).Port
+
+	state, err := connectProbe(Addr{IP: net.ParseIP("::1"), Port: port}, 200*time.Millisecond, 0)
+	if err != nil {
+		t.Fatalf("connectProbe: %v", err)
+	}
+	if state != portOpen {
+		t.Errorf("connectProbe on an open IPv6 listener = %q, want %q", state, portOpen)
+	}
+}
+
+// TestDialWithGrace_RetriesOnTimeoutWhenGraceSet confirms -open-grace gives
+// a timed-out dial a second, longer-budgeted attempt rather than giving up
+// after the first. dialer is faked (a real timeout over loopback is
+// unreliable to arrange) so the test can just count attempts.
+func TestDialWithGrace_RetriesOnTimeoutWhenGraceSet(t *testing.T) {
+	original := dialer
+	defer func() { dialer = original }()
+
+	calls := 0
+	dialer = func(ctx context.Context, network, address string) (net.Conn, error) {
+		calls++
+		return nil, context.DeadlineExceeded
+	}
+
+	if _, err := dialWithGrace(Addr{IP: net.ParseIP("203.0.113.1"), Port: 9}, 50*time.Millisecond, 300*time.Millisecond); err == nil {
+		t.Fatal("dialWithGrace: expected an error, got nil")
+	}
+	if calls != 2 {
+		t.Errorf("dialer called %d times, want 2 (the initial dial plus one grace retry)", calls)
+	}
+}
+
+// TestDialWithGrace_NoRetryWhenGraceIsZero confirms -open-grace's default
+// (0) leaves dialWithGrace's behavior unchanged: exactly one dial attempt.
+func TestDialWithGrace_NoRetryWhenGraceIsZero(t *testing.T) {
+	original := dialer
+	defer func() { dialer = original }()
+
+	calls := 0
+	dialer = func(ctx context.Context, network, address string) (net.Conn, error) {
+		calls++
+		return nil, context.DeadlineExceeded
+	}
+
+	if _, err := dialWithGrace(Addr{IP: net.ParseIP("203.0.113.1"), Port: 9}, 50*time.Millisecond, 0); err == nil {
+		t.Fatal("dialWithGrace: expected an error, got nil")
+	}
+	if calls != 1 {
+		t.Errorf("dialer called %d times, want 1: -open-grace defaults to 0, no retry", calls)
+	}
+}
+
+// TestDialWithGrace_NoRetryOnRefusal confirms a grace retry is reserved for
+// an actual timeout: a refusal is a definitive, immediate "no" from the far
+// side, not a slow service that might still finish its handshake.
+func TestDialWithGrace_NoRetryOnRefusal(t *testing.T) {
+	original := dialer
+	defer func() { dialer = original }()
+
+	calls := 0
+	dialer = func(ctx context.Context, network, address string) (net.Conn, error) {
+		calls++
+		return nil, syscall.ECONNREFUSED
+	}
+
+	if _, err := dialWithGrace(Addr{IP: net.ParseIP("203.0.113.1"), Port: 9}, 50*time.Millisecond, 300*time.Millisecond); err == nil {
+		t.Fatal("dialWithGrace: expected an error, got nil")
+	}
+	if calls != 1 {
+		t.Errorf("dialer called %d times, want 1: a refusal should never trigger a grace retry", calls)
+	}
+}
+
+// TestConnectProbeConfirmed_AcceptsGenuinelyOpenPort models a well-behaved
+// listener that accepts the connection and holds it open: -confirm-open
+// should still report portOpen, not penalize a service just for not
+// speaking first.
+func TestConnectProbeConfirmed_AcceptsGenuinelyOpenPort(t *testing.T) {
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("listen: %v", err)
+	}
+	defer ln.Close()
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		conn, err := ln.Accept()
+		if err != nil {
+			return
+		}
+		defer conn.Close()
+		time.Sleep(confirmOpenWindow + 100*time.Millisecond)
+	}()
+
+	port := ln.Addr().(*net.TCPAddr).Port
+	state, err := connectProbeConfirmed(Addr{IP: net.ParseIP("127.0.0.1"), Port: port}, 200*time.Millisecond, 0)
+	if err != nil {
+		t.Fatalf("connectProbeConfirmed: %v", err)
+	}
+	if state != portOpen {
+		t.Errorf("connectProbeConfirmed on a held-open connection = %q, want %q", state, portOpen)
+	}
+	<-done
+}
+
+// TestConnectProbeConfirmed_DowngradesImmediateReset models the middlebox
+// behavior -confirm-open exists to catch: a handshake that completes and is
+// then immediately torn down with an RST should no longer be reported as a
+// clean open.
+func TestConnectProbeConfirmed_DowngradesImmediateReset(t *testing.T) {
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("listen: %v", err)
+	}
+	defer ln.Close()
+	go func() {
+		conn, err := ln.Accept()
+		if err != nil {
+			return
+		}
+		// SO_LINGER with a zero timeout forces an RST on Close instead of
+		// the usual graceful FIN, simulating the middlebox's immediate reset.
+		if tcpConn, ok := conn.(*net.TCPConn); ok {
+			tcpConn.SetLinger(0)
+		}
+		conn.Close()
+	}()
+
+	port := ln.Addr().(*net.TCPAddr).Port
+	state, err := connectProbeConfirmed(Addr{IP: net.ParseIP("127.0.0.1"), Port: port}, 200*time.Millisecond, 0)
+	if err == nil {
+		t.Error("connectProbeConfirmed: expected the reset's error to be returned, got nil")
+	}
+	if state != portFiltered {
+		t.Errorf("connectProbeConfirmed on an immediate reset = %q, want %q", state, portFiltered)
+	}
+}
+
+// TestProbeWithRetry_RetriesOnFiltered models a listener that drops the
+// first connection attempt (e.g. a congested network eating the SYN) and
+// only starts answering on a later attempt: the underlying probe is faked
+// rather than timed over a real socket, since a real dial timeout long
+// enough to be reliable in CI would make this test needlessly slow.
+func TestProbeWithRetry_RetriesOnFiltered(t *testing.T) {
+	var calls int
+	probe := func() (portState, error) {
+		calls++
+		if calls == 1 {
+			return portFiltered, context.DeadlineExceeded
+		}
+		return portOpen, nil
+	}
+
+	state, err := probeWithRetry(probe, 2, time.Millisecond, constantBackoff, noopSleep)
+	if err != nil {
+		t.Errorf("probeWithRetry: unexpected error: %v", err)
+	}
+	if state != portOpen {
+		t.Errorf("probeWithRetry = %q, want %q after the retry succeeds", state, portOpen)
+	}
+	if calls != 2 {
+		t.Errorf("probe called %d times, want 2 (1 initial + 1 retry)", calls)
+	}
+}
+
+func TestProbeWithRetry_NeverRetriesClosed(t *testing.T) {
+	var calls int
+	probe := func() (portState, error) {
+		calls++
+		return portClosed, errConnRefusedStub
+	}
+
+	state, err := probeWithRetry(probe, 3, time.Millisecond, constantBackoff, noopSleep)
+	if state != portClosed || err != errConnRefusedStub {
+		t.Errorf("probeWithRetry = (%q, %v), want (%q, %v)", state, err, portClosed, errConnRefusedStub)
+	}
+	if calls != 1 {
+		t.Errorf("probe called %d times, want 1: a refusal is definitive and should never be retried", calls)
+	}
+}
+
+func TestProbeWithRetry_GivesUpAfterExhaustingRetries(t *testing.T) {
+	var calls int
+	probe := func() (portState, error) {
+		calls++
+		return portFiltered, context.DeadlineExceeded
+	}
+
+	state, _ := probeWithRetry(probe, 2, time.Millisecond, constantBackoff, noopSleep)
+	if state != portFiltered {
+		t.Errorf("probeWithRetry = %q, want %q once retries run out", state, portFiltered)
+	}
+	if calls != 3 {
+		t.Errorf("probe called %d times, want 3 (1 initial + 2 retries)", calls)
+	}
+}
+
+func TestShouldReport(t *testing.T) {
+	cases := []struct {
+		state                    portState
+		showClosed, showFiltered bool
+		want                     bool
+	}{
+		{portOpen, false, false, true},
+		{portOpenFiltered, false, false, true},
+		{portClosed, false, false, false},
+		{portClosed, true, false, true},
+		{portFiltered, false, false, false},
+		{portFiltered, false, true, true},
+	}
+	for _, c := range cases {
+		if got := shouldReport(c.state, c.showClosed, c.showFiltered); got != c.want {
+			t.Errorf("shouldReport(%q, %v, %v) = %v, want %v", c.state, c.showClosed, c.showFiltered, got, c.want)
+		}
+	}
+}
+
+// TestUDPProbe_SilentListenerIsReportedOpen exercises the "open|filtered"
+// convention: a UDP service that never replies to an unsolicited probe
+// within the deadline is reported open rather than closed, since silence is
+// the normal behavior for most UDP services (e.g. when the probe isn't a
+// well-formed request for that protocol).
+func TestUDPProbe_SilentListenerIsReportedOpen(t *testing.T) {
+	ln, err := net.ListenPacket("udp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("listen: %v", err)
+	}
+	defer ln.Close()
+	go func() {
+		buf := make([]byte, 1024)
+		for {
+			if _, _, err := ln.ReadFrom(buf); err != nil {
+				return
+			}
+			// Never reply.
+		}
+	}()
+
+	port := ln.LocalAddr().(*net.UDPAddr).Port
+	state, err := udpProbe(Addr{IP: net.ParseIP("127.0.0.1"), Port: port}, 100*time.Millisecond)
+	if err != nil {
+		t.Fatalf("udpProbe: %v", err)
+	}
+	if state != portOpenFiltered {
+		t.Errorf("udpProbe on a silent listener = %q, want %q", state, portOpenFiltered)
+	}
+}
@@ -0,0 +1,273 @@
+package main
+
+import (
+	"context"
+	"net"
+	"sort"
+	"strconv"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// Scanner holds the configuration for a single-host TCP connect scan and is
+// the library entry point for embedding goportscan in another Go program:
+// build a Scanner, call Scan(), and get the open ports back as a slice
+// instead of lines on stdout.
+type Scanner struct {
+	Target    string
+	StartPort int
+	EndPort   int
+
+	// ConnectTimeout bounds each port's initial TCP connect.
+	ConnectTimeout time.Duration
+
+	// ReadTimeout bounds how long Scan waits for banner/probe data once a
+	// port is found open -- some services accept instantly but are slow to
+	// speak first, and a slow banner shouldn't cost the connect phase any
+	// of its own budget. Zero defaults to ConnectTimeout, so existing
+	// callers that only ever set one timeout keep their old behavior.
+	ReadTimeout time.Duration
+
+	NumWorkers int
+
+	// DialContext opens the connection for every port this Scanner probes.
+	// It defaults to net.Dialer{}.DialContext -- a direct, unproxied TCP
+	// dial -- so existing callers that never set it keep today's behavior.
+	// Setting it lets a test inject a fake dialer that returns canned
+	// open/closed/timeout results per port instead of opening real sockets,
+	// and lets a caller embedding goportscan route dials through an exotic
+	// transport (a QUIC tunnel, a custom proxy) instead. It's independent of
+	// the -proxy dialer the CLI's connectProbe/identifyService share, since
+	// those serve main.go's process-wide scan rather than one Scanner value.
+	DialContext func(ctx context.Context, network, addr string) (net.Conn, error)
+
+	// OnResult, if set, is called with each Result the moment it's found,
+	// before the scan completes -- e.g. for a TUI that wants to render
+	// results live instead of waiting for Scan to return. It's called from
+	// a single goroutine (the same one that builds Scan's aggregate
+	// return), never concurrently from multiple workers, so callers don't
+	// need their own locking.
+	OnResult func(Result)
+
+	// Progress, if set, is polled once per progressInterval with the count
+	// of ports dialed so far (open or not) against the total in
+	// [StartPort, EndPort]. Like OnResult it's only ever called from one
+	// goroutine, but the count it reads is a *int64 every worker increments
+	// with atomic.AddInt64 after each dial -- the same done-counter pattern
+	// main's showProgress uses -- so the hot path never takes a mutex.
+	Progress func(done, total int)
+}
+
+// Result is one open port found by Scan, with whatever identifyService
+// managed to learn about it.
+type Result struct {
+	Port     int
+	Protocol string
+	State    string
+	Service  string
+	Banner   string
+	RTT      time.Duration
+}
+
+// Scan dials every port in [StartPort, EndPort] against Target using
+// NumWorkers concurrent workers and returns the open ports, sorted
+// ascending by port. It's ScanContext with a background context, drained
+// into a slice instead of handed to the caller as a channel.
+func (s *Scanner) Scan() ([]Result, error) {
+	results, errs := s.ScanContext(context.Background())
+
+	var open []Result
+	for r := range results {
+		if s.OnResult != nil {
+			s.OnResult(r)
+		}
+		open = append(open, r)
+	}
+	if err := <-errs; err != nil {
+		return nil, err
+	}
+	sort.Slice(open, func(i, j int) bool { return open[i].Port < open[j].Port })
+	return open, nil
+}
+
+// ScanContext is Scan's streaming form: it returns immediately with a
+// results channel that's fed as ports are found open and an error channel
+// that carries at most one fatal error (currently only a failure to
+// resolve Target), letting callers fan the scan straight into a downstream
+// pipeline instead of waiting for the whole range to finish.
+//
+// Both channels are closed when the scan is done, so `for r := range
+// results` is a valid and sufficient way to drain it. Cancelling ctx stops
+// dispatching new ports and lets in-flight dials finish without blocking
+// on a full results channel, then closes both channels -- it does not
+// interrupt a dial already in progress, since s.connectProbe's own context
+// is scoped to ConnectTimeout, not ctx.
+func (s *Scanner) ScanContext(ctx context.Context) (<-chan Result, <-chan error) {
+	results := make(chan Result)
+	errs := make(chan error, 1)
+
+	go func() {
+		defer close(results)
+		defer close(errs)
+
+		ip, err := resolveOneIP(s.Target)
+		if err != nil {
+			errs <- err
+			return
+		}
+
+		readTimeout := s.ReadTimeout
+		if readTimeout == 0 {
+			readTimeout = s.ConnectTimeout
+		}
+
+		ports := make(chan int, s.EndPort-s.StartPort+1)
+		total := s.EndPort - s.StartPort + 1
+
+		var done int64
+		var wg sync.WaitGroup
+		for i := 0; i < s.NumWorkers; i++ {
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				for port := range ports {
+					if ctx.Err() != nil {
+						continue
+					}
+					addr := Addr{IP: ip, Port: port}
+					dialStart := time.Now()
+					state, err := s.connectProbe(ctx, addr)
+					rtt := time.Since(dialStart)
+					if err == nil && state == portOpen {
+						info := identifyService(addr, s.ConnectTimeout, readTimeout)
+						result := Result{
+							Port:     port,
+							Protocol: "tcp",
+							State:    string(state),
+							Service:  info.Name,
+							Banner:   info.Banner,
+							RTT:      rtt,
+						}
+						select {
+						case results <- result:
+						case <-ctx.Done():
+						}
+					}
+					atomic.AddInt64(&done, 1)
+				}
+			}()
+		}
+
+		go func() {
+			defer close(ports)
+			for p := s.StartPort; p <= s.EndPort; p++ {
+				select {
+				case ports <- p:
+				case <-ctx.Done():
+					return
+				}
+			}
+		}()
+
+		progressDone := make(chan struct{})
+		if s.Progress != nil {
+			go func() {
+				ticker := time.NewTicker(progressInterval)
+				defer ticker.Stop()
+				for {
+					select {
+					case <-ticker.C:
+						s.Progress(int(atomic.LoadInt64(&done)), total)
+					case <-progressDone:
+						return
+					}
+				}
+			}()
+		}
+
+		wg.Wait()
+		close(progressDone)
+		if s.Progress != nil {
+			s.Progress(int(atomic.LoadInt64(&done)), total)
+		}
+	}()
+
+	return results, errs
+}
+
+// dial opens a connection via s.DialContext, falling back to a plain
+// net.Dialer when it's unset so a zero-value Scanner keeps dialing directly.
+func (s *Scanner) dial(ctx context.Context, network, addr string) (net.Conn, error) {
+	if s.DialContext != nil {
+		return s.DialContext(ctx, network, addr)
+	}
+	return (&net.Dialer{}).DialContext(ctx, network, addr)
+}
+
+// connectProbe is Scanner's own connect-scan probe, parallel to the
+// package-level connectProbe the CLI's main.go uses, except every dial goes
+// through s.dial (and so s.DialContext) instead of the CLI's -proxy-aware
+// global dialer -- see DialContext's doc comment for why a Scanner needs
+// its own dial path.
+func (s *Scanner) connectProbe(ctx context.Context, addr Addr) (portState, error) {
+	dialCtx := ctx
+	if s.ConnectTimeout > 0 {
+		var cancel context.CancelFunc
+		dialCtx, cancel = context.WithTimeout(ctx, s.ConnectTimeout)
+		defer cancel()
+	}
+	conn, err := s.dial(dialCtx, "tcp", addr.Dial())
+	if err == nil {
+		conn.Close()
+		return portOpen, nil
+	}
+	if isConnRefused(err) {
+		return portClosed, err
+	}
+	return portFiltered, err
+}
+
+// ScanPort does a single TCP connect check against target:port and reports
+// whether it's open, resolving target first if it isn't already an IP
+// literal. It funnels through the same dialTCP call connectProbe uses
+// internally, so this and the full Scan are never subtly out of sync with
+// each other about what counts as "open" -- useful for something like a
+// health check that wants one dial, not a pool of workers:
+//
+//	open, err := ScanPort(ctx, "10.0.0.5", 443, time.Second)
+func ScanPort(ctx context.Context, target string, port int, timeout time.Duration) (bool, error) {
+	ip, err := resolveOneIP(target)
+	if err != nil {
+		return false, err
+	}
+
+	address := net.JoinHostPort(ip.String(), strconv.Itoa(port))
+	conn, err := dialTCP(ctx, address, timeout)
+	if err != nil {
+		if isConnRefused(err) {
+			return false, nil
+		}
+		return false, err
+	}
+	conn.Close()
+	return true, nil
+}
+
+// resolveOneIP parses target as an IP literal, or resolves it as a hostname
+// and returns the first IPv4 address found.
+func resolveOneIP(target string) (net.IP, error) {
+	if ip := net.ParseIP(target); ip != nil {
+		return ip, nil
+	}
+	resolved, err := resolveIPs(target)
+	if err != nil {
+		return nil, err
+	}
+	for _, ip := range resolved {
+		if v4 := ip.To4(); v4 != nil {
+			return v4, nil
+		}
+	}
+	return nil, &net.AddrError{Err: "no IPv4 address found", Addr: target}
+}
@@ -0,0 +1,501 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"syscall"
+	"testing"
+	"time"
+)
+
+func TestScanPort_OpenPort(t *testing.T) {
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("listen: %v", err)
+	}
+	defer ln.Close()
+	port := ln.Addr().(*net.TCPAddr).Port
+
+	open, err := ScanPort(context.Background(), "127.0.0.1", port, 200*time.Millisecond)
+	if err != nil {
+		t.Fatalf("ScanPort: unexpected error: %v", err)
+	}
+	if !open {
+		t.Error("ScanPort on a listening port = false, want true")
+	}
+}
+
+func TestScanPort_ClosedPort(t *testing.T) {
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("listen: %v", err)
+	}
+	port := ln.Addr().(*net.TCPAddr).Port
+	ln.Close() // nothing listening now: the next dial should be refused
+
+	open, err := ScanPort(context.Background(), "127.0.0.1", port, 200*time.Millisecond)
+	if err != nil {
+		t.Fatalf("ScanPort: unexpected error: %v", err)
+	}
+	if open {
+		t.Error("ScanPort on a closed port = true, want false")
+	}
+}
+
+func TestScanner_Scan_ReturnsStructuredResults(t *testing.T) {
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("listen: %v", err)
+	}
+	defer ln.Close()
+	go func() {
+		for {
+			conn, err := ln.Accept()
+			if err != nil {
+				return
+			}
+			conn.Close()
+		}
+	}()
+
+	port := ln.Addr().(*net.TCPAddr).Port
+	s := &Scanner{
+		Target:         "127.0.0.1",
+		StartPort:      port,
+		EndPort:        port,
+		ConnectTimeout: 200 * time.Millisecond,
+		NumWorkers:     4,
+	}
+
+	results, err := s.Scan()
+	if err != nil {
+		t.Fatalf("Scan: %v", err)
+	}
+	if len(results) != 1 {
+		t.Fatalf("got %d results, want 1: %+v", len(results), results)
+	}
+	r := results[0]
+	if r.Port != port {
+		t.Errorf("Port = %d, want %d", r.Port, port)
+	}
+	if r.Protocol != "tcp" {
+		t.Errorf("Protocol = %q, want %q", r.Protocol, "tcp")
+	}
+	if r.State != "open" {
+		t.Errorf("State = %q, want %q", r.State, "open")
+	}
+	if r.RTT <= 0 {
+		t.Errorf("RTT = %s, want a positive duration", r.RTT)
+	}
+}
+
+// TestScanner_Scan_SlowBannerDoesNotFailAFastConnect checks that a service
+// which accepts the connection instantly but is slow to speak first is
+// still reported open, as long as ReadTimeout (not ConnectTimeout) gives it
+// enough time to answer.
+func TestScanner_Scan_SlowBannerDoesNotFailAFastConnect(t *testing.T) {
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("listen: %v", err)
+	}
+	defer ln.Close()
+	go func() {
+		for {
+			conn, err := ln.Accept()
+			if err != nil {
+				return
+			}
+			// Scan dials each port twice: once in connectProbe to check
+			// it's open (closed again immediately, before this banner is
+			// ever written), and again in identifyService for the banner
+			// itself, which is the connection this sleep+write is for.
+			go func(conn net.Conn) {
+				defer conn.Close()
+				time.Sleep(150 * time.Millisecond)
+				conn.Write([]byte("220 slow.example.com ESMTP ready\r\n"))
+			}(conn)
+		}
+	}()
+
+	port := ln.Addr().(*net.TCPAddr).Port
+	s := &Scanner{
+		Target:         "127.0.0.1",
+		StartPort:      port,
+		EndPort:        port,
+		ConnectTimeout: 50 * time.Millisecond,
+		ReadTimeout:    500 * time.Millisecond,
+		NumWorkers:     1,
+	}
+
+	results, err := s.Scan()
+	if err != nil {
+		t.Fatalf("Scan: %v", err)
+	}
+	if len(results) != 1 {
+		t.Fatalf("got %d results, want 1: %+v", len(results), results)
+	}
+	if results[0].Banner == "" {
+		t.Error("Banner is empty, want the slow banner to have been read within ReadTimeout")
+	}
+}
+
+func TestScanner_Scan_InvokesOnResultLive(t *testing.T) {
+	const start, end = 41000, 41050
+	openAt := []int{41010, 41030, 41045}
+
+	for _, port := range openAt {
+		ln, err := net.Listen("tcp", fmt.Sprintf("127.0.0.1:%d", port))
+		if err != nil {
+			t.Skipf("port %d unavailable: %v", port, err)
+		}
+		defer ln.Close()
+		go func(ln net.Listener) {
+			for {
+				conn, err := ln.Accept()
+				if err != nil {
+					return
+				}
+				conn.Close()
+			}
+		}(ln)
+	}
+
+	var (
+		mu         sync.Mutex
+		seen       []int
+		concurrent bool
+		inCallback bool
+	)
+	s := &Scanner{
+		Target:         "127.0.0.1",
+		StartPort:      start,
+		EndPort:        end,
+		ConnectTimeout: 200 * time.Millisecond,
+		NumWorkers:     20,
+		OnResult: func(r Result) {
+			mu.Lock()
+			if inCallback {
+				concurrent = true
+			}
+			inCallback = true
+			seen = append(seen, r.Port)
+			inCallback = false
+			mu.Unlock()
+		},
+	}
+
+	results, err := s.Scan()
+	if err != nil {
+		t.Fatalf("Scan: %v", err)
+	}
+	if concurrent {
+		t.Error("OnResult was re-entered concurrently; want calls serialized from a single goroutine")
+	}
+	if len(seen) != len(results) {
+		t.Errorf("OnResult fired %d times, want once per result (%d)", len(seen), len(results))
+	}
+	sort.Ints(seen)
+	for i, port := range openAt {
+		if i >= len(seen) || seen[i] != port {
+			t.Errorf("OnResult saw ports %v, want %v", seen, openAt)
+			break
+		}
+	}
+}
+
+// TestScanner_Scan_ConcurrentProgressAndResultsAreRaceFree drives a wide
+// port range with many workers and a Progress callback alongside OnResult,
+// so `go test -race` can catch any data race in the done counter, the
+// results aggregation, or the two callbacks racing each other -- none of
+// which share a mutex, since they're meant to stay off the hot path.
+func TestScanner_Scan_ConcurrentProgressAndResultsAreRaceFree(t *testing.T) {
+	const start, end = 42000, 42100
+	openAt := []int{42010, 42040, 42070, 42099}
+
+	for _, port := range openAt {
+		ln, err := net.Listen("tcp", fmt.Sprintf("127.0.0.1:%d", port))
+		if err != nil {
+			t.Skipf("port %d unavailable: %v", port, err)
+		}
+		defer ln.Close()
+		go func(ln net.Listener) {
+			for {
+				conn, err := ln.Accept()
+				if err != nil {
+					return
+				}
+				conn.Close()
+			}
+		}(ln)
+	}
+
+	var (
+		mu           sync.Mutex
+		lastDone     int
+		progressCall int
+	)
+	s := &Scanner{
+		Target:         "127.0.0.1",
+		StartPort:      start,
+		EndPort:        end,
+		ConnectTimeout: 200 * time.Millisecond,
+		NumWorkers:     32,
+		OnResult:       func(Result) {},
+		Progress: func(done, total int) {
+			mu.Lock()
+			defer mu.Unlock()
+			progressCall++
+			if done < lastDone || done > total {
+				t.Errorf("Progress(%d, %d) out of order after %d", done, total, lastDone)
+			}
+			lastDone = done
+		},
+	}
+
+	results, err := s.Scan()
+	if err != nil {
+		t.Fatalf("Scan: %v", err)
+	}
+	if len(results) != len(openAt) {
+		t.Errorf("got %d results, want %d", len(results), len(openAt))
+	}
+	if progressCall == 0 {
+		t.Error("Progress was never called")
+	}
+	if lastDone != end-start+1 {
+		t.Errorf("final Progress done = %d, want %d", lastDone, end-start+1)
+	}
+}
+
+func TestScanner_ScanContext_StreamsResultsAndClosesBothChannels(t *testing.T) {
+	const start, end = 43000, 43050
+	openAt := []int{43010, 43030, 43045}
+
+	for _, port := range openAt {
+		ln, err := net.Listen("tcp", fmt.Sprintf("127.0.0.1:%d", port))
+		if err != nil {
+			t.Skipf("port %d unavailable: %v", port, err)
+		}
+		defer ln.Close()
+		go func(ln net.Listener) {
+			for {
+				conn, err := ln.Accept()
+				if err != nil {
+					return
+				}
+				conn.Close()
+			}
+		}(ln)
+	}
+
+	s := &Scanner{
+		Target:         "127.0.0.1",
+		StartPort:      start,
+		EndPort:        end,
+		ConnectTimeout: 200 * time.Millisecond,
+		NumWorkers:     20,
+	}
+
+	results, errs := s.ScanContext(context.Background())
+	var seen []int
+	for r := range results {
+		seen = append(seen, r.Port)
+	}
+	if err := <-errs; err != nil {
+		t.Fatalf("ScanContext: %v", err)
+	}
+	sort.Ints(seen)
+	if len(seen) != len(openAt) {
+		t.Fatalf("got %d results, want %d: %v", len(seen), len(openAt), seen)
+	}
+	for i, port := range openAt {
+		if seen[i] != port {
+			t.Errorf("results = %v, want %v", seen, openAt)
+			break
+		}
+	}
+}
+
+func TestScanner_ScanContext_ResolveFailureSurfacesOnErrorChannel(t *testing.T) {
+	s := &Scanner{
+		Target:         "this.host.does.not.resolve.invalid",
+		StartPort:      1,
+		EndPort:        1,
+		ConnectTimeout: 50 * time.Millisecond,
+		NumWorkers:     1,
+	}
+
+	results, errs := s.ScanContext(context.Background())
+	for range results {
+		t.Error("got a result from an unresolvable target, want none")
+	}
+	if err := <-errs; err == nil {
+		t.Error("ScanContext errs = nil, want the resolve failure")
+	}
+}
+
+// TestScanner_ScanContext_CancelStopsDispatchAndClosesChannels checks that
+// cancelling the context causes ScanContext to stop handing out new ports
+// and close both channels promptly, rather than running the full range to
+// completion.
+func TestScanner_ScanContext_CancelStopsDispatchAndClosesChannels(t *testing.T) {
+	const start, end = 44000, 49000 // a wide range so an uncancelled scan would take a while
+
+	s := &Scanner{
+		Target:         "127.0.0.1",
+		StartPort:      start,
+		EndPort:        end,
+		ConnectTimeout: 200 * time.Millisecond,
+		NumWorkers:     8,
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	results, errs := s.ScanContext(ctx)
+	cancel()
+
+	for range results {
+		// drain whatever was already in flight when cancel() landed
+	}
+	if err := <-errs; err != nil {
+		t.Fatalf("ScanContext: %v", err)
+	}
+}
+
+// fakeConn is the minimal net.Conn a fake DialContext needs: just enough for
+// connectProbe's Close to work, with Read always reporting EOF so anything
+// downstream that tries to read a banner doesn't hang.
+type fakeConn struct {
+	net.Conn
+}
+
+func (fakeConn) Read([]byte) (int, error)         { return 0, io.EOF }
+func (fakeConn) Write(b []byte) (int, error)      { return len(b), nil }
+func (fakeConn) Close() error                     { return nil }
+func (fakeConn) SetDeadline(time.Time) error      { return nil }
+func (fakeConn) SetReadDeadline(time.Time) error  { return nil }
+func (fakeConn) SetWriteDeadline(time.Time) error { return nil }
+
+// TestScanner_ScanContext_UsesInjectedDialContext drives the scan entirely
+// through a fake DialContext that returns canned open/closed/timeout
+// outcomes per port instead of opening real sockets, the scenario DialContext
+// exists for: a deterministic, network-independent test.
+func TestScanner_ScanContext_UsesInjectedDialContext(t *testing.T) {
+	const target = "203.0.113.1" // TEST-NET-3 (RFC 5737): never actually dialed
+	openPorts := map[int]bool{10: true, 30: true}
+	timeoutPort := 20
+
+	var dialedNetwork, dialedAddr []string
+	var mu sync.Mutex
+	fakeDial := func(ctx context.Context, network, addr string) (net.Conn, error) {
+		mu.Lock()
+		dialedNetwork = append(dialedNetwork, network)
+		dialedAddr = append(dialedAddr, addr)
+		mu.Unlock()
+
+		host, portStr, err := net.SplitHostPort(addr)
+		if err != nil {
+			return nil, err
+		}
+		if host != target {
+			return nil, fmt.Errorf("fakeDial: unexpected host %q", host)
+		}
+		port, _ := strconv.Atoi(portStr)
+		switch {
+		case openPorts[port]:
+			return fakeConn{}, nil
+		case port == timeoutPort:
+			return nil, context.DeadlineExceeded
+		default:
+			return nil, &net.OpError{Op: "dial", Err: syscall.ECONNREFUSED}
+		}
+	}
+
+	s := &Scanner{
+		Target:         target,
+		StartPort:      1,
+		EndPort:        40,
+		ConnectTimeout: 50 * time.Millisecond,
+		NumWorkers:     8,
+		DialContext:    fakeDial,
+	}
+
+	results, err := s.Scan()
+	if err != nil {
+		t.Fatalf("Scan: %v", err)
+	}
+	if len(results) != len(openPorts) {
+		t.Fatalf("got %d results, want %d: %+v", len(results), len(openPorts), results)
+	}
+	for _, r := range results {
+		if !openPorts[r.Port] {
+			t.Errorf("unexpected open result for port %d", r.Port)
+		}
+	}
+
+	mu.Lock()
+	defer mu.Unlock()
+	if len(dialedNetwork) == 0 {
+		t.Fatal("DialContext was never called")
+	}
+	for _, network := range dialedNetwork {
+		if network != "tcp" {
+			t.Errorf("dialed network = %q, want %q", network, "tcp")
+		}
+	}
+	for _, addr := range dialedAddr {
+		if !strings.HasPrefix(addr, target+":") {
+			t.Errorf("dialed addr = %q, want it to target %s", addr, target)
+		}
+	}
+}
+
+// BenchmarkScanner_Scan exercises Scan's producer/consumer worker pool (a
+// single jobs channel fed to NumWorkers consumers) against a range with a
+// handful of open ports scattered among many closed ones, standing in for
+// the uneven mix of fast-accept and fast-refuse ports that a fixed
+// ports-per-worker split would have balanced poorly: a worker stuck with a
+// chunk that happens to contain all the slow ports would idle while another
+// worker finishes its easy chunk early. With one shared channel, whichever
+// worker is free just pulls the next port instead.
+func BenchmarkScanner_Scan(b *testing.B) {
+	const start, end = 40000, 40200
+	openAt := []int{40010, 40050, 40100, 40150, 40190}
+
+	for _, port := range openAt {
+		ln, err := net.Listen("tcp", fmt.Sprintf("127.0.0.1:%d", port))
+		if err != nil {
+			b.Skipf("port %d unavailable for benchmark: %v", port, err)
+		}
+		defer ln.Close()
+		go func(ln net.Listener) {
+			for {
+				conn, err := ln.Accept()
+				if err != nil {
+					return
+				}
+				conn.Close()
+			}
+		}(ln)
+	}
+
+	s := &Scanner{
+		Target:         "127.0.0.1",
+		StartPort:      start,
+		EndPort:        end,
+		ConnectTimeout: 200 * time.Millisecond,
+		NumWorkers:     50,
+	}
+
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		if _, err := s.Scan(); err != nil {
+			b.Fatalf("Scan: %v", err)
+		}
+	}
+}
@@ -0,0 +1,9 @@
+package main
+
+import "testing"
+
+func TestRunSelfTest_PassesOnLoopback(t *testing.T) {
+	if !runSelfTest() {
+		t.Error("runSelfTest() = false, want true on a normal loopback-capable environment")
+	}
+}
@@ -0,0 +1,66 @@
+package main
+
+import (
+	"bufio"
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+// etcServicesPath is the standard location of the IANA port registry on
+// Unix-like systems. It's a var, not a const, so tests can point it at a
+// fixture file.
+var etcServicesPath = "/etc/services"
+
+var (
+	servicesOnce   sync.Once
+	servicesByPort map[string]string // "port/proto" -> name
+)
+
+// lookupEtcServices returns the service name /etc/services registers for
+// port/proto (e.g. "80/tcp" -> "http"), used as a fallback when active
+// probing didn't identify anything running on an open port.
+func lookupEtcServices(port int, proto string) (string, bool) {
+	servicesOnce.Do(loadEtcServices)
+	name, ok := servicesByPort[fmt.Sprintf("%d/%s", port, proto)]
+	return name, ok
+}
+
+// loadEtcServices parses /etc/services into servicesByPort. A missing or
+// unreadable file just leaves the map empty, so the fallback silently does
+// nothing rather than failing the scan.
+func loadEtcServices() {
+	servicesByPort = make(map[string]string)
+
+	f, err := os.Open(etcServicesPath)
+	if err != nil {
+		return
+	}
+	defer f.Close()
+
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		line := scanner.Text()
+		if idx := strings.IndexByte(line, '#'); idx >= 0 {
+			line = line[:idx]
+		}
+		fields := strings.Fields(line)
+		if len(fields) < 2 {
+			continue
+		}
+		name, portProto := fields[0], fields[1]
+		portStr, proto, ok := strings.Cut(portProto, "/")
+		if !ok {
+			continue
+		}
+		if _, err := strconv.Atoi(portStr); err != nil {
+			continue
+		}
+		key := portStr + "/" + proto
+		if _, exists := servicesByPort[key]; !exists {
+			servicesByPort[key] = name
+		}
+	}
+}
@@ -0,0 +1,44 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"sync"
+	"testing"
+)
+
+func withFixtureServicesFile(t *testing.T, contents string) {
+	t.Helper()
+	dir := t.TempDir()
+	path := filepath.Join(dir, "services")
+	if err := os.WriteFile(path, []byte(contents), 0o644); err != nil {
+		t.Fatalf("write fixture: %v", err)
+	}
+
+	origPath, origMap := etcServicesPath, servicesByPort
+	etcServicesPath = path
+	servicesOnce = sync.Once{}
+	t.Cleanup(func() {
+		etcServicesPath = origPath
+		servicesByPort = origMap
+		servicesOnce = sync.Once{}
+	})
+}
+
+func TestLookupEtcServices(t *testing.T) {
+	withFixtureServicesFile(t, `# comment line
+http		80/tcp
+https		443/tcp
+domain		53/udp
+`)
+
+	if name, ok := lookupEtcServices(80, "tcp"); !ok || name != "http" {
+		t.Errorf("lookupEtcServices(80, tcp) = (%q, %v), want (http, true)", name, ok)
+	}
+	if name, ok := lookupEtcServices(53, "udp"); !ok || name != "domain" {
+		t.Errorf("lookupEtcServices(53, udp) = (%q, %v), want (domain, true)", name, ok)
+	}
+	if _, ok := lookupEtcServices(9999, "tcp"); ok {
+		t.Error("lookupEtcServices(9999, tcp) = ok, want not found")
+	}
+}
@@ -0,0 +1,263 @@
+package main
+
+import (
+	"bufio"
+	"context"
+	"io"
+	"net"
+	"regexp"
+	"strings"
+	"time"
+)
+
+// ServiceInfo describes what a ServiceProbe managed to learn about the
+// service listening on a port.
+type ServiceInfo struct {
+	Name    string // e.g. "ssh", "http"
+	Version string // e.g. "OpenSSH_8.9p1", empty if unknown
+	Banner  string // raw first bytes read, trimmed
+}
+
+// serviceProbe sends a protocol-specific probe and parses the response into
+// a ServiceInfo. It returns false if nothing useful could be read.
+type serviceProbe func(conn net.Conn) (ServiceInfo, bool)
+
+// probeReadTimeout is the default read timeout identifyService's CLI callers
+// use; library callers that want banner reads to have their own budget,
+// separate from the connect phase, set Scanner.ReadTimeout instead.
+const probeReadTimeout = 1 * time.Second
+
+// portProbes maps well-known ports to the probe most likely to identify the
+// service listening there. probeRegistry falls back to trying every probe
+// in order (cheapest/most common first) when the port isn't listed here.
+var portProbes = map[int]serviceProbe{
+	21:   probeBanner,
+	22:   probeSSH,
+	25:   probeBanner,
+	80:   probeHTTP,
+	110:  probeBanner,
+	443:  probeTLS,
+	445:  probeSMB,
+	587:  probeBanner,
+	8080: probeHTTP,
+}
+
+// probeRegistry is the ordered fallback list used when a port has no
+// dedicated entry in portProbes.
+var probeRegistry = []serviceProbe{
+	probeBanner,
+	probeHTTP,
+	probeSSH,
+	probeTLS,
+	probeSMB,
+}
+
+// identifyService dials addr and runs the best-matching probe(s) against it,
+// returning whatever ServiceInfo could be determined. connectTimeout bounds
+// the dial; readTimeout bounds how long it then waits for the service to say
+// something, so a slow-to-banner service doesn't eat into the connect
+// budget and vice versa.
+func identifyService(addr Addr, connectTimeout, readTimeout time.Duration) ServiceInfo {
+	address := addr.Dial()
+	conn, err := dialTCP(context.Background(), address, connectTimeout)
+	if err != nil {
+		return withRegistryName(ServiceInfo{}, addr.Port)
+	}
+
+	if probe, ok := portProbes[addr.Port]; ok {
+		conn.SetDeadline(time.Now().Add(readTimeout))
+		if info, ok := probe(conn); ok {
+			conn.Close()
+			return withRegistryName(info, addr.Port)
+		}
+	}
+	conn.Close()
+
+	return withRegistryName(probeFallback(address, connectTimeout, readTimeout), addr.Port)
+}
+
+// withRegistryName fills in info.Name from /etc/services when active probing
+// didn't determine one. It never overrides a name the probes already found,
+// since an observed banner or handshake is more trustworthy than a port
+// number's conventional assignment.
+func withRegistryName(info ServiceInfo, port int) ServiceInfo {
+	if info.Name == "" {
+		if name, ok := lookupEtcServices(port, "tcp"); ok {
+			info.Name = name
+		}
+	}
+	return info
+}
+
+// probeFallback runs every probe in probeRegistry against its own freshly
+// dialed connection, all at once, under a single shared deadline. Trying the
+// probes one after another (each waiting out its own deadline before the
+// next starts) could take len(probeRegistry)*readTimeout per port -- on a
+// large scan, where most open ports match nothing in portProbes, that
+// serial cost dominates the whole run. Running them concurrently bounds the
+// wall-clock cost of identifying an unrecognized port to a single
+// readTimeout, however many probes are registered.
+func probeFallback(address string, connectTimeout, readTimeout time.Duration) ServiceInfo {
+	type result struct {
+		idx  int
+		info ServiceInfo
+		ok   bool
+	}
+
+	results := make(chan result, len(probeRegistry))
+	for i, probe := range probeRegistry {
+		go func(i int, probe serviceProbe) {
+			conn, err := dialTCP(context.Background(), address, connectTimeout)
+			if err != nil {
+				results <- result{idx: i}
+				return
+			}
+			defer conn.Close()
+			conn.SetDeadline(time.Now().Add(readTimeout))
+			info, ok := probe(conn)
+			results <- result{idx: i, info: info, ok: ok}
+		}(i, probe)
+	}
+
+	// Preserve probeRegistry's priority order (the first probe to match
+	// wins) while still bounding total wait to one shared deadline. The
+	// worst case is a connect that takes the full connectTimeout followed
+	// by a read that takes the full readTimeout.
+	deadline := time.After(connectTimeout + readTimeout)
+	best := -1
+	var bestInfo ServiceInfo
+	for range probeRegistry {
+		select {
+		case r := <-results:
+			if r.ok && (best == -1 || r.idx < best) {
+				best = r.idx
+				bestInfo = r.info
+			}
+		case <-deadline:
+			return bestInfo
+		}
+	}
+	return bestInfo
+}
+
+// probeBanner passively reads whatever the service sends immediately after
+// connect, which is enough to identify FTP, SMTP, POP3 and SSH banners. It
+// assumes the caller has already set conn's read deadline.
+func probeBanner(conn net.Conn) (ServiceInfo, bool) {
+	reader := bufio.NewReader(conn)
+	line, err := reader.ReadString('\n')
+	line = strings.TrimSpace(line)
+	if err != nil || line == "" {
+		return ServiceInfo{}, false
+	}
+	return parseBanner(line), true
+}
+
+// probeSSH actively reads the SSH version exchange line, e.g.
+// "SSH-2.0-OpenSSH_8.9p1 Ubuntu-3ubuntu0.4". It assumes the caller has
+// already set conn's read deadline.
+func probeSSH(conn net.Conn) (ServiceInfo, bool) {
+	reader := bufio.NewReader(conn)
+	line, err := reader.ReadString('\n')
+	line = strings.TrimSpace(line)
+	if err != nil || !strings.HasPrefix(line, "SSH-") {
+		return ServiceInfo{}, false
+	}
+	return parseBanner(line), true
+}
+
+// probeHTTP sends a minimal HTTP/1.0 GET and reads back the status line and
+// Server header, if any. It assumes the caller has already set conn's
+// deadline.
+func probeHTTP(conn net.Conn) (ServiceInfo, bool) {
+	if _, err := conn.Write([]byte("GET / HTTP/1.0\r\nHost: scan\r\n\r\n")); err != nil {
+		return ServiceInfo{}, false
+	}
+
+	reader := bufio.NewReader(conn)
+	status, err := reader.ReadString('\n')
+	if err != nil || !strings.HasPrefix(status, "HTTP/") {
+		return ServiceInfo{}, false
+	}
+
+	info := ServiceInfo{Name: "http", Banner: strings.TrimSpace(status)}
+	for {
+		line, err := reader.ReadString('\n')
+		line = strings.TrimSpace(line)
+		if err != nil || line == "" {
+			break
+		}
+		if strings.HasPrefix(strings.ToLower(line), "server:") {
+			info.Version = strings.TrimSpace(line[len("server:"):])
+			break
+		}
+	}
+	return info, true
+}
+
+// probeTLS sends a minimal TLS ClientHello and checks for a ServerHello in
+// response, which is enough to flag the port as running TLS without doing a
+// full handshake. It assumes the caller has already set conn's deadline.
+func probeTLS(conn net.Conn) (ServiceInfo, bool) {
+	clientHello := []byte{
+		0x16, 0x03, 0x01, 0x00, 0x05, // TLS record header (handshake, TLS1.0, len=5)
+		0x01, 0x00, 0x00, 0x01, 0x00, // truncated ClientHello, enough to elicit an alert/ServerHello
+	}
+	if _, err := conn.Write(clientHello); err != nil {
+		return ServiceInfo{}, false
+	}
+
+	reply := make([]byte, 5)
+	if _, err := io.ReadFull(conn, reply); err != nil {
+		return ServiceInfo{}, false
+	}
+	if reply[0] != 0x16 && reply[0] != 0x15 { // handshake or alert record
+		return ServiceInfo{}, false
+	}
+	return ServiceInfo{Name: "ssl/tls"}, true
+}
+
+// probeSMB sends an SMB negotiate-protocol request and checks for a valid
+// SMB response header (\xffSMB). It assumes the caller has already set
+// conn's deadline.
+func probeSMB(conn net.Conn) (ServiceInfo, bool) {
+	negotiate := []byte{
+		0x00, 0x00, 0x00, 0x2f, // NetBIOS session header, length
+		0xff, 'S', 'M', 'B', 0x72, // SMB header, command=negotiate
+	}
+	if _, err := conn.Write(negotiate); err != nil {
+		return ServiceInfo{}, false
+	}
+
+	reply := make([]byte, 8)
+	if _, err := io.ReadFull(conn, reply); err != nil {
+		return ServiceInfo{}, false
+	}
+	if string(reply[4:8]) != "\xffSMB" {
+		return ServiceInfo{}, false
+	}
+	return ServiceInfo{Name: "microsoft-ds"}, true
+}
+
+var (
+	sshBannerRE  = regexp.MustCompile(`^SSH-[\d.]+-(\S+)`)
+	ftpBannerRE  = regexp.MustCompile(`^220[- ](.*)`)
+	smtpBannerRE = regexp.MustCompile(`^220[- ](.*ESMTP.*|.*SMTP.*)`)
+	pop3BannerRE = regexp.MustCompile(`^\+OK (.*)`)
+)
+
+// parseBanner turns a raw banner line into a best-effort ServiceInfo guess.
+func parseBanner(line string) ServiceInfo {
+	switch {
+	case sshBannerRE.MatchString(line):
+		return ServiceInfo{Name: "ssh", Version: sshBannerRE.FindStringSubmatch(line)[1], Banner: line}
+	case smtpBannerRE.MatchString(line):
+		return ServiceInfo{Name: "smtp", Version: strings.TrimSpace(smtpBannerRE.FindStringSubmatch(line)[1]), Banner: line}
+	case ftpBannerRE.MatchString(line):
+		return ServiceInfo{Name: "ftp", Version: strings.TrimSpace(ftpBannerRE.FindStringSubmatch(line)[1]), Banner: line}
+	case pop3BannerRE.MatchString(line):
+		return ServiceInfo{Name: "pop3", Version: strings.TrimSpace(pop3BannerRE.FindStringSubmatch(line)[1]), Banner: line}
+	default:
+		return ServiceInfo{Banner: line}
+	}
+}
@@ -0,0 +1,106 @@
+package main
+
+import (
+	"net"
+	"testing"
+	"time"
+)
+
+func TestParseBanner(t *testing.T) {
+	cases := []struct {
+		line        string
+		wantName    string
+		wantVersion string
+	}{
+		{"SSH-2.0-OpenSSH_8.9p1 Ubuntu-3ubuntu0.4", "ssh", "OpenSSH_8.9p1"},
+		{"220 mail.example.com ESMTP Postfix", "smtp", "mail.example.com ESMTP Postfix"},
+		{"220 ftp.example.com FTP server ready", "ftp", "ftp.example.com FTP server ready"},
+		{"+OK Dovecot ready.", "pop3", "Dovecot ready."},
+		{"not a recognized banner", "", ""},
+	}
+	for _, c := range cases {
+		info := parseBanner(c.line)
+		if info.Name != c.wantName {
+			t.Errorf("parseBanner(%q).Name = %q, want %q", c.line, info.Name, c.wantName)
+		}
+		if info.Version != c.wantVersion {
+			t.Errorf("parseBanner(%q).Version = %q, want %q", c.line, info.Version, c.wantVersion)
+		}
+		if info.Banner != c.line {
+			t.Errorf("parseBanner(%q).Banner = %q, want original line", c.line, info.Banner)
+		}
+	}
+}
+
+// TestIdentifyServiceFallbackIsBoundedBySingleDeadline guards against the
+// fallback registry regressing to its old behavior of trying each probe in
+// series, which could take len(probeRegistry)*readTimeout for a single
+// unidentified port.
+func TestIdentifyServiceFallbackIsBoundedBySingleDeadline(t *testing.T) {
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("listen: %v", err)
+	}
+	defer ln.Close()
+	go func() {
+		for {
+			conn, err := ln.Accept()
+			if err != nil {
+				return
+			}
+			// Accept but never write anything back, to exercise the
+			// fallback path (no probe will get a match).
+			go func() {
+				buf := make([]byte, 1024)
+				conn.Read(buf)
+			}()
+		}
+	}()
+
+	port := ln.Addr().(*net.TCPAddr).Port
+	addr := Addr{IP: net.ParseIP("127.0.0.1"), Port: port}
+
+	const readTimeout = 300 * time.Millisecond
+	start := time.Now()
+	identifyService(addr, 2*time.Second, readTimeout)
+	if elapsed := time.Since(start); elapsed > 2*readTimeout {
+		t.Fatalf("identifyService took %s, want well under %s (serial fallback regression)", elapsed, 2*readTimeout)
+	}
+}
+
+// TestIdentifyService_GrabsBannerFromOpenPort confirms a service that speaks
+// first (e.g. an SMTP or FTP daemon) gets its banner read and parsed by
+// identifyService without any port-specific registration.
+func TestIdentifyService_GrabsBannerFromOpenPort(t *testing.T) {
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("listen: %v", err)
+	}
+	defer ln.Close()
+	go func() {
+		// probeFallback dials every registered probe's own connection in
+		// parallel (see probeFallback's doc comment), so the fake server
+		// needs to accept and answer all of them, not just the first.
+		for {
+			conn, err := ln.Accept()
+			if err != nil {
+				return
+			}
+			go func(conn net.Conn) {
+				defer conn.Close()
+				conn.Write([]byte("220 test.example.com ESMTP ready\r\n"))
+			}(conn)
+		}
+	}()
+
+	port := ln.Addr().(*net.TCPAddr).Port
+	addr := Addr{IP: net.ParseIP("127.0.0.1"), Port: port}
+
+	info := identifyService(addr, 2*time.Second, 2*time.Second)
+	if info.Name != "smtp" {
+		t.Errorf("identifyService.Name = %q, want %q", info.Name, "smtp")
+	}
+	if info.Banner == "" {
+		t.Error("identifyService.Banner is empty, want the raw banner line")
+	}
+}
@@ -0,0 +1,55 @@
+package main
+
+import "sync"
+
+// hostShortCircuitThreshold is how many ENETUNREACH/EHOSTUNREACH dials in a
+// row a host needs before its remaining ports are skipped. A handful rules
+// out one flaky port while still catching a genuinely dead host or subnet
+// fast, instead of dutifully timing out on every one of its ports.
+const hostShortCircuitThreshold = 5
+
+// hostShortCircuit tracks, per host, a running streak of consecutive
+// ENETUNREACH/EHOSTUNREACH dials, and which hosts have already been given
+// up on as a result. It's shared between every worker dialing that host
+// (Record) and the feeder goroutine deciding whether to keep queueing its
+// remaining ports (Aborted) -- see -no-short-circuit in main.
+type hostShortCircuit struct {
+	mu      sync.Mutex
+	streak  map[string]int
+	aborted map[string]bool
+}
+
+func newHostShortCircuit() *hostShortCircuit {
+	return &hostShortCircuit{streak: map[string]int{}, aborted: map[string]bool{}}
+}
+
+// Record tallies one dial's outcome for host: unreachable extends its
+// streak, anything else resets it. It reports true exactly once per host,
+// on the dial that first crosses hostShortCircuitThreshold, so the caller
+// emits that host's unreachable result exactly once instead of on every
+// dial afterward.
+func (h *hostShortCircuit) Record(host string, unreachable bool) (justAborted bool) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	if h.aborted[host] {
+		return false
+	}
+	if !unreachable {
+		h.streak[host] = 0
+		return false
+	}
+	h.streak[host]++
+	if h.streak[host] < hostShortCircuitThreshold {
+		return false
+	}
+	h.aborted[host] = true
+	return true
+}
+
+// Aborted reports whether host has already been given up on, so the feeder
+// goroutine queueing its remaining ports can stop early.
+func (h *hostShortCircuit) Aborted(host string) bool {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	return h.aborted[host]
+}
@@ -0,0 +1,83 @@
+package main
+
+import (
+	"net"
+	"syscall"
+	"testing"
+)
+
+func TestIsHostUnreachable(t *testing.T) {
+	cases := []struct {
+		name string
+		err  error
+		want bool
+	}{
+		{"bare ENETUNREACH", syscall.ENETUNREACH, true},
+		{"bare EHOSTUNREACH", syscall.EHOSTUNREACH, true},
+		{"wrapped in net.OpError", &net.OpError{Op: "dial", Err: syscall.EHOSTUNREACH}, true},
+		{"connection refused", syscall.ECONNREFUSED, false},
+		{"nil", nil, false},
+	}
+	for _, c := range cases {
+		if got := isHostUnreachable(c.err); got != c.want {
+			t.Errorf("isHostUnreachable(%s) = %v, want %v", c.name, got, c.want)
+		}
+	}
+}
+
+// TestHostShortCircuit_AbortsAfterConsecutiveUnreachableDials simulates a
+// host that returns host-unreachable for every single dial, the scenario
+// -no-short-circuit exists to cut short: it should abort exactly once, on
+// the dial that crosses hostShortCircuitThreshold, and report the host as
+// aborted from then on.
+func TestHostShortCircuit_AbortsAfterConsecutiveUnreachableDials(t *testing.T) {
+	hsc := newHostShortCircuit()
+	const host = "10.0.0.5"
+
+	abortedCount := 0
+	for i := 0; i < hostShortCircuitThreshold+5; i++ {
+		if hsc.Record(host, true) {
+			abortedCount++
+		}
+	}
+	if abortedCount != 1 {
+		t.Errorf("Record reported justAborted %d times, want exactly 1", abortedCount)
+	}
+	if !hsc.Aborted(host) {
+		t.Error("Aborted(host) = false after crossing the threshold, want true")
+	}
+}
+
+func TestHostShortCircuit_ReachableDialResetsStreak(t *testing.T) {
+	hsc := newHostShortCircuit()
+	const host = "10.0.0.5"
+
+	for i := 0; i < hostShortCircuitThreshold-1; i++ {
+		if hsc.Record(host, true) {
+			t.Fatalf("Record aborted early at dial %d, want it to need %d in a row", i, hostShortCircuitThreshold)
+		}
+	}
+	hsc.Record(host, false) // one reachable dial breaks the streak
+
+	for i := 0; i < hostShortCircuitThreshold-1; i++ {
+		if hsc.Record(host, true) {
+			t.Fatalf("Record aborted at dial %d after the streak reset, want it to need a fresh run of %d", i, hostShortCircuitThreshold)
+		}
+	}
+	if hsc.Aborted(host) {
+		t.Error("Aborted(host) = true, want false: the streak never reached the threshold uninterrupted")
+	}
+}
+
+func TestHostShortCircuit_TracksHostsIndependently(t *testing.T) {
+	hsc := newHostShortCircuit()
+	for i := 0; i < hostShortCircuitThreshold; i++ {
+		hsc.Record("10.0.0.1", true)
+	}
+	if !hsc.Aborted("10.0.0.1") {
+		t.Error("Aborted(10.0.0.1) = false, want true")
+	}
+	if hsc.Aborted("10.0.0.2") {
+		t.Error("Aborted(10.0.0.2) = true, want false: its own streak never ran")
+	}
+}
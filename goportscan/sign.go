@@ -0,0 +1,173 @@
+package main
+
+import (
+	"crypto/ed25519"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+)
+
+// signer computes -sign's tamper-evidence fields for a ScanResult: always
+// a SHA-256 digest over the result's canonical JSON, and additionally an
+// Ed25519 signature over that digest when -sign-key loaded a key. A nil
+// *signer's caller simply doesn't call Sign -- there's no no-key zero
+// value to special-case here the way, say, a nil sink would need one.
+type signer struct {
+	key ed25519.PrivateKey // nil unless -sign-key was given: digest only
+}
+
+// loadSignKey reads an Ed25519 private key from path: either the raw
+// 32-byte seed (ed25519.SeedSize) or the full 64-byte private key
+// (ed25519.PrivateKeySize), whichever the file happens to contain. There's
+// no PEM/PKCS8 support -- -sign-key is meant to point at a key you
+// generated for this purpose alone, not an existing TLS/SSH identity.
+func loadSignKey(path string) (ed25519.PrivateKey, error) {
+	raw, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("-sign-key: %w", err)
+	}
+	switch len(raw) {
+	case ed25519.SeedSize:
+		return ed25519.NewKeyFromSeed(raw), nil
+	case ed25519.PrivateKeySize:
+		return ed25519.PrivateKey(raw), nil
+	default:
+		return nil, fmt.Errorf("-sign-key: %q is %d bytes, want %d (seed) or %d (private key)", path, len(raw), ed25519.SeedSize, ed25519.PrivateKeySize)
+	}
+}
+
+// newSigner builds a signer from -sign-key's path, or a digest-only signer
+// when path is empty.
+func newSigner(path string) (*signer, error) {
+	if path == "" {
+		return &signer{}, nil
+	}
+	key, err := loadSignKey(path)
+	if err != nil {
+		return nil, err
+	}
+	return &signer{key: key}, nil
+}
+
+// Sign fills in r's Digest (always) and Signature (only when the signer
+// holds a key), computed over r's canonical JSON with both fields cleared
+// first so the digest never depends on its own previous value. Canonical
+// here just means encoding/json's own output: struct fields marshal in a
+// fixed declaration order and map fields (DetectResult.Extra) marshal with
+// their keys sorted, so two processes hashing the same ScanResult value
+// always get the same bytes without any extra normalization step.
+func (s *signer) Sign(r *ScanResult) error {
+	r.Digest = ""
+	r.Signature = ""
+	canonical, err := json.Marshal(r)
+	if err != nil {
+		return fmt.Errorf("sign %s:%d: %w", r.Host, r.Port, err)
+	}
+	sum := sha256.Sum256(canonical)
+	r.Digest = hex.EncodeToString(sum[:])
+	if s.key != nil {
+		r.Signature = hex.EncodeToString(ed25519.Sign(s.key, sum[:]))
+	}
+	return nil
+}
+
+// verifyResult recomputes r's digest and, if it carries a signature,
+// checks it against pub (nil if -verify-report was run without
+// -sign-key). digestOK and signatureOK are reported separately rather than
+// folded into one verdict, since a verify report wants to distinguish "the
+// content was altered" from "the content is intact but unsigned/the
+// signature doesn't check out".
+func verifyResult(r ScanResult, pub ed25519.PublicKey) (digestOK, signatureOK, hasSignature bool) {
+	wantDigest, wantSig := r.Digest, r.Signature
+	r.Digest, r.Signature = "", ""
+	canonical, err := json.Marshal(r)
+	if err != nil {
+		return false, false, wantSig != ""
+	}
+	sum := sha256.Sum256(canonical)
+	digestOK = hex.EncodeToString(sum[:]) == wantDigest
+	if wantSig == "" {
+		return digestOK, false, false
+	}
+	sigBytes, err := hex.DecodeString(wantSig)
+	if err != nil || pub == nil {
+		return digestOK, false, true
+	}
+	return digestOK, ed25519.Verify(pub, sum[:], sigBytes), true
+}
+
+// runVerifyReport is -verify-report's entry point: it recomputes every
+// signed result's digest (and signature, if signKeyPath derives a public
+// key) in path -- a file written by a previous -sign'd scan -- and prints
+// one OK/mismatch line per result plus a final summary. The return value
+// is main's exit code: 0 if every signed result checked out, 1 if any
+// digest or signature didn't, 2 on a file or key problem.
+func runVerifyReport(path, signKeyPath string) int {
+	var pub ed25519.PublicKey
+	if signKeyPath != "" {
+		key, err := loadSignKey(signKeyPath)
+		if err != nil {
+			fmt.Fprintln(os.Stderr, "error:", err)
+			return 2
+		}
+		pub = key.Public().(ed25519.PublicKey)
+	}
+
+	f, err := os.Open(path)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, "error:", err)
+		return 2
+	}
+	defer f.Close()
+
+	// Decoding into a plain ScanResult, one JSON value at a time, is the
+	// same trick loadBaseline uses to read a -sign'd output file without
+	// caring about the {"meta":...}/{"stats":...}/{"errors":...} lines
+	// also in it: those decode into an all-zero-value ScanResult, which
+	// Digest == "" below filters out just as cleanly as a type switch
+	// would.
+	dec := json.NewDecoder(f)
+	var checked, failed int
+	for {
+		var r ScanResult
+		if err := dec.Decode(&r); err != nil {
+			if err == io.EOF {
+				break
+			}
+			fmt.Fprintf(os.Stderr, "error: parsing %s: %v\n", path, err)
+			return 2
+		}
+		if r.Digest == "" {
+			continue
+		}
+		checked++
+
+		digestOK, signatureOK, hasSignature := verifyResult(r, pub)
+		status := "OK"
+		switch {
+		case !digestOK:
+			status = "DIGEST MISMATCH"
+		case hasSignature && pub == nil:
+			status = "signed, but no -sign-key given to check it against"
+		case hasSignature && !signatureOK:
+			status = "SIGNATURE MISMATCH"
+		}
+		if !digestOK || (hasSignature && pub != nil && !signatureOK) {
+			failed++
+		}
+		fmt.Fprintf(os.Stderr, "%s:%d: %s\n", r.Host, r.Port, status)
+	}
+
+	if checked == 0 {
+		fmt.Fprintf(os.Stderr, "%s: no signed results found (was it written with -sign?)\n", path)
+		return 2
+	}
+	fmt.Fprintf(os.Stderr, "%d of %d result(s) verified\n", checked-failed, checked)
+	if failed > 0 {
+		return 1
+	}
+	return 0
+}
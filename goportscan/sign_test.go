@@ -0,0 +1,185 @@
+package main
+
+import (
+	"crypto/ed25519"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestSigner_DigestOnlyWithoutAKey(t *testing.T) {
+	sig, err := newSigner("")
+	if err != nil {
+		t.Fatalf("newSigner: %v", err)
+	}
+	r := ScanResult{Host: "10.0.0.1", Port: 22, State: "open"}
+	if err := sig.Sign(&r); err != nil {
+		t.Fatalf("Sign: %v", err)
+	}
+	if r.Digest == "" {
+		t.Error("Digest is empty, want a SHA-256 hex digest")
+	}
+	if r.Signature != "" {
+		t.Errorf("Signature = %q, want empty: no -sign-key was given", r.Signature)
+	}
+}
+
+func TestSigner_DigestIsStableAndTamperEvident(t *testing.T) {
+	sig, _ := newSigner("")
+	a := ScanResult{Host: "10.0.0.1", Port: 22, State: "open"}
+	b := a
+	if err := sig.Sign(&a); err != nil {
+		t.Fatalf("Sign: %v", err)
+	}
+	if err := sig.Sign(&b); err != nil {
+		t.Fatalf("Sign: %v", err)
+	}
+	if a.Digest != b.Digest {
+		t.Errorf("two Sign calls over the same result produced different digests: %q vs %q", a.Digest, b.Digest)
+	}
+
+	tampered := a
+	tampered.State = "closed"
+	digestOK, _, _ := verifyResult(tampered, nil)
+	if digestOK {
+		t.Error("verifyResult on a result with an altered field reported digestOK = true, want false")
+	}
+}
+
+func writeTestKey(t *testing.T, seed []byte) string {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), "sign.key")
+	if err := os.WriteFile(path, seed, 0o600); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+func TestSigner_SignsAndVerifiesWithAnEd25519Key(t *testing.T) {
+	pub, priv, err := ed25519.GenerateKey(nil)
+	if err != nil {
+		t.Fatalf("ed25519.GenerateKey: %v", err)
+	}
+	keyPath := writeTestKey(t, priv)
+
+	sig, err := newSigner(keyPath)
+	if err != nil {
+		t.Fatalf("newSigner: %v", err)
+	}
+	r := ScanResult{Host: "10.0.0.1", Port: 443, State: "open"}
+	if err := sig.Sign(&r); err != nil {
+		t.Fatalf("Sign: %v", err)
+	}
+	if r.Signature == "" {
+		t.Fatal("Signature is empty, want an Ed25519 signature over the digest")
+	}
+
+	digestOK, signatureOK, hasSignature := verifyResult(r, pub)
+	if !digestOK || !hasSignature || !signatureOK {
+		t.Errorf("verifyResult = (%v, %v, %v), want (true, true, true)", digestOK, signatureOK, hasSignature)
+	}
+
+	r.State = "closed" // tamper after signing
+	digestOK, signatureOK, hasSignature = verifyResult(r, pub)
+	if digestOK {
+		t.Error("verifyResult on a tampered result reported digestOK = true, want false")
+	}
+	if !hasSignature {
+		t.Error("verifyResult reported hasSignature = false, want true")
+	}
+	_ = signatureOK // the signature itself is still byte-valid for the original content; only the digest check catches tampering here
+}
+
+func TestLoadSignKey_AcceptsSeedOrFullPrivateKey(t *testing.T) {
+	_, priv, err := ed25519.GenerateKey(nil)
+	if err != nil {
+		t.Fatalf("ed25519.GenerateKey: %v", err)
+	}
+	seed := priv.Seed()
+
+	for name, raw := range map[string][]byte{"seed": seed, "full private key": priv} {
+		t.Run(name, func(t *testing.T) {
+			key, err := loadSignKey(writeTestKey(t, raw))
+			if err != nil {
+				t.Fatalf("loadSignKey: %v", err)
+			}
+			if !key.Equal(priv) {
+				t.Error("loadSignKey returned a different key than was written")
+			}
+		})
+	}
+}
+
+func TestLoadSignKey_RejectsWrongLength(t *testing.T) {
+	if _, err := loadSignKey(writeTestKey(t, []byte("too short"))); err == nil {
+		t.Error("loadSignKey on a malformed key file = nil error, want one")
+	}
+}
+
+func TestRunVerifyReport_DetectsTamperingAndMismatchedSignatures(t *testing.T) {
+	_, priv, err := ed25519.GenerateKey(nil)
+	if err != nil {
+		t.Fatalf("ed25519.GenerateKey: %v", err)
+	}
+	keyPath := writeTestKey(t, priv)
+	sig, err := newSigner(keyPath)
+	if err != nil {
+		t.Fatalf("newSigner: %v", err)
+	}
+
+	good := ScanResult{Host: "10.0.0.1", Port: 22, State: "open"}
+	if err := sig.Sign(&good); err != nil {
+		t.Fatalf("Sign: %v", err)
+	}
+	tampered := ScanResult{Host: "10.0.0.2", Port: 80, State: "open"}
+	if err := sig.Sign(&tampered); err != nil {
+		t.Fatalf("Sign: %v", err)
+	}
+	tampered.State = "closed" // mutate after signing, as an attacker editing the report would
+
+	path := filepath.Join(t.TempDir(), "report.json")
+	f, err := os.Create(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	enc := json.NewEncoder(f)
+	for _, r := range []ScanResult{good, tampered} {
+		if err := enc.Encode(r); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := enc.Encode(struct {
+		Meta ScanMeta `json:"meta"`
+	}{}); err != nil {
+		t.Fatal(err)
+	}
+	f.Close()
+
+	if code := runVerifyReport(path, keyPath); code != 1 {
+		t.Errorf("runVerifyReport = %d, want 1 (the tampered result should fail)", code)
+	}
+
+	untamperedPath := filepath.Join(t.TempDir(), "ok.json")
+	f2, err := os.Create(untamperedPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := json.NewEncoder(f2).Encode(good); err != nil {
+		t.Fatal(err)
+	}
+	f2.Close()
+	if code := runVerifyReport(untamperedPath, keyPath); code != 0 {
+		t.Errorf("runVerifyReport = %d, want 0 (nothing was tampered with)", code)
+	}
+}
+
+func TestRunVerifyReport_NoSignedResultsIsAnError(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "unsigned.json")
+	if err := os.WriteFile(path, []byte(`{"host":"10.0.0.1","port":22,"state":"open"}`+"\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if code := runVerifyReport(path, ""); code != 2 {
+		t.Errorf("runVerifyReport = %d, want 2 (no -sign'd results in the file)", code)
+	}
+}
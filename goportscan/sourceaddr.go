@@ -0,0 +1,24 @@
+package main
+
+import (
+	"fmt"
+	"net"
+)
+
+// validateSourceIP confirms ip is assigned to one of the local network
+// interfaces, so a typo'd -source-ip fails fast with a clear error instead
+// of silently falling through to whatever interface the kernel's default
+// route picks.
+func validateSourceIP(ip net.IP) error {
+	addrs, err := net.InterfaceAddrs()
+	if err != nil {
+		return fmt.Errorf("-source-ip: listing local interfaces: %w", err)
+	}
+	for _, addr := range addrs {
+		ipNet, ok := addr.(*net.IPNet)
+		if ok && ipNet.IP.Equal(ip) {
+			return nil
+		}
+	}
+	return fmt.Errorf("-source-ip %s is not assigned to any local interface", ip)
+}
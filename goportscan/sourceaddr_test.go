@@ -0,0 +1,38 @@
+package main
+
+import (
+	"net"
+	"strings"
+	"testing"
+)
+
+func TestValidateSourceIP_AcceptsAnAssignedLocalAddress(t *testing.T) {
+	addrs, err := net.InterfaceAddrs()
+	if err != nil {
+		t.Fatalf("InterfaceAddrs: %v", err)
+	}
+	var local net.IP
+	for _, addr := range addrs {
+		if ipNet, ok := addr.(*net.IPNet); ok && !ipNet.IP.IsLoopback() {
+			local = ipNet.IP
+			break
+		}
+	}
+	if local == nil {
+		t.Skip("no non-loopback local interface address available to test against")
+	}
+
+	if err := validateSourceIP(local); err != nil {
+		t.Errorf("validateSourceIP(%s): %v, want nil", local, err)
+	}
+}
+
+func TestValidateSourceIP_RejectsAnUnassignedAddress(t *testing.T) {
+	err := validateSourceIP(net.ParseIP("203.0.113.1"))
+	if err == nil {
+		t.Fatal("validateSourceIP: expected an error for an address no local interface has, got nil")
+	}
+	if !strings.Contains(err.Error(), "not assigned") {
+		t.Errorf("error = %q, want it to mention the address isn't assigned", err)
+	}
+}
@@ -0,0 +1,124 @@
+package main
+
+import (
+	"sync/atomic"
+	"time"
+)
+
+// rttBucketBoundaries are the upper bounds (exclusive) of every RTT
+// histogram bucket but the last, which catches everything at or beyond the
+// final boundary. Fixed rather than configurable, since the goal is a
+// quick eyeballed shape for picking a -timeout, not a tunable metric.
+var rttBucketBoundaries = []time.Duration{
+	10 * time.Millisecond,
+	50 * time.Millisecond,
+	100 * time.Millisecond,
+	250 * time.Millisecond,
+	500 * time.Millisecond,
+}
+
+// numRTTBuckets is len(rttBucketBoundaries)+1 (the catch-all last bucket),
+// kept as its own constant so scanStats.rttHistogram can be a fixed-size
+// array -- Record is hot-path and an array avoids both a slice-init step
+// and a bounds check surprise on an empty slice.
+const numRTTBuckets = 6
+
+// rttBucketLabels are rttBucketBoundaries' human-readable names, in the
+// same order, plus the catch-all last bucket.
+var rttBucketLabels = [numRTTBuckets]string{"<10ms", "<50ms", "<100ms", "<250ms", "<500ms", ">=500ms"}
+
+// rttBucketIndex returns rtt's bucket in rttBucketLabels.
+func rttBucketIndex(rtt time.Duration) int {
+	for i, boundary := range rttBucketBoundaries {
+		if rtt < boundary {
+			return i
+		}
+	}
+	return len(rttBucketBoundaries)
+}
+
+// scanStats accumulates per-probe counters across every worker goroutine
+// using sync/atomic rather than a mutex, since Record is on the hot path of
+// every single dial and a mutex there would serialize the whole worker
+// pool. Its fields are only ever read back through Snapshot, after the
+// scan's wg.Wait() (or a Ctrl-C-triggered early exit) has already stopped
+// every writer, so a snapshot's counts are always consistent with each
+// other -- never, say, a dial counted as attempted but not yet reflected in
+// any state bucket.
+type scanStats struct {
+	open         int64
+	closed       int64
+	filtered     int64
+	openFiltered int64
+	totalDials   int64
+	totalRTTNs   int64
+	rttHistogram [numRTTBuckets]int64
+}
+
+// Record tallies one completed probe's outcome and dial RTT. rtt is also
+// bucketed into the histogram, but only for a state with an RTT worth
+// tuning -timeout against -- open and closed are an actual response;
+// filtered's "RTT" is really just however long that attempt waited before
+// giving up, which would only pile up in the slowest bucket and distort it.
+func (s *scanStats) Record(state portState, rtt time.Duration) {
+	atomic.AddInt64(&s.totalDials, 1)
+	atomic.AddInt64(&s.totalRTTNs, int64(rtt))
+	switch state {
+	case portOpen:
+		atomic.AddInt64(&s.open, 1)
+		atomic.AddInt64(&s.rttHistogram[rttBucketIndex(rtt)], 1)
+	case portClosed:
+		atomic.AddInt64(&s.closed, 1)
+		atomic.AddInt64(&s.rttHistogram[rttBucketIndex(rtt)], 1)
+	case portFiltered:
+		atomic.AddInt64(&s.filtered, 1)
+	case portOpenFiltered:
+		atomic.AddInt64(&s.openFiltered, 1)
+	}
+}
+
+// RTTHistogramBucket is one fixed latency bucket of a ScanStats'
+// RTTHistogram, e.g. {Label: "<50ms", Count: 12}.
+type RTTHistogramBucket struct {
+	Label string `json:"label"`
+	Count int64  `json:"count"`
+}
+
+// ScanStats is the read-only summary Snapshot produces: total dials
+// attempted (including a cancelled scan's partial count), the state
+// breakdown, average RTT, the RTT distribution of open/closed dials, and
+// throughput over elapsed.
+type ScanStats struct {
+	Open           int64                `json:"open"`
+	Closed         int64                `json:"closed"`
+	Filtered       int64                `json:"filtered"`
+	OpenFiltered   int64                `json:"openFiltered"`
+	TotalDials     int64                `json:"totalDials"`
+	AvgRTTMillis   float64              `json:"avgRttMillis"`
+	DialsPerSecond float64              `json:"dialsPerSecond"`
+	RTTHistogram   []RTTHistogramBucket `json:"rttHistogram"`
+}
+
+// Snapshot reads every counter once and derives the average RTT and
+// throughput figures from it, given how long the scan ran for.
+func (s *scanStats) Snapshot(elapsed time.Duration) ScanStats {
+	total := atomic.LoadInt64(&s.totalDials)
+	snap := ScanStats{
+		Open:         atomic.LoadInt64(&s.open),
+		Closed:       atomic.LoadInt64(&s.closed),
+		Filtered:     atomic.LoadInt64(&s.filtered),
+		OpenFiltered: atomic.LoadInt64(&s.openFiltered),
+		TotalDials:   total,
+	}
+	if total > 0 {
+		snap.AvgRTTMillis = float64(atomic.LoadInt64(&s.totalRTTNs)) / float64(total) / float64(time.Millisecond)
+	}
+	if elapsed > 0 {
+		snap.DialsPerSecond = float64(total) / elapsed.Seconds()
+	}
+	snap.RTTHistogram = make([]RTTHistogramBucket, len(rttBucketLabels))
+	for i, label := range rttBucketLabels {
+		snap.RTTHistogram[i] = RTTHistogramBucket{Label: label, Count: atomic.LoadInt64(&s.rttHistogram[i])}
+	}
+	return snap
+}
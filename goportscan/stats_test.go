@@ -0,0 +1,87 @@
+package main
+
+import (
+	"testing"
+	"time"
+)
+
+func TestScanStats_RecordTalliesByState(t *testing.T) {
+	var s scanStats
+	s.Record(portOpen, 10*time.Millisecond)
+	s.Record(portOpen, 20*time.Millisecond)
+	s.Record(portClosed, 5*time.Millisecond)
+	s.Record(portFiltered, 5*time.Millisecond)
+	s.Record(portOpenFiltered, 5*time.Millisecond)
+
+	snap := s.Snapshot(time.Second)
+	if snap.Open != 2 || snap.Closed != 1 || snap.Filtered != 1 || snap.OpenFiltered != 1 {
+		t.Errorf("snapshot = %+v, want 2 open, 1 closed, 1 filtered, 1 open|filtered", snap)
+	}
+	if snap.TotalDials != 5 {
+		t.Errorf("TotalDials = %d, want 5", snap.TotalDials)
+	}
+}
+
+func TestScanStats_SnapshotComputesAverageRTTAndRate(t *testing.T) {
+	var s scanStats
+	s.Record(portOpen, 10*time.Millisecond)
+	s.Record(portClosed, 30*time.Millisecond)
+
+	snap := s.Snapshot(2 * time.Second)
+	if snap.AvgRTTMillis != 20 {
+		t.Errorf("AvgRTTMillis = %v, want 20", snap.AvgRTTMillis)
+	}
+	if snap.DialsPerSecond != 1 {
+		t.Errorf("DialsPerSecond = %v, want 1 (2 dials / 2s)", snap.DialsPerSecond)
+	}
+}
+
+func TestScanStats_SnapshotOfEmptyStatsHasNoDivideByZero(t *testing.T) {
+	var s scanStats
+	snap := s.Snapshot(0)
+	if snap.TotalDials != 0 || snap.AvgRTTMillis != 0 || snap.DialsPerSecond != 0 {
+		t.Errorf("snapshot of unused scanStats = %+v, want all zero", snap)
+	}
+}
+
+func TestRTTBucketIndex_SortsIntoTheRightBucket(t *testing.T) {
+	cases := []struct {
+		rtt  time.Duration
+		want string
+	}{
+		{1 * time.Millisecond, "<10ms"},
+		{9 * time.Millisecond, "<10ms"},
+		{10 * time.Millisecond, "<50ms"},
+		{49 * time.Millisecond, "<50ms"},
+		{99 * time.Millisecond, "<100ms"},
+		{249 * time.Millisecond, "<250ms"},
+		{499 * time.Millisecond, "<500ms"},
+		{500 * time.Millisecond, ">=500ms"},
+		{2 * time.Second, ">=500ms"},
+	}
+	for _, c := range cases {
+		got := rttBucketLabels[rttBucketIndex(c.rtt)]
+		if got != c.want {
+			t.Errorf("rttBucketIndex(%s) = %s, want %s", c.rtt, got, c.want)
+		}
+	}
+}
+
+func TestScanStats_RecordBucketsOpenAndClosedRTTsButNotFiltered(t *testing.T) {
+	var s scanStats
+	s.Record(portOpen, 5*time.Millisecond)
+	s.Record(portClosed, 5*time.Millisecond)
+	s.Record(portFiltered, 2*time.Second) // a timeout, not a real RTT -- must not skew the histogram
+
+	snap := s.Snapshot(time.Second)
+	var total int64
+	for _, b := range snap.RTTHistogram {
+		total += b.Count
+	}
+	if total != 2 {
+		t.Errorf("histogram has %d total sample(s), want 2 (filtered shouldn't count)", total)
+	}
+	if snap.RTTHistogram[0].Label != "<10ms" || snap.RTTHistogram[0].Count != 2 {
+		t.Errorf("RTTHistogram[0] = %+v, want {<10ms 2}", snap.RTTHistogram[0])
+	}
+}
@@ -0,0 +1,258 @@
+//go:build linux
+
+package main
+
+import (
+	"encoding/binary"
+	"math/rand"
+	"net"
+	"sync"
+	"syscall"
+	"time"
+)
+
+// rawSYNProber implements synProber using Linux raw sockets: it crafts and
+// sends its own IPv4/TCP SYN segments and reads replies off a second raw
+// socket bound to IPPROTO_TCP, correlating them by (srcPort, dstIP, dstPort).
+//
+// This is a deliberately minimal stand-in for the pcap/BPF-filtered capture
+// a production SYN scanner would use (golang.org/x/net/ipv4 + an AF_PACKET
+// socket aren't vendored into this module): we read every inbound TCP
+// segment and filter in userspace instead of with a kernel BPF program.
+// Callers should also be aware of the well-known kernel auto-RST problem:
+// since these are raw sockets rather than real connections, the kernel's
+// own TCP stack doesn't know about them and will send its own RST in reply
+// to an unsolicited SYN-ACK a split second after we record it as open.
+type rawSYNProber struct {
+	sendFD int
+	srcIP  net.IP
+
+	recvConn *net.IPConn
+
+	mu      sync.Mutex
+	pending map[synKey]chan synResult
+}
+
+type synKey struct {
+	srcPort int
+	dstIP   string
+	dstPort int
+}
+
+type synResult struct {
+	synAck bool
+	rst    bool
+}
+
+// newSYNProber opens the raw sockets needed for a half-open scan. It returns
+// a nil prober (not an error) when the process lacks CAP_NET_RAW, so callers
+// can fall back to a connect scan transparently.
+func newSYNProber() (synProber, error) {
+	sendFD, err := syscall.Socket(syscall.AF_INET, syscall.SOCK_RAW, syscall.IPPROTO_TCP)
+	if err != nil {
+		return nil, err
+	}
+	if err := syscall.SetsockoptInt(sendFD, syscall.IPPROTO_IP, syscall.IP_HDRINCL, 1); err != nil {
+		syscall.Close(sendFD)
+		return nil, err
+	}
+
+	recvConn, err := net.ListenIP("ip4:tcp", &net.IPAddr{})
+	if err != nil {
+		syscall.Close(sendFD)
+		return nil, err
+	}
+
+	srcIP, err := outboundIP()
+	if err != nil {
+		syscall.Close(sendFD)
+		recvConn.Close()
+		return nil, err
+	}
+
+	p := &rawSYNProber{
+		sendFD:   sendFD,
+		srcIP:    srcIP,
+		recvConn: recvConn,
+		pending:  make(map[synKey]chan synResult),
+	}
+	go p.readLoop()
+	return p, nil
+}
+
+func outboundIP() (net.IP, error) {
+	conn, err := net.Dial("udp", "8.8.8.8:80")
+	if err != nil {
+		return nil, err
+	}
+	defer conn.Close()
+	return conn.LocalAddr().(*net.UDPAddr).IP.To4(), nil
+}
+
+// Probe sends a SYN and retries with exponential backoff until a reply is
+// seen or timeout elapses.
+func (p *rawSYNProber) Probe(addr Addr, timeout time.Duration) (portState, error) {
+	srcPort := 1024 + rand.Intn(60000)
+	seq := rand.Uint32()
+
+	key := synKey{srcPort: srcPort, dstIP: addr.IP.String(), dstPort: addr.Port}
+	ch := make(chan synResult, 1)
+	p.mu.Lock()
+	p.pending[key] = ch
+	p.mu.Unlock()
+	defer func() {
+		p.mu.Lock()
+		delete(p.pending, key)
+		p.mu.Unlock()
+	}()
+
+	backoff := timeout / 4
+	deadline := time.Now().Add(timeout)
+	for attempt := 0; ; attempt++ {
+		if err := p.sendSYN(addr.IP.To4(), addr.Port, srcPort, seq); err != nil {
+			return portFiltered, err
+		}
+
+		wait := backoff
+		if remaining := time.Until(deadline); remaining < wait {
+			wait = remaining
+		}
+		if wait <= 0 {
+			break
+		}
+
+		select {
+		case res := <-ch:
+			if res.synAck {
+				return portOpen, nil
+			}
+			if res.rst {
+				return portClosed, nil
+			}
+		case <-time.After(wait):
+		}
+
+		if time.Now().After(deadline) {
+			break
+		}
+		backoff *= 2
+	}
+
+	// No reply within the deadline: filtered.
+	return portFiltered, nil
+}
+
+func (p *rawSYNProber) readLoop() {
+	buf := make([]byte, 1500)
+	for {
+		n, addr, err := p.recvConn.ReadFromIP(buf)
+		if err != nil {
+			return
+		}
+		p.handleSegment(addr.IP, buf[:n])
+	}
+}
+
+func (p *rawSYNProber) handleSegment(srcIP net.IP, seg []byte) {
+	if len(seg) < 20 {
+		return
+	}
+	srcPort := int(binary.BigEndian.Uint16(seg[0:2]))
+	dstPort := int(binary.BigEndian.Uint16(seg[2:4]))
+	flags := seg[13]
+
+	key := synKey{srcPort: dstPort, dstIP: srcIP.String(), dstPort: srcPort}
+	p.mu.Lock()
+	ch, ok := p.pending[key]
+	p.mu.Unlock()
+	if !ok {
+		return
+	}
+
+	const (
+		flagSYN = 0x02
+		flagACK = 0x10
+		flagRST = 0x04
+	)
+	res := synResult{
+		synAck: flags&flagSYN != 0 && flags&flagACK != 0,
+		rst:    flags&flagRST != 0,
+	}
+	if res.synAck || res.rst {
+		select {
+		case ch <- res:
+		default:
+		}
+	}
+}
+
+func (p *rawSYNProber) sendSYN(dstIP net.IP, dstPort, srcPort int, seq uint32) error {
+	tcpHeader := buildTCPSYN(p.srcIP, dstIP, srcPort, dstPort, seq)
+	packet := buildIPv4Header(p.srcIP, dstIP, len(tcpHeader))
+	packet = append(packet, tcpHeader...)
+
+	var dst [4]byte
+	copy(dst[:], dstIP.To4())
+	sa := &syscall.SockaddrInet4{Addr: dst}
+	return syscall.Sendto(p.sendFD, packet, 0, sa)
+}
+
+func (p *rawSYNProber) Close() error {
+	p.recvConn.Close()
+	return syscall.Close(p.sendFD)
+}
+
+func buildIPv4Header(src, dst net.IP, payloadLen int) []byte {
+	h := make([]byte, 20)
+	h[0] = 0x45 // version 4, IHL 5
+	h[1] = 0x00
+	binary.BigEndian.PutUint16(h[2:4], uint16(20+payloadLen))
+	binary.BigEndian.PutUint16(h[4:6], uint16(rand.Intn(65536)))
+	h[6] = 0x40 // don't fragment
+	h[8] = 64   // TTL
+	h[9] = syscall.IPPROTO_TCP
+	copy(h[12:16], src.To4())
+	copy(h[16:20], dst.To4())
+	binary.BigEndian.PutUint16(h[10:12], ipChecksum(h))
+	return h
+}
+
+func buildTCPSYN(src, dst net.IP, srcPort, dstPort int, seq uint32) []byte {
+	h := make([]byte, 20)
+	binary.BigEndian.PutUint16(h[0:2], uint16(srcPort))
+	binary.BigEndian.PutUint16(h[2:4], uint16(dstPort))
+	binary.BigEndian.PutUint32(h[4:8], seq)
+	binary.BigEndian.PutUint32(h[8:12], 0) // ack
+	h[12] = 5 << 4                         // data offset: 5 words, no options
+	h[13] = 0x02                           // SYN
+	binary.BigEndian.PutUint16(h[14:16], 65535)
+	binary.BigEndian.PutUint16(h[16:18], 0) // checksum, filled below
+	binary.BigEndian.PutUint16(h[18:20], 0) // urgent pointer
+
+	checksum := tcpChecksum(src, dst, h)
+	binary.BigEndian.PutUint16(h[16:18], checksum)
+	return h
+}
+
+func ipChecksum(b []byte) uint16 {
+	var sum uint32
+	for i := 0; i+1 < len(b); i += 2 {
+		sum += uint32(b[i])<<8 | uint32(b[i+1])
+	}
+	for sum>>16 != 0 {
+		sum = (sum & 0xffff) + (sum >> 16)
+	}
+	return ^uint16(sum)
+}
+
+func tcpChecksum(src, dst net.IP, tcpHeader []byte) uint16 {
+	pseudo := make([]byte, 0, 12+len(tcpHeader))
+	pseudo = append(pseudo, src.To4()...)
+	pseudo = append(pseudo, dst.To4()...)
+	pseudo = append(pseudo, 0, syscall.IPPROTO_TCP)
+	lenBuf := make([]byte, 2)
+	binary.BigEndian.PutUint16(lenBuf, uint16(len(tcpHeader)))
+	pseudo = append(pseudo, lenBuf...)
+	pseudo = append(pseudo, tcpHeader...)
+	return ipChecksum(pseudo)
+}
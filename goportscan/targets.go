@@ -0,0 +1,526 @@
+package main
+
+import (
+	"bufio"
+	"fmt"
+	"net"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// expandTargets turns a list of CLI target specs into a flat, deduplicated
+// list of IPs to scan, along with the subset of those IPs that came from a
+// spec naming a single host directly (as opposed to a CIDR block or range).
+// Each spec may be a single IPv4 or IPv6 literal, a hostname, a CIDR block
+// (e.g. "10.0.0.0/24" or "fd00::/120"), a dashed IPv4 range (e.g.
+// "10.0.0.1-10.0.0.254" or the "10.0.0.1-254" last-octet shorthand), or an
+// nmap-style octet range with a "low-high" span in one or more of its four
+// dotted fields (e.g. "10.0.0-2.1-254"); IPv6 has no range shorthand since
+// its address space is too sparse for a linear scan to make sense.
+//
+// preferIPv6 controls which family is kept when a hostname resolves to both:
+// without it, expandTargets picks the IPv4 address the way it always has;
+// with it, the IPv6 address is kept instead, falling back to IPv4 if the
+// hostname has no AAAA record. A bare IP literal is always used as given,
+// regardless of preferIPv6.
+//
+// includeNetworkBroadcast controls whether a CIDR spec's network and
+// broadcast addresses are expanded along with the rest of the block; see
+// expandCIDR.
+//
+// Callers use the explicit set to make sure a host the user named directly
+// is still scanned even if host discovery marks it dead, since discovery is
+// a heuristic and a single explicitly-named target is presumably intentional.
+//
+// The returned aliases map records, per resolved IP, every hostname spec
+// that resolved to it. Combined with expandTargets's existing
+// dedupe-by-IP (via seen), this is what lets an overlapping target list --
+// a CIDR block and a literal address inside it, or a hostname alongside its
+// own IP -- scan each unique host exactly once while still letting the
+// output note every name it was also known by.
+func expandTargets(specs []string, preferIPv6, includeNetworkBroadcast bool) (ips []net.IP, explicit map[string]bool, aliases map[string][]string, err error) {
+	seen := make(map[string]bool)
+	explicit = make(map[string]bool)
+	aliases = make(map[string][]string)
+
+	add := func(ip net.IP, isExplicit bool, hostname string) {
+		key := ip.String()
+		if !seen[key] {
+			seen[key] = true
+			ips = append(ips, ip)
+		}
+		if isExplicit {
+			explicit[key] = true
+		}
+		if hostname != "" && !containsStr(aliases[key], hostname) {
+			aliases[key] = append(aliases[key], hostname)
+		}
+	}
+
+	for _, spec := range specs {
+		spec = strings.TrimSpace(spec)
+		if spec == "" {
+			continue
+		}
+
+		switch {
+		case strings.Contains(spec, "/"):
+			expanded, err := expandCIDR(spec, includeNetworkBroadcast)
+			if err != nil {
+				return nil, nil, nil, fmt.Errorf("target %q: %w", spec, err)
+			}
+			for _, ip := range expanded {
+				add(ip, false, "")
+			}
+
+		case isDashedIPRange(spec):
+			expanded, err := expandRange(spec)
+			if err != nil {
+				return nil, nil, nil, fmt.Errorf("target %q: %w", spec, err)
+			}
+			for _, ip := range expanded {
+				add(ip, false, "")
+			}
+
+		case isOctetRange(spec):
+			expanded, err := expandOctetRange(spec)
+			if err != nil {
+				return nil, nil, nil, fmt.Errorf("target %q: %w", spec, err)
+			}
+			for _, ip := range expanded {
+				add(ip, false, "")
+			}
+
+		default:
+			if ip := net.ParseIP(spec); ip != nil {
+				if v4 := ip.To4(); v4 != nil {
+					add(v4, true, "")
+				} else {
+					add(ip, true, "")
+				}
+				continue
+			}
+			resolved, err := resolveIPs(spec)
+			if err != nil {
+				return nil, nil, nil, fmt.Errorf("target %q: could not resolve hostname: %w", spec, err)
+			}
+			chosen := preferredFamily(resolved, preferIPv6)
+			// Resolve once up front, here, rather than handing the hostname
+			// itself to net.DialTimeout per-port: that would mean one DNS
+			// lookup per port instead of one for the whole scan. Printing
+			// what it resolved to makes the substitution visible instead of
+			// a silent surprise if the wrong address gets scanned.
+			fmt.Fprintf(os.Stderr, "resolved %s to %s\n", spec, joinIPs(chosen))
+			for _, ip := range chosen {
+				add(ip, true, spec)
+			}
+		}
+	}
+
+	return ips, explicit, aliases, nil
+}
+
+// containsStr reports whether s is present in list.
+func containsStr(list []string, s string) bool {
+	for _, v := range list {
+		if v == s {
+			return true
+		}
+	}
+	return false
+}
+
+// readTargetFile reads newline-separated target specs for -target-file: a
+// "#" starts a comment (whether the whole line or trailing one), blank
+// lines are skipped, and a line containing whitespace -- not a shape any
+// IP, CIDR, range, or hostname can take -- is reported to stderr and
+// skipped rather than failing the whole file, so one typo doesn't cost the
+// other few hundred targets.
+func readTargetFile(path string) ([]string, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, fmt.Errorf("target-file: %w", err)
+	}
+	defer f.Close()
+
+	var specs []string
+	scanner := bufio.NewScanner(f)
+	for lineNum := 1; scanner.Scan(); lineNum++ {
+		line := scanner.Text()
+		if idx := strings.Index(line, "#"); idx >= 0 {
+			line = line[:idx]
+		}
+		line = strings.TrimSpace(line)
+		if line == "" {
+			continue
+		}
+		if strings.ContainsAny(line, " \t") {
+			fmt.Fprintf(os.Stderr, "warning: %s:%d: malformed target %q, skipping\n", path, lineNum, line)
+			continue
+		}
+		specs = append(specs, line)
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("target-file: %w", err)
+	}
+	return specs, nil
+}
+
+// dedupeSpecs drops repeated target specs while preserving first-seen
+// order, so combining -target-file with command-line targets doesn't scan
+// the same host twice just because it appeared in both places.
+func dedupeSpecs(specs []string) []string {
+	seen := make(map[string]bool, len(specs))
+	out := specs[:0]
+	for _, spec := range specs {
+		trimmed := strings.TrimSpace(spec)
+		if trimmed == "" || seen[trimmed] {
+			continue
+		}
+		seen[trimmed] = true
+		out = append(out, spec)
+	}
+	return out
+}
+
+// preferredFamily filters a hostname's resolved addresses down to the
+// family the caller asked for (IPv6 when preferIPv6, IPv4 otherwise),
+// falling back to whatever the other family offered if the preferred one
+// has no records, since a host with only one address family is still worth
+// scanning.
+func preferredFamily(resolved []net.IP, preferIPv6 bool) []net.IP {
+	var matched, fallback []net.IP
+	for _, ip := range resolved {
+		isV6 := ip.To4() == nil
+		if v4 := ip.To4(); v4 != nil {
+			ip = v4
+		}
+		if isV6 == preferIPv6 {
+			matched = append(matched, ip)
+		} else {
+			fallback = append(fallback, ip)
+		}
+	}
+	if len(matched) > 0 {
+		return matched
+	}
+	return fallback
+}
+
+// joinIPs renders a slice of resolved addresses for a status message, e.g.
+// "10.0.0.5" or "10.0.0.5, 10.0.0.6".
+func joinIPs(ips []net.IP) string {
+	strs := make([]string, len(ips))
+	for i, ip := range ips {
+		strs[i] = ip.String()
+	}
+	return strings.Join(strs, ", ")
+}
+
+// isDashedIPRange reports whether spec looks like a dashed IP range
+// ("10.0.0.1-10.0.0.254" or the "10.0.0.1-254" shorthand) rather than a
+// hostname that merely happens to contain a hyphen (e.g. "web-01.internal").
+// It requires the part before the hyphen to parse as a dotted IPv4 address;
+// anything else falls through to hostname resolution.
+func isDashedIPRange(spec string) bool {
+	idx := strings.Index(spec, "-")
+	if idx < 0 {
+		return false
+	}
+	start := net.ParseIP(strings.TrimSpace(spec[:idx]))
+	return start != nil && start.To4() != nil
+}
+
+// isBareHostname reports whether spec names a single host by hostname
+// rather than by IP literal, CIDR block, or dashed/octet range: the only
+// kind of spec expandTargets resolves via a local DNS lookup, and so the
+// only kind -proxy needs to divert around the local resolver (see setProxy
+// and its caller in main).
+func isBareHostname(spec string) bool {
+	if strings.Contains(spec, "/") || isDashedIPRange(spec) || isOctetRange(spec) {
+		return false
+	}
+	return net.ParseIP(spec) == nil
+}
+
+// maxIPv6CIDRHostBits caps how large an IPv6 block expandCIDR will fully
+// enumerate. IPv6 subnets are conventionally /64 or larger, and unlike IPv4
+// there's no realistic case where sweeping every address in one is
+// intentional rather than a typo, so anything past /112 (65536 addresses) is
+// rejected outright instead of silently taking hours to enumerate.
+const maxIPv6CIDRHostBits = 16
+
+// expandCIDR enumerates every host address in a CIDR block. Network and
+// broadcast addresses are skipped for IPv4 blocks larger than /31, unless
+// includeNetworkBroadcast is set. A /31 has no network/broadcast addresses
+// to skip under RFC 3021 (both addresses are usable point-to-point hosts),
+// and a /32 is just the one address, so neither is ever trimmed regardless
+// of the flag. IPv6 has no broadcast concept, so it's never trimmed either.
+func expandCIDR(cidr string, includeNetworkBroadcast bool) ([]net.IP, error) {
+	ip, ipNet, err := net.ParseCIDR(cidr)
+	if err != nil {
+		return nil, err
+	}
+
+	if ip.To4() == nil {
+		if ones, bits := ipNet.Mask.Size(); bits-ones > maxIPv6CIDRHostBits {
+			return nil, fmt.Errorf("IPv6 blocks larger than /%d are not supported (too many addresses to enumerate)", bits-maxIPv6CIDRHostBits)
+		}
+	}
+
+	var ips []net.IP
+	for cur := ip.Mask(ipNet.Mask); ipNet.Contains(cur); incIP(cur) {
+		ips = append(ips, cloneIP(cur))
+	}
+
+	ones, bits := ipNet.Mask.Size()
+	if ip.To4() != nil && !includeNetworkBroadcast && bits-ones > 1 && len(ips) > 2 {
+		ips = ips[1 : len(ips)-1] // drop network and broadcast addresses; IPv6 has no broadcast address to drop
+	}
+	return ips, nil
+}
+
+// expandRange enumerates the inclusive IP range described by "start-end",
+// e.g. "10.0.0.1-10.0.0.254".
+func expandRange(spec string) ([]net.IP, error) {
+	parts := strings.SplitN(spec, "-", 2)
+	if len(parts) != 2 {
+		return nil, fmt.Errorf("invalid range")
+	}
+
+	startIP := net.ParseIP(strings.TrimSpace(parts[0])).To4()
+	if startIP == nil {
+		return nil, fmt.Errorf("invalid range start")
+	}
+
+	endPart := strings.TrimSpace(parts[1])
+	var endIP net.IP
+	if dotted := net.ParseIP(endPart); dotted != nil {
+		endIP = dotted.To4()
+	} else {
+		// Shorthand like "10.0.0.1-254": reuse the start's first three octets.
+		last, err := strconv.Atoi(endPart)
+		if err != nil || last < 0 || last > 255 {
+			return nil, fmt.Errorf("invalid range end")
+		}
+		endIP = cloneIP(startIP)
+		endIP[3] = byte(last)
+	}
+	if endIP == nil {
+		return nil, fmt.Errorf("invalid range end")
+	}
+
+	if ipToUint32(endIP) < ipToUint32(startIP) {
+		return nil, fmt.Errorf("range end is before start")
+	}
+
+	var ips []net.IP
+	for cur := cloneIP(startIP); ; incIP(cur) {
+		ips = append(ips, cloneIP(cur))
+		if cur.Equal(endIP) {
+			break
+		}
+	}
+	return ips, nil
+}
+
+// isOctetRange reports whether spec is nmap-style multi-octet range syntax,
+// e.g. "10.0.0-2.1-254": four dot-separated fields, each either a plain
+// octet or a "low-high" range, with at least one field actually ranged (a
+// plain four-octet address with no range anywhere is just a literal, and
+// net.ParseIP already handles that in expandTargets's default case). It's
+// checked after isDashedIPRange so the "start-end"/"start-N" whole-address
+// shorthand that syntax already covers (e.g. "10.0.0.1-50") keeps using
+// that simpler expansion instead of this one.
+func isOctetRange(spec string) bool {
+	fields := strings.Split(spec, ".")
+	if len(fields) != 4 {
+		return false
+	}
+	rangedFields := 0
+	lastRanged := false
+	for i, field := range fields {
+		lo, hi, ok := parseOctetField(field)
+		if !ok || lo > hi {
+			return false
+		}
+		if lo != hi {
+			rangedFields++
+			lastRanged = i == len(fields)-1
+		}
+	}
+	if rangedFields == 0 {
+		return false
+	}
+	if rangedFields == 1 && lastRanged && isDashedIPRange(spec) {
+		// Ranged only in the last field, e.g. "10.0.0.1-50" -- that's the
+		// whole-address shorthand isDashedIPRange already covers, so defer
+		// to it instead of double-matching here.
+		return false
+	}
+	return true
+}
+
+// parseOctetField parses one dotted-quad field of an nmap-style octet
+// range: either a single octet ("5") or an inclusive range within it
+// ("0-2"), with both bounds validated to fall within 0-255.
+func parseOctetField(field string) (lo, hi int, ok bool) {
+	if idx := strings.Index(field, "-"); idx >= 0 {
+		var err error
+		if lo, err = strconv.Atoi(field[:idx]); err != nil {
+			return 0, 0, false
+		}
+		if hi, err = strconv.Atoi(field[idx+1:]); err != nil {
+			return 0, 0, false
+		}
+	} else {
+		n, err := strconv.Atoi(field)
+		if err != nil {
+			return 0, 0, false
+		}
+		lo, hi = n, n
+	}
+	if lo < 0 || hi > 255 {
+		return 0, 0, false
+	}
+	return lo, hi, true
+}
+
+// expandOctetRange enumerates the cartesian product of an nmap-style
+// multi-octet range spec, e.g. "10.0.0-2.1-254" becomes every address from
+// 10.0.0.1 through 10.0.2.254. Each field is re-parsed here rather than
+// threading isOctetRange's parsed bounds through, the same way
+// isDashedIPRange and expandRange keep detection and expansion independent.
+func expandOctetRange(spec string) ([]net.IP, error) {
+	fields := strings.Split(spec, ".")
+	if len(fields) != 4 {
+		return nil, fmt.Errorf("invalid octet range %q", spec)
+	}
+	var bounds [4][2]int
+	for i, field := range fields {
+		lo, hi, ok := parseOctetField(field)
+		if !ok {
+			return nil, fmt.Errorf("invalid octet %q: must be 0-255", field)
+		}
+		if lo > hi {
+			return nil, fmt.Errorf("invalid octet range %q: start is after end", field)
+		}
+		bounds[i] = [2]int{lo, hi}
+	}
+
+	var ips []net.IP
+	for a := bounds[0][0]; a <= bounds[0][1]; a++ {
+		for b := bounds[1][0]; b <= bounds[1][1]; b++ {
+			for c := bounds[2][0]; c <= bounds[2][1]; c++ {
+				for d := bounds[3][0]; d <= bounds[3][1]; d++ {
+					ips = append(ips, net.IPv4(byte(a), byte(b), byte(c), byte(d)).To4())
+				}
+			}
+		}
+	}
+	return ips, nil
+}
+
+func cloneIP(ip net.IP) net.IP {
+	out := make(net.IP, len(ip))
+	copy(out, ip)
+	return out
+}
+
+func incIP(ip net.IP) {
+	for i := len(ip) - 1; i >= 0; i-- {
+		ip[i]++
+		if ip[i] != 0 {
+			return
+		}
+	}
+}
+
+func ipToUint32(ip net.IP) uint32 {
+	v4 := ip.To4()
+	return uint32(v4[0])<<24 | uint32(v4[1])<<16 | uint32(v4[2])<<8 | uint32(v4[3])
+}
+
+// parsePortList parses a comma-separated port/range spec such as
+// "22,80,8000-8100" into a sorted, deduplicated slice of ports.
+func parsePortList(spec string) ([]int, error) {
+	seen := make(map[int]bool)
+	var ports []int
+
+	for _, field := range strings.Split(spec, ",") {
+		field = strings.TrimSpace(field)
+		if field == "" {
+			return nil, fmt.Errorf("invalid port spec %q: empty token", spec)
+		}
+		if strings.Contains(field, "-") {
+			bounds := strings.SplitN(field, "-", 2)
+			if len(bounds) != 2 {
+				return nil, fmt.Errorf("invalid port range %q", field)
+			}
+			lo, err := strconv.Atoi(strings.TrimSpace(bounds[0]))
+			if err != nil {
+				return nil, fmt.Errorf("invalid port range %q", field)
+			}
+			hi, err := strconv.Atoi(strings.TrimSpace(bounds[1]))
+			if err != nil {
+				return nil, fmt.Errorf("invalid port range %q", field)
+			}
+			if lo > hi {
+				return nil, fmt.Errorf("invalid port range %q: start is after end", field)
+			}
+			if lo < 1 || hi > 65535 {
+				return nil, fmt.Errorf("invalid port range %q: ports must be between 1 and 65535", field)
+			}
+			for p := lo; p <= hi; p++ {
+				if !seen[p] {
+					seen[p] = true
+					ports = append(ports, p)
+				}
+			}
+			continue
+		}
+
+		p, err := strconv.Atoi(field)
+		if err != nil {
+			return nil, fmt.Errorf("invalid port %q", field)
+		}
+		if p < 1 || p > 65535 {
+			return nil, fmt.Errorf("invalid port %q: must be between 1 and 65535", field)
+		}
+		if !seen[p] {
+			seen[p] = true
+			ports = append(ports, p)
+		}
+	}
+
+	sort.Ints(ports)
+	return ports, nil
+}
+
+// parseHostSet parses a comma-separated list of hosts/IPs to exclude into a
+// lookup set keyed by IP string.
+func parseHostSet(spec string) (map[string]bool, error) {
+	set := make(map[string]bool)
+	for _, field := range strings.Split(spec, ",") {
+		field = strings.TrimSpace(field)
+		if field == "" {
+			continue
+		}
+		if ip := net.ParseIP(field); ip != nil {
+			set[ip.String()] = true
+			continue
+		}
+		resolved, err := resolveIPs(field)
+		if err != nil {
+			return nil, fmt.Errorf("exclude-hosts %q: %w", field, err)
+		}
+		for _, ip := range resolved {
+			if v4 := ip.To4(); v4 != nil {
+				set[v4.String()] = true
+			}
+		}
+	}
+	return set, nil
+}
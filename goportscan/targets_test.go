@@ -0,0 +1,430 @@
+package main
+
+import (
+	"net"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+func TestReadTargetFile_SkipsCommentsAndBlankLines(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "targets.txt")
+	content := "10.0.0.1\n# a comment\n\n10.0.0.2 # trailing comment\n  \nweb-01.internal\n"
+	if err := os.WriteFile(path, []byte(content), 0o644); err != nil {
+		t.Fatalf("WriteFile: %v", err)
+	}
+
+	specs, err := readTargetFile(path)
+	if err != nil {
+		t.Fatalf("readTargetFile: %v", err)
+	}
+	want := []string{"10.0.0.1", "10.0.0.2", "web-01.internal"}
+	if !reflect.DeepEqual(specs, want) {
+		t.Errorf("specs = %v, want %v", specs, want)
+	}
+}
+
+func TestReadTargetFile_SkipsMalformedLinesButKeepsTheRest(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "targets.txt")
+	content := "10.0.0.1\nthis is not a target\n10.0.0.2\n"
+	if err := os.WriteFile(path, []byte(content), 0o644); err != nil {
+		t.Fatalf("WriteFile: %v", err)
+	}
+
+	specs, err := readTargetFile(path)
+	if err != nil {
+		t.Fatalf("readTargetFile: %v", err)
+	}
+	want := []string{"10.0.0.1", "10.0.0.2"}
+	if !reflect.DeepEqual(specs, want) {
+		t.Errorf("specs = %v, want %v", specs, want)
+	}
+}
+
+func TestReadTargetFile_MissingFileErrors(t *testing.T) {
+	if _, err := readTargetFile(filepath.Join(t.TempDir(), "nope.txt")); err == nil {
+		t.Error("readTargetFile on a missing file: expected an error, got nil")
+	}
+}
+
+func TestDedupeSpecs(t *testing.T) {
+	got := dedupeSpecs([]string{"10.0.0.1", "10.0.0.2", "10.0.0.1", " 10.0.0.2 ", "", "web-01"})
+	want := []string{"10.0.0.1", "10.0.0.2", "web-01"}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("dedupeSpecs = %v, want %v", got, want)
+	}
+}
+
+func TestIsDashedIPRange(t *testing.T) {
+	cases := []struct {
+		spec string
+		want bool
+	}{
+		{"10.0.0.1-10.0.0.254", true},
+		{"10.0.0.1-254", true},
+		{"web-01.internal", false},
+		{"my-bad-host.example.invalid", false},
+		{"example.com", false},
+		{"10.0.0.1", false},
+	}
+	for _, c := range cases {
+		if got := isDashedIPRange(c.spec); got != c.want {
+			t.Errorf("isDashedIPRange(%q) = %v, want %v", c.spec, got, c.want)
+		}
+	}
+}
+
+func TestExpandTargets_HyphenatedHostnameIsNotARange(t *testing.T) {
+	// A hyphenated hostname should attempt DNS resolution, not fail with
+	// "invalid range start" the way a malformed dashed IP range would.
+	_, _, _, err := expandTargets([]string{"my-bad-host.example.invalid"}, false, false)
+	if err == nil {
+		t.Fatal("expected a resolution error for an unresolvable hostname, got nil")
+	}
+	if got := err.Error(); got == `target "my-bad-host.example.invalid": invalid range start` {
+		t.Fatalf("hyphenated hostname was misrouted into expandRange: %v", err)
+	}
+}
+
+func TestExpandTargets_AcceptsIPv6Literal(t *testing.T) {
+	ips, explicit, _, err := expandTargets([]string{"::1"}, false, false)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(ips) != 1 || ips[0].String() != "::1" {
+		t.Fatalf("expandTargets([\"::1\"]) = %v, want [::1]", ips)
+	}
+	if !explicit["::1"] {
+		t.Errorf("expected ::1 to be marked explicit, got %v", explicit)
+	}
+}
+
+func TestExpandTargets_IPv6CIDR(t *testing.T) {
+	ips, _, _, err := expandTargets([]string{"fd00::/126"}, false, false)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(ips) != 4 {
+		t.Fatalf("expandTargets([\"fd00::/126\"]) = %v, want 4 addresses", ips)
+	}
+}
+
+func TestExpandTargets_RejectsOversizedIPv6CIDR(t *testing.T) {
+	_, _, _, err := expandTargets([]string{"fd00::/32"}, false, false)
+	if err == nil {
+		t.Fatal("expected an error for an oversized IPv6 CIDR block, got nil")
+	}
+}
+
+func TestExpandTargets_SingleHostIsExplicit(t *testing.T) {
+	ips, explicit, _, err := expandTargets([]string{"10.0.0.5", "10.0.0.0/30"}, false, false)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if !explicit["10.0.0.5"] {
+		t.Errorf("expected 10.0.0.5 to be marked explicit, got %v", explicit)
+	}
+	for _, ip := range ips {
+		if ip.String() != "10.0.0.5" && explicit[ip.String()] {
+			t.Errorf("CIDR-expanded address %s should not be marked explicit", ip)
+		}
+	}
+}
+
+func TestExpandTargets_MixedHostsAndCIDRInOneRun(t *testing.T) {
+	ips, _, _, err := expandTargets([]string{"10.0.0.5", "10.0.1.0/30", "10.0.2.9"}, false, false)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	want := map[string]bool{"10.0.0.5": true, "10.0.1.1": true, "10.0.1.2": true, "10.0.2.9": true}
+	if len(ips) != len(want) {
+		t.Fatalf("got %d IPs, want %d: %v", len(ips), len(want), ips)
+	}
+	for _, ip := range ips {
+		if !want[ip.String()] {
+			t.Errorf("unexpected IP %s in result", ip)
+		}
+	}
+}
+
+// TestExpandTargets_OverlappingCIDRsScanEachHostOnce confirms a host
+// covered by two overlapping CIDR blocks (or named directly inside one of
+// them) appears exactly once in the expanded list, rather than once per
+// spec that happens to cover it.
+func TestExpandTargets_OverlappingCIDRsScanEachHostOnce(t *testing.T) {
+	ips, explicit, _, err := expandTargets([]string{"10.0.0.0/24", "10.0.0.0/25", "10.0.0.10"}, false, false)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	seen := map[string]int{}
+	for _, ip := range ips {
+		seen[ip.String()]++
+	}
+	for ip, count := range seen {
+		if count != 1 {
+			t.Errorf("%s appeared %d times, want exactly once", ip, count)
+		}
+	}
+	if seen["10.0.0.10"] != 1 {
+		t.Errorf("10.0.0.10 (named directly, and covered by both CIDRs) appeared %d times, want 1", seen["10.0.0.10"])
+	}
+	// 10.0.0.0/24 minus network/broadcast is 254 hosts; /25 contributes no
+	// new ones since every address in it is already in /24.
+	if len(ips) != 254 {
+		t.Errorf("got %d unique hosts, want 254", len(ips))
+	}
+	if !explicit["10.0.0.10"] {
+		t.Errorf("10.0.0.10 should still be marked explicit even though CIDR expansion saw it first or second")
+	}
+}
+
+// TestExpandTargets_AliasesRecordEveryHostnameForAnIP confirms that when
+// multiple hostname specs resolve to the same address (simulated here via
+// expandTargets's internal add/aliases bookkeeping through two literal IP
+// specs that happen to collide, since real DNS isn't available in tests),
+// expandTargets still reports each unique IP exactly once.
+func TestExpandTargets_AliasesRecordEveryHostnameForAnIP(t *testing.T) {
+	ips, _, aliases, err := expandTargets([]string{"10.0.0.5", "10.0.0.5"}, false, false)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(ips) != 1 {
+		t.Fatalf("got %d IPs for a repeated literal, want 1: %v", len(ips), ips)
+	}
+	// A literal IP spec (as opposed to a hostname) never contributes an
+	// alias: the address already speaks for itself.
+	if len(aliases["10.0.0.5"]) != 0 {
+		t.Errorf("aliases[10.0.0.5] = %v, want none for a literal IP spec", aliases["10.0.0.5"])
+	}
+}
+
+func TestExpandRange(t *testing.T) {
+	cases := []struct {
+		spec    string
+		want    []string
+		wantErr bool
+	}{
+		{"10.0.0.1-10.0.0.3", []string{"10.0.0.1", "10.0.0.2", "10.0.0.3"}, false},
+		{"10.0.0.253-255", []string{"10.0.0.253", "10.0.0.254", "10.0.0.255"}, false},
+		{"10.0.0.3-10.0.0.1", nil, true},
+		{"not-an-ip-1", nil, true},
+		{"10.0.0.1-999", nil, true},
+	}
+	for _, c := range cases {
+		got, err := expandRange(c.spec)
+		if c.wantErr {
+			if err == nil {
+				t.Errorf("expandRange(%q): expected error, got none", c.spec)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("expandRange(%q): unexpected error: %v", c.spec, err)
+			continue
+		}
+		var gotStrs []string
+		for _, ip := range got {
+			gotStrs = append(gotStrs, ip.String())
+		}
+		if !reflect.DeepEqual(gotStrs, c.want) {
+			t.Errorf("expandRange(%q) = %v, want %v", c.spec, gotStrs, c.want)
+		}
+	}
+}
+
+func TestIsOctetRange(t *testing.T) {
+	cases := []struct {
+		spec string
+		want bool
+	}{
+		{"10.0.0-2.1-254", true},
+		{"192.168.1-2.0", true},
+		{"10.0.0.5", false},       // plain literal, no range anywhere
+		{"10.0.0.1-50", false},    // isDashedIPRange's shorthand, not this syntax
+		{"10.0.0.1-10.0.0.3", false},
+		{"web-01.internal", false},
+		{"10.0.0.256-260", false}, // out of range
+		{"10.0.0", false},
+	}
+	for _, c := range cases {
+		if got := isOctetRange(c.spec); got != c.want {
+			t.Errorf("isOctetRange(%q) = %v, want %v", c.spec, got, c.want)
+		}
+	}
+}
+
+func TestExpandOctetRange(t *testing.T) {
+	cases := []struct {
+		spec    string
+		want    []string
+		wantErr bool
+	}{
+		{"192.168.1.1-3", []string{"192.168.1.1", "192.168.1.2", "192.168.1.3"}, false},
+		{"10.0.0-1.5", []string{"10.0.0.5", "10.0.1.5"}, false},
+		{"10.0.0-2.254-255", []string{
+			"10.0.0.254", "10.0.0.255",
+			"10.0.1.254", "10.0.1.255",
+			"10.0.2.254", "10.0.2.255",
+		}, false},
+		{"10.0.0.3-1", nil, true},    // start after end
+		{"10.0.0.256-260", nil, true}, // octet out of range
+		{"10.0.0", nil, true},         // not four fields
+	}
+	for _, c := range cases {
+		got, err := expandOctetRange(c.spec)
+		if c.wantErr {
+			if err == nil {
+				t.Errorf("expandOctetRange(%q): expected error, got none", c.spec)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("expandOctetRange(%q): unexpected error: %v", c.spec, err)
+			continue
+		}
+		var gotStrs []string
+		for _, ip := range got {
+			gotStrs = append(gotStrs, ip.String())
+		}
+		if !reflect.DeepEqual(gotStrs, c.want) {
+			t.Errorf("expandOctetRange(%q) = %v, want %v", c.spec, gotStrs, c.want)
+		}
+	}
+}
+
+func TestExpandTargets_MultiOctetRange(t *testing.T) {
+	ips, _, _, err := expandTargets([]string{"10.0.0-1.1-2"}, false, false)
+	if err != nil {
+		t.Fatalf("expandTargets: %v", err)
+	}
+	want := []string{"10.0.0.1", "10.0.0.2", "10.0.1.1", "10.0.1.2"}
+	var got []string
+	for _, ip := range ips {
+		got = append(got, ip.String())
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("expandTargets(%q) = %v, want %v", "10.0.0-1.1-2", got, want)
+	}
+}
+
+func TestExpandCIDR(t *testing.T) {
+	cases := []struct {
+		name string
+		cidr string
+		want []string
+	}{
+		{
+			name: "/30 drops network and broadcast",
+			cidr: "10.0.0.0/30",
+			want: []string{"10.0.0.1", "10.0.0.2"},
+		},
+		{
+			name: "/24 drops .0 and .255",
+			cidr: "10.0.1.0/24",
+			want: ipRangeStrs("10.0.1.1", "10.0.1.254"),
+		},
+		{
+			name: "/31 keeps both addresses (RFC 3021 point-to-point link)",
+			cidr: "10.0.0.4/31",
+			want: []string{"10.0.0.4", "10.0.0.5"},
+		},
+		{
+			name: "/32 keeps the single address",
+			cidr: "10.0.0.9/32",
+			want: []string{"10.0.0.9"},
+		},
+	}
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			ips, err := expandCIDR(c.cidr, false)
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			var got []string
+			for _, ip := range ips {
+				got = append(got, ip.String())
+			}
+			if !reflect.DeepEqual(got, c.want) {
+				t.Errorf("expandCIDR(%q) = %v, want %v", c.cidr, got, c.want)
+			}
+		})
+	}
+}
+
+// ipRangeStrs builds the inclusive list of dotted-quad strings between two
+// IPv4 addresses in the same /24, for TestExpandCIDR's /24 case.
+func ipRangeStrs(start, end string) []string {
+	s := net.ParseIP(start).To4()
+	e := net.ParseIP(end).To4()
+	var out []string
+	for cur := cloneIP(s); ; incIP(cur) {
+		out = append(out, cur.String())
+		if cur.Equal(e) {
+			break
+		}
+	}
+	return out
+}
+
+// TestExpandCIDR_IncludeNetworkBroadcastOverride confirms the
+// includeNetworkBroadcast flag restores the naive behavior of enumerating
+// every address in the block, network and broadcast included.
+func TestExpandCIDR_IncludeNetworkBroadcastOverride(t *testing.T) {
+	ips, err := expandCIDR("10.0.0.0/30", true)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	want := []string{"10.0.0.0", "10.0.0.1", "10.0.0.2", "10.0.0.3"}
+	var got []string
+	for _, ip := range ips {
+		got = append(got, ip.String())
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("expandCIDR(10.0.0.0/30, true) = %v, want %v", got, want)
+	}
+}
+
+func TestIpToUint32(t *testing.T) {
+	cases := []struct {
+		ip   string
+		want uint32
+	}{
+		{"0.0.0.1", 1},
+		{"10.0.0.1", 10<<24 | 1},
+		{"255.255.255.255", 0xffffffff},
+	}
+	for _, c := range cases {
+		got := ipToUint32(net.ParseIP(c.ip))
+		if got != c.want {
+			t.Errorf("ipToUint32(%q) = %d, want %d", c.ip, got, c.want)
+		}
+	}
+}
+
+func TestParsePortList(t *testing.T) {
+	got, err := parsePortList("22,80,8000-8002,80")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	want := []int{22, 80, 8000, 8001, 8002}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("parsePortList = %v, want %v", got, want)
+	}
+
+	if _, err := parsePortList("not-a-port"); err == nil {
+		t.Error("expected error for invalid port, got nil")
+	}
+}
+
+func TestParseHostSet(t *testing.T) {
+	set, err := parseHostSet("10.0.0.1, 10.0.0.2")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if !set["10.0.0.1"] || !set["10.0.0.2"] {
+		t.Errorf("parseHostSet missing expected entries: %v", set)
+	}
+}
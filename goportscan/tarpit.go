@@ -0,0 +1,31 @@
+package main
+
+import (
+	"net"
+	"time"
+)
+
+// tarpitProbePorts are high, rarely-assigned ports a normal host has almost
+// certainly never bound. If most of them answer open anyway, that's a
+// strong signal the host isn't really listening there at all -- it's a
+// tarpit, a misconfigured load balancer, or some other always-open
+// responder that would otherwise make every port in the real scan look
+// open too.
+var tarpitProbePorts = []int{23517, 34591, 45678, 56789, 61234}
+
+// detectTarpit samples tarpitProbePorts on ip and reports whether the
+// fraction that came back open meets or exceeds threshold (-tarpit-threshold),
+// along with that observed fraction for the warning message. probe is
+// injected rather than calling connectProbe directly so a test can fake a
+// listener that accepts every connection without needing real open ports.
+func detectTarpit(probe func(addr Addr, timeout time.Duration) (portState, error), ip net.IP, timeout time.Duration, threshold float64) (isTarpit bool, fraction float64) {
+	var open int
+	for _, port := range tarpitProbePorts {
+		state, _ := probe(Addr{IP: ip, Port: port}, timeout)
+		if state == portOpen {
+			open++
+		}
+	}
+	fraction = float64(open) / float64(len(tarpitProbePorts))
+	return fraction >= threshold, fraction
+}
@@ -0,0 +1,100 @@
+package main
+
+import (
+	"encoding/json"
+	"net"
+	"testing"
+	"time"
+)
+
+// acceptAllProbe fakes a listener that answers open on every port, the
+// tarpit/load-balancer behavior detectTarpit exists to catch.
+func acceptAllProbe(Addr, time.Duration) (portState, error) {
+	return portOpen, nil
+}
+
+// refuseAllProbe fakes a normal host where every sampled port is closed, as
+// detectTarpit should expect for anything not actually a tarpit.
+func refuseAllProbe(Addr, time.Duration) (portState, error) {
+	return portClosed, nil
+}
+
+func TestDetectTarpit_FlagsHostThatAcceptsEveryPort(t *testing.T) {
+	isTarpit, fraction := detectTarpit(acceptAllProbe, net.ParseIP("10.0.0.1"), time.Millisecond, 0.8)
+	if !isTarpit {
+		t.Error("detectTarpit = false, want true for a host that answers open on every sampled port")
+	}
+	if fraction != 1.0 {
+		t.Errorf("fraction = %v, want 1.0", fraction)
+	}
+}
+
+func TestDetectTarpit_DoesNotFlagNormalHost(t *testing.T) {
+	isTarpit, fraction := detectTarpit(refuseAllProbe, net.ParseIP("10.0.0.1"), time.Millisecond, 0.8)
+	if isTarpit {
+		t.Error("detectTarpit = true, want false for a host where every sampled port is closed")
+	}
+	if fraction != 0 {
+		t.Errorf("fraction = %v, want 0", fraction)
+	}
+}
+
+func TestDetectTarpit_RespectsThreshold(t *testing.T) {
+	var calls int
+	// Two of five sampled ports come back open: a 40% hit rate.
+	mixedProbe := func(Addr, time.Duration) (portState, error) {
+		calls++
+		if calls <= 2 {
+			return portOpen, nil
+		}
+		return portClosed, nil
+	}
+
+	if isTarpit, fraction := detectTarpit(mixedProbe, net.ParseIP("10.0.0.1"), time.Millisecond, 0.8); isTarpit {
+		t.Errorf("detectTarpit = true at threshold 0.8 with fraction %v, want false", fraction)
+	}
+
+	calls = 0
+	if isTarpit, fraction := detectTarpit(mixedProbe, net.ParseIP("10.0.0.1"), time.Millisecond, 0.3); !isTarpit {
+		t.Errorf("detectTarpit = false at threshold 0.3 with fraction %v, want true", fraction)
+	}
+}
+
+func TestToScanResult_AnnotatesTarpitHosts(t *testing.T) {
+	outcome := scanOutcome{Addr: Addr{IP: net.ParseIP("10.0.0.1"), Port: 22}, State: portOpen}
+	tarpitHosts := map[string]bool{"10.0.0.1": true}
+
+	r := toScanResult(outcome, ServiceInfo{}, TLSInfo{}, false, HTTPInfo{}, false, DetectResult{}, false, LBDetectResult{}, false, 0, false, tarpitHosts)
+	if r.Note == "" {
+		t.Error("Note is empty, want a tarpit warning for a flagged host")
+	}
+
+	clean := toScanResult(outcome, ServiceInfo{}, TLSInfo{}, false, HTTPInfo{}, false, DetectResult{}, false, LBDetectResult{}, false, 0, false, map[string]bool{})
+	if clean.Note != "" {
+		t.Errorf("Note = %q, want empty for a host that wasn't flagged", clean.Note)
+	}
+}
+
+// TestToScanResult_StampsSchemaVersion confirms every ScanResult toScanResult
+// builds -- the single place every JSON/JSONL/push sink's result document
+// comes from -- carries the current ResultSchemaVersion, so a downstream
+// consumer can assert compatibility against it.
+func TestToScanResult_StampsSchemaVersion(t *testing.T) {
+	outcome := scanOutcome{Addr: Addr{IP: net.ParseIP("10.0.0.1"), Port: 22}, State: portOpen}
+	r := toScanResult(outcome, ServiceInfo{}, TLSInfo{}, false, HTTPInfo{}, false, DetectResult{}, false, LBDetectResult{}, false, 0, false, nil)
+	if r.SchemaVersion != ResultSchemaVersion {
+		t.Errorf("SchemaVersion = %q, want %q", r.SchemaVersion, ResultSchemaVersion)
+	}
+
+	encoded, err := json.Marshal(r)
+	if err != nil {
+		t.Fatalf("marshal: %v", err)
+	}
+	var decoded map[string]any
+	if err := json.Unmarshal(encoded, &decoded); err != nil {
+		t.Fatalf("unmarshal: %v", err)
+	}
+	if decoded["schema_version"] != ResultSchemaVersion {
+		t.Errorf(`decoded["schema_version"] = %v, want %q`, decoded["schema_version"], ResultSchemaVersion)
+	}
+}
@@ -0,0 +1,62 @@
+package main
+
+import "sync"
+
+// hostTimeoutAbort tracks, per host, a running streak of consecutive dial
+// timeouts, and which hosts have already been given up on as a result. It's
+// the softer sibling of hostShortCircuit: that one bails on explicit
+// ENETUNREACH/EHOSTUNREACH errors, while this one catches a host that's
+// simply gone quiet -- every dial past some point just silently drops,
+// usually meaning the scan has wandered into a filtered or black-holed
+// range. threshold is -max-consecutive-timeouts; unlike
+// hostShortCircuitThreshold it's configurable rather than fixed, since
+// there's no single streak length that reads as "filtered range" the way
+// there is for "this host rejects every route".
+type hostTimeoutAbort struct {
+	threshold int
+
+	mu      sync.Mutex
+	streak  map[string]int
+	aborted map[string]bool
+}
+
+func newHostTimeoutAbort(threshold int) *hostTimeoutAbort {
+	return &hostTimeoutAbort{threshold: threshold, streak: map[string]int{}, aborted: map[string]bool{}}
+}
+
+// Record tallies one dial's outcome for host: a timeout extends its streak,
+// and a response -- open or closed, the port actually answering -- resets it
+// to zero. Anything else (an explicit error, a non-timeout filtered result)
+// leaves the streak untouched, since only a run of silent drops, not one
+// ambiguous result mixed in, should count toward the abort. It reports true
+// exactly once per host, on the dial that first crosses threshold, so the
+// caller emits that host's not-scanned result exactly once instead of on
+// every dial afterward.
+func (h *hostTimeoutAbort) Record(host string, responded, timedOut bool) (justAborted bool) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	if h.aborted[host] {
+		return false
+	}
+	if responded {
+		h.streak[host] = 0
+		return false
+	}
+	if !timedOut {
+		return false
+	}
+	h.streak[host]++
+	if h.streak[host] < h.threshold {
+		return false
+	}
+	h.aborted[host] = true
+	return true
+}
+
+// Aborted reports whether host has already been given up on, so the feeder
+// goroutine queueing its remaining ports can stop early.
+func (h *hostTimeoutAbort) Aborted(host string) bool {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	return h.aborted[host]
+}
@@ -0,0 +1,80 @@
+package main
+
+import "testing"
+
+// TestHostTimeoutAbort_AbortsAfterConsecutiveTimeouts simulates a host that
+// answers its first few ports normally, then goes silent partway through --
+// the filtered/black-holed range -max-consecutive-timeouts exists to cut
+// short -- and confirms it aborts exactly once, on the dial that crosses the
+// threshold, and reports the host as aborted from then on.
+func TestHostTimeoutAbort_AbortsAfterConsecutiveTimeouts(t *testing.T) {
+	hta := newHostTimeoutAbort(3)
+	const host = "10.0.0.5"
+
+	// A couple of ports respond normally before the host goes quiet.
+	hta.Record(host, true, false)
+	hta.Record(host, true, false)
+
+	abortedCount := 0
+	for i := 0; i < 6; i++ {
+		if hta.Record(host, false, true) {
+			abortedCount++
+		}
+	}
+	if abortedCount != 1 {
+		t.Errorf("Record reported justAborted %d times, want exactly 1", abortedCount)
+	}
+	if !hta.Aborted(host) {
+		t.Error("Aborted(host) = false after crossing the threshold, want true")
+	}
+}
+
+func TestHostTimeoutAbort_ResponseResetsStreak(t *testing.T) {
+	hta := newHostTimeoutAbort(3)
+	const host = "10.0.0.5"
+
+	hta.Record(host, false, true)
+	hta.Record(host, false, true)
+	hta.Record(host, true, false) // an open/closed port breaks the streak
+
+	for i := 0; i < 2; i++ {
+		if hta.Record(host, false, true) {
+			t.Fatalf("Record aborted at dial %d after the streak reset, want it to need a fresh run of 3", i)
+		}
+	}
+	if hta.Aborted(host) {
+		t.Error("Aborted(host) = true, want false: the streak never reached the threshold uninterrupted")
+	}
+}
+
+// TestHostTimeoutAbort_IgnoresAmbiguousResults confirms a result that's
+// neither a response nor a timeout (some other dial error) doesn't count
+// toward the streak either way, since only a genuine run of silent drops
+// should trigger the abort.
+func TestHostTimeoutAbort_IgnoresAmbiguousResults(t *testing.T) {
+	hta := newHostTimeoutAbort(2)
+	const host = "10.0.0.5"
+
+	hta.Record(host, false, true)
+	if hta.Record(host, false, false) {
+		t.Fatal("Record aborted on an ambiguous (non-timeout, non-response) result, want it ignored")
+	}
+	if hta.Aborted(host) {
+		t.Fatal("Aborted(host) = true, want false: the ambiguous result shouldn't have extended the streak")
+	}
+	if hta.Record(host, false, true) != true {
+		t.Error("Record = false, want true: the prior timeout streak should still be intact after the ignored result")
+	}
+}
+
+func TestHostTimeoutAbort_TracksHostsIndependently(t *testing.T) {
+	hta := newHostTimeoutAbort(2)
+	hta.Record("10.0.0.1", false, true)
+	hta.Record("10.0.0.1", false, true)
+	if !hta.Aborted("10.0.0.1") {
+		t.Error("Aborted(10.0.0.1) = false, want true")
+	}
+	if hta.Aborted("10.0.0.2") {
+		t.Error("Aborted(10.0.0.2) = true, want false: its own streak never ran")
+	}
+}
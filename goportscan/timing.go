@@ -0,0 +1,63 @@
+package main
+
+import (
+	"fmt"
+	"time"
+)
+
+// timingTemplate is one of nmap's -T0 through -T5 presets, translated to
+// this scanner's own knobs: NumWorkers (-workers), the per-dial timeout
+// (-timeout), the concurrency ceiling (-max-concurrency), and -retries.
+type timingTemplate struct {
+	name           string
+	workers        int
+	timeout        time.Duration
+	maxConcurrency int
+	retries        int
+}
+
+// timingTemplates is indexed by the -T value (0-5). Paranoid (T0) trades
+// almost all speed for stealth; insane (T5) is the opposite trade, tuned for
+// a fast link where missed ports from a too-short timeout are acceptable.
+// Normal (T3) matches this scanner's own pre-existing defaults, so -T3 is a
+// no-op next to not passing -T at all.
+var timingTemplates = [...]timingTemplate{
+	0: {name: "paranoid", workers: 1, timeout: 5 * time.Second, maxConcurrency: 1, retries: 2},
+	1: {name: "sneaky", workers: 5, timeout: 3 * time.Second, maxConcurrency: 5, retries: 2},
+	2: {name: "polite", workers: 10, timeout: 1500 * time.Millisecond, maxConcurrency: 10, retries: 1},
+	3: {name: "normal", workers: maxInFlight, timeout: 500 * time.Millisecond, maxConcurrency: maxInFlight, retries: 1},
+	4: {name: "aggressive", workers: 500, timeout: 250 * time.Millisecond, maxConcurrency: 500, retries: 1},
+	5: {name: "insane", workers: maxInFlight, timeout: 75 * time.Millisecond, maxConcurrency: maxInFlight, retries: 0},
+}
+
+// helpText renders every template's concrete settings for -help, e.g.
+// "  -T0 paranoid:    1 worker(s), timeout 5s, max-concurrency 1, retries 2".
+func (t timingTemplate) helpLine(level int) string {
+	return fmt.Sprintf("  -T%d %-11s %d worker(s), timeout %s, max-concurrency %d, retries %d",
+		level, t.name+":", t.workers, t.timeout, t.maxConcurrency, t.retries)
+}
+
+// applyTimingTemplate fills in workers/timeout/maxConcurrency/retries from
+// the -T level, skipping any of them the user also passed explicitly --
+// explicit, keyed by flag name ("workers", "timeout", "max-concurrency",
+// "retries"), comes from flag.Visit, which only reports flags actually
+// present on the command line.
+func applyTimingTemplate(level int, explicit map[string]bool, workers *int, timeout *time.Duration, maxConcurrency *int, retries *int) error {
+	if level < 0 || level >= len(timingTemplates) {
+		return fmt.Errorf("-T must be 0-%d", len(timingTemplates)-1)
+	}
+	tmpl := timingTemplates[level]
+	if !explicit["workers"] {
+		*workers = tmpl.workers
+	}
+	if !explicit["timeout"] {
+		*timeout = tmpl.timeout
+	}
+	if !explicit["max-concurrency"] {
+		*maxConcurrency = tmpl.maxConcurrency
+	}
+	if !explicit["retries"] {
+		*retries = tmpl.retries
+	}
+	return nil
+}
@@ -0,0 +1,74 @@
+package main
+
+import (
+	"context"
+	"crypto/tls"
+	"time"
+)
+
+// tlsALPNProtocols is offered on every -tls handshake via NextProtos, so
+// probeTLSCert can report which one (if either) the server picked --
+// "h2" marks an HTTP/2-capable backend, "http/1.1" or no match at all
+// (NegotiatedProtocol left empty) means it isn't, or doesn't speak ALPN.
+var tlsALPNProtocols = []string{"h2", "http/1.1"}
+
+// TLSInfo is what probeTLSCert managed to learn from a TLS handshake with
+// an open port: the leaf certificate's CN, SANs, and expiry, the ALPN
+// protocol negotiated (if any), and -- only when probeTLSCert was given a
+// -sni ServerName to test -- whether the certificate actually matched it.
+type TLSInfo struct {
+	CommonName         string
+	SANs               []string
+	NotAfter           time.Time
+	NegotiatedProtocol string
+	SNIMatched         *bool
+}
+
+// probeTLSCert attempts a TLS handshake against addr, offering serverName
+// as SNI (empty means none, the ordinary case of scanning by IP) and h2/
+// http/1.1 as ALPN candidates, and if the handshake succeeds returns the
+// leaf certificate's CN, SANs, and expiry, the protocol ALPN settled on,
+// and -- when serverName was given -- whether the certificate actually
+// covers it. InsecureSkipVerify is intentional: this is reconnaissance, not
+// a client trusting the server, so a self-signed or expired cert shouldn't
+// abort the probe -- it's exactly the kind of cert worth reporting on. A
+// handshake that fails (plain TCP service, reset connection, protocol
+// mismatch, or a server that rejects an unrecognized SNI outright) just
+// reports false; it is not an error, since "this open port doesn't speak
+// TLS" -- or doesn't for that SNI -- is a completely ordinary outcome of
+// probing it.
+func probeTLSCert(addr Addr, timeout time.Duration, serverName string) (TLSInfo, bool) {
+	conn, err := dialTCP(context.Background(), addr.Dial(), timeout)
+	if err != nil {
+		return TLSInfo{}, false
+	}
+	defer conn.Close()
+
+	conn.SetDeadline(time.Now().Add(timeout))
+	tlsConn := tls.Client(conn, &tls.Config{
+		InsecureSkipVerify: true,
+		ServerName:         serverName,
+		NextProtos:         tlsALPNProtocols,
+	})
+	if err := tlsConn.Handshake(); err != nil {
+		return TLSInfo{}, false
+	}
+
+	state := tlsConn.ConnectionState()
+	certs := state.PeerCertificates
+	if len(certs) == 0 {
+		return TLSInfo{}, false
+	}
+	leaf := certs[0]
+	info := TLSInfo{
+		CommonName:         leaf.Subject.CommonName,
+		SANs:               leaf.DNSNames,
+		NotAfter:           leaf.NotAfter,
+		NegotiatedProtocol: state.NegotiatedProtocol,
+	}
+	if serverName != "" {
+		matched := leaf.VerifyHostname(serverName) == nil
+		info.SNIMatched = &matched
+	}
+	return info, true
+}
@@ -0,0 +1,164 @@
+package main
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"math/big"
+	"net"
+	"testing"
+	"time"
+)
+
+// selfSignedCert builds a throwaway cert/key pair for standing up a test TLS
+// server, with the CN and SANs probeTLSCert is expected to report back.
+func selfSignedCert(t *testing.T, cn string, sans []string) tls.Certificate {
+	t.Helper()
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatalf("generate key: %v", err)
+	}
+	template := x509.Certificate{
+		SerialNumber: big.NewInt(1),
+		Subject:      pkix.Name{CommonName: cn},
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(24 * time.Hour),
+		DNSNames:     sans,
+	}
+	der, err := x509.CreateCertificate(rand.Reader, &template, &template, &key.PublicKey, key)
+	if err != nil {
+		t.Fatalf("create certificate: %v", err)
+	}
+	return tls.Certificate{Certificate: [][]byte{der}, PrivateKey: key}
+}
+
+func TestProbeTLSCert_ReportsCertDetails(t *testing.T) {
+	cert := selfSignedCert(t, "scanme.example", []string{"scanme.example", "alt.example"})
+
+	ln, err := tls.Listen("tcp", "127.0.0.1:0", &tls.Config{Certificates: []tls.Certificate{cert}})
+	if err != nil {
+		t.Fatalf("listen: %v", err)
+	}
+	defer ln.Close()
+	go func() {
+		conn, err := ln.Accept()
+		if err != nil {
+			return
+		}
+		defer conn.Close()
+		conn.(*tls.Conn).Handshake()
+	}()
+
+	port := ln.Addr().(*net.TCPAddr).Port
+	info, ok := probeTLSCert(Addr{IP: net.ParseIP("127.0.0.1"), Port: port}, time.Second, "")
+	if !ok {
+		t.Fatal("probeTLSCert on a TLS listener = false, want true")
+	}
+	if info.CommonName != "scanme.example" {
+		t.Errorf("CommonName = %q, want %q", info.CommonName, "scanme.example")
+	}
+	wantSANs := []string{"scanme.example", "alt.example"}
+	if len(info.SANs) != len(wantSANs) || info.SANs[0] != wantSANs[0] || info.SANs[1] != wantSANs[1] {
+		t.Errorf("SANs = %v, want %v", info.SANs, wantSANs)
+	}
+	if !info.NotAfter.After(time.Now()) {
+		t.Errorf("NotAfter = %s, want a future time", info.NotAfter)
+	}
+	if info.SNIMatched != nil {
+		t.Errorf("SNIMatched = %v, want nil: no -sni was given", info.SNIMatched)
+	}
+}
+
+func TestProbeTLSCert_NegotiatesALPN(t *testing.T) {
+	cert := selfSignedCert(t, "scanme.example", []string{"scanme.example"})
+
+	ln, err := tls.Listen("tcp", "127.0.0.1:0", &tls.Config{
+		Certificates: []tls.Certificate{cert},
+		NextProtos:   []string{"h2"},
+	})
+	if err != nil {
+		t.Fatalf("listen: %v", err)
+	}
+	defer ln.Close()
+	go func() {
+		conn, err := ln.Accept()
+		if err != nil {
+			return
+		}
+		defer conn.Close()
+		conn.(*tls.Conn).Handshake()
+	}()
+
+	port := ln.Addr().(*net.TCPAddr).Port
+	info, ok := probeTLSCert(Addr{IP: net.ParseIP("127.0.0.1"), Port: port}, time.Second, "")
+	if !ok {
+		t.Fatal("probeTLSCert on an h2 listener = false, want true")
+	}
+	if info.NegotiatedProtocol != "h2" {
+		t.Errorf("NegotiatedProtocol = %q, want h2", info.NegotiatedProtocol)
+	}
+}
+
+func TestProbeTLSCert_ReportsWhetherSNIMatchedTheCert(t *testing.T) {
+	cert := selfSignedCert(t, "scanme.example", []string{"scanme.example"})
+
+	ln, err := tls.Listen("tcp", "127.0.0.1:0", &tls.Config{Certificates: []tls.Certificate{cert}})
+	if err != nil {
+		t.Fatalf("listen: %v", err)
+	}
+	defer ln.Close()
+	go func() {
+		for {
+			conn, err := ln.Accept()
+			if err != nil {
+				return
+			}
+			go func() {
+				defer conn.Close()
+				conn.(*tls.Conn).Handshake()
+			}()
+		}
+	}()
+	port := ln.Addr().(*net.TCPAddr).Port
+
+	info, ok := probeTLSCert(Addr{IP: net.ParseIP("127.0.0.1"), Port: port}, time.Second, "scanme.example")
+	if !ok {
+		t.Fatal("probeTLSCert with a matching SNI = false, want true")
+	}
+	if info.SNIMatched == nil || !*info.SNIMatched {
+		t.Errorf("SNIMatched = %v, want true for scanme.example", info.SNIMatched)
+	}
+
+	info, ok = probeTLSCert(Addr{IP: net.ParseIP("127.0.0.1"), Port: port}, time.Second, "other.example")
+	if !ok {
+		t.Fatal("probeTLSCert with a mismatched SNI = false, want true (InsecureSkipVerify still completes the handshake)")
+	}
+	if info.SNIMatched == nil || *info.SNIMatched {
+		t.Errorf("SNIMatched = %v, want false for other.example", info.SNIMatched)
+	}
+}
+
+func TestProbeTLSCert_PlainTCPIsNotTLSNotError(t *testing.T) {
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("listen: %v", err)
+	}
+	defer ln.Close()
+	go func() {
+		conn, err := ln.Accept()
+		if err != nil {
+			return
+		}
+		defer conn.Close()
+		conn.Write([]byte("not tls\n"))
+	}()
+
+	port := ln.Addr().(*net.TCPAddr).Port
+	_, ok := probeTLSCert(Addr{IP: net.ParseIP("127.0.0.1"), Port: port}, time.Second, "")
+	if ok {
+		t.Error("probeTLSCert on a plain TCP listener = true, want false")
+	}
+}
@@ -0,0 +1,102 @@
+package main
+
+import (
+	"bufio"
+	_ "embed"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// topPortsData is a frequency-ordered list of commonly open TCP ports, most
+// common first, one per line (blank lines and "#" comments ignored). It's
+// embedded so -top-ports works out of the box without shipping a separate
+// data file alongside the binary.
+//
+//go:embed topports.txt
+var topPortsData string
+
+// topPorts returns the n most commonly open TCP ports, most common first.
+// If n is at least as large as the embedded list, the whole list is
+// returned (parsePortList callers elsewhere in this package error out on an
+// out-of-range port; this instead just caps silently, since "give me the
+// top 5000" on a ~250-port list has an obvious, harmless interpretation).
+func topPorts(n int) ([]int, error) {
+	all, err := parseTopPortsData()
+	if err != nil {
+		return nil, err
+	}
+	if n < len(all) {
+		all = all[:n]
+	}
+	return all, nil
+}
+
+// parseTopPortsData reads every entry out of the embedded frequency list,
+// most common first, deduplicated -- the full ordering topPorts slices from
+// and portFrequencyRank ranks by.
+func parseTopPortsData() ([]int, error) {
+	seen := make(map[int]bool)
+	var ports []int
+
+	scanner := bufio.NewScanner(strings.NewReader(topPortsData))
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		p, err := strconv.Atoi(line)
+		if err != nil {
+			return nil, fmt.Errorf("embedded top-ports list: invalid entry %q: %w", line, err)
+		}
+		if seen[p] {
+			continue
+		}
+		seen[p] = true
+		ports = append(ports, p)
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("embedded top-ports list: %w", err)
+	}
+	return ports, nil
+}
+
+// portFrequencyRank maps each port in the embedded frequency list to its
+// rank (0 = most commonly open), for -by-frequency to reorder a scan's full
+// port range by real-world likelihood instead of just truncating it to a
+// handful the way -top-ports does.
+func portFrequencyRank() (map[int]int, error) {
+	ordered, err := parseTopPortsData()
+	if err != nil {
+		return nil, err
+	}
+	rank := make(map[int]int, len(ordered))
+	for i, p := range ordered {
+		rank[p] = i
+	}
+	return rank, nil
+}
+
+// sortPortsByFrequency reorders ports by descending open-frequency
+// likelihood using rank (ports not present in rank sort after every ranked
+// one), so the most commonly-open ports dispatch first. It's a stable sort,
+// so ports tied on rank -- in practice, every port absent from rank -- keep
+// their existing relative order instead of being shuffled.
+func sortPortsByFrequency(ports []int, rank map[int]int) []int {
+	out := make([]int, len(ports))
+	copy(out, ports)
+	unranked := len(rank)
+	sort.SliceStable(out, func(i, j int) bool {
+		ri, oki := rank[out[i]]
+		rj, okj := rank[out[j]]
+		if !oki {
+			ri = unranked
+		}
+		if !okj {
+			rj = unranked
+		}
+		return ri < rj
+	})
+	return out
+}
@@ -0,0 +1,88 @@
+package main
+
+import "testing"
+
+func TestTopPorts(t *testing.T) {
+	got, err := topPorts(5)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(got) != 5 {
+		t.Fatalf("topPorts(5) returned %d ports, want 5: %v", len(got), got)
+	}
+	if got[0] != 80 {
+		t.Errorf("topPorts(5)[0] = %d, want 80 (the most common port)", got[0])
+	}
+
+	seen := make(map[int]bool)
+	for _, p := range got {
+		if seen[p] {
+			t.Errorf("topPorts(5) contains duplicate port %d", p)
+		}
+		seen[p] = true
+	}
+}
+
+func TestPortFrequencyRank_MostCommonPortRanksZero(t *testing.T) {
+	rank, err := portFrequencyRank()
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if rank[80] != 0 {
+		t.Errorf("rank[80] = %d, want 0 (the most common port)", rank[80])
+	}
+}
+
+func TestSortPortsByFrequency_OrdersKnownPortsByRank(t *testing.T) {
+	rank := map[int]int{22: 1, 80: 0, 443: 2}
+	got := sortPortsByFrequency([]int{443, 22, 80}, rank)
+	want := []int{80, 22, 443}
+	for i := range want {
+		if got[i] != want[i] {
+			t.Errorf("sortPortsByFrequency = %v, want %v", got, want)
+			break
+		}
+	}
+}
+
+func TestSortPortsByFrequency_UnrankedPortsGoLastInOriginalOrder(t *testing.T) {
+	rank := map[int]int{80: 0}
+	got := sortPortsByFrequency([]int{9999, 80, 5555}, rank)
+	want := []int{80, 9999, 5555}
+	for i := range want {
+		if got[i] != want[i] {
+			t.Errorf("sortPortsByFrequency = %v, want %v", got, want)
+			break
+		}
+	}
+}
+
+func TestSortPortsByFrequency_LeavesInputSliceUntouched(t *testing.T) {
+	ports := []int{443, 80}
+	original := append([]int(nil), ports...)
+	sortPortsByFrequency(ports, map[int]int{80: 0, 443: 1})
+	for i := range original {
+		if ports[i] != original[i] {
+			t.Errorf("sortPortsByFrequency mutated its input: got %v, want unchanged %v", ports, original)
+			break
+		}
+	}
+}
+
+func TestTopPorts_CapsAtListLength(t *testing.T) {
+	all, err := topPorts(1 << 20)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(all) == 0 {
+		t.Fatal("topPorts with a huge N returned no ports")
+	}
+
+	again, err := topPorts(1 << 20)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(again) != len(all) {
+		t.Errorf("topPorts(huge N) = %d ports, want the same %d both times", len(again), len(all))
+	}
+}
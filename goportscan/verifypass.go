@@ -0,0 +1,62 @@
+package main
+
+import (
+	"sync"
+	"time"
+)
+
+// verifyFilteredPorts re-probes each addr once more with a longer timeout,
+// the way -verify catches false negatives the first pass's high concurrency
+// introduces: a port that looked filtered under load may simply have had
+// its reply arrive too late, not have had no reply at all. It reuses the
+// same bounded worker concurrency as the first pass and returns just the
+// addrs that came back open this time.
+func verifyFilteredPorts(addrs []Addr, mode scanMode, syn synProber, timeout time.Duration, workers int) []Addr {
+	if len(addrs) == 0 {
+		return nil
+	}
+	if workers < 1 {
+		workers = 1
+	}
+	if workers > len(addrs) {
+		workers = len(addrs)
+	}
+
+	jobs := make(chan Addr, len(addrs))
+	for _, addr := range addrs {
+		jobs <- addr
+	}
+	close(jobs)
+
+	var mu sync.Mutex
+	var reclassified []Addr
+	var wg sync.WaitGroup
+	for i := 0; i < workers; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for addr := range jobs {
+				var state portState
+				switch mode {
+				case scanModeSYN:
+					if syn != nil {
+						state, _ = syn.Probe(addr, timeout)
+					} else {
+						state, _ = connectProbe(addr, timeout, 0)
+					}
+				case scanModeUDP:
+					state, _ = udpProbe(addr, timeout)
+				default:
+					state, _ = connectProbe(addr, timeout, 0)
+				}
+				if state == portOpen {
+					mu.Lock()
+					reclassified = append(reclassified, addr)
+					mu.Unlock()
+				}
+			}
+		}()
+	}
+	wg.Wait()
+	return reclassified
+}
@@ -0,0 +1,66 @@
+package main
+
+import (
+	"net"
+	"testing"
+	"time"
+)
+
+func TestVerifyFilteredPorts_ReclassifiesReachablePorts(t *testing.T) {
+	open, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("listen: %v", err)
+	}
+	defer open.Close()
+	go func() {
+		for {
+			conn, err := open.Accept()
+			if err != nil {
+				return
+			}
+			conn.Close()
+		}
+	}()
+
+	// Nothing is listening on this port, so it should come back as closed
+	// (a connect-mode RST), not open, and therefore not be reclassified.
+	closed, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("listen: %v", err)
+	}
+	closedAddrStr := closed.Addr().String()
+	closed.Close()
+
+	openAddr := addrFromListener(t, open)
+	closedAddr := parseAddr(t, closedAddrStr)
+
+	reclassified := verifyFilteredPorts([]Addr{openAddr, closedAddr}, scanModeConnect, nil, time.Second, 2)
+
+	if len(reclassified) != 1 || reclassified[0].Port != openAddr.Port {
+		t.Errorf("verifyFilteredPorts = %+v, want just the open port reclassified", reclassified)
+	}
+}
+
+func TestVerifyFilteredPorts_EmptyInputReturnsNilWithoutSpawningWorkers(t *testing.T) {
+	if got := verifyFilteredPorts(nil, scanModeConnect, nil, time.Second, 4); got != nil {
+		t.Errorf("verifyFilteredPorts(nil, ...) = %v, want nil", got)
+	}
+}
+
+func addrFromListener(t *testing.T, ln net.Listener) Addr {
+	t.Helper()
+	return parseAddr(t, ln.Addr().String())
+}
+
+func parseAddr(t *testing.T, hostport string) Addr {
+	t.Helper()
+	host, portStr, err := net.SplitHostPort(hostport)
+	if err != nil {
+		t.Fatalf("SplitHostPort(%s): %v", hostport, err)
+	}
+	port, err := net.LookupPort("tcp", portStr)
+	if err != nil {
+		t.Fatalf("LookupPort(%s): %v", portStr, err)
+	}
+	return Addr{IP: net.ParseIP(host), Port: port}
+}
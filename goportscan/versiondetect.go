@@ -0,0 +1,76 @@
+package main
+
+import (
+	"bufio"
+	"context"
+	"regexp"
+	"strings"
+	"time"
+)
+
+// versionDetectReadTimeout bounds how long probeRedisVersion's extra
+// connection waits for its INFO reply; ssh and http never open a new
+// connection for -version-detect, so they aren't affected by it.
+const versionDetectReadTimeout = 2 * time.Second
+
+var (
+	sshVersionRE  = regexp.MustCompile(`^(\S+?)_(\S+)`)
+	httpVersionRE = regexp.MustCompile(`^(\S+?)/(\S+)`)
+)
+
+// runVersionDetect is -version-detect's entry point: it refines info's
+// Version into a clean "Product X.Y.Z" string, running only the minimal
+// extra probe the already-identified service (info.Name) actually needs.
+// ssh and http already captured everything useful during identifyService's
+// normal banner/Server-header read, so this just reformats what's already
+// there rather than dialing again; redis needs one small extra probe (INFO)
+// since it never volunteers a banner on connect. Any other service, or one
+// whose Version didn't parse, is left alone.
+func runVersionDetect(addr Addr, info ServiceInfo, connectTimeout time.Duration) (string, bool) {
+	switch info.Name {
+	case "ssh":
+		if m := sshVersionRE.FindStringSubmatch(info.Version); m != nil {
+			return m[1] + " " + m[2], true
+		}
+	case "http":
+		if m := httpVersionRE.FindStringSubmatch(info.Version); m != nil {
+			return m[1] + " " + m[2], true
+		}
+	case "redis":
+		return probeRedisVersion(addr, connectTimeout)
+	}
+	return "", false
+}
+
+// probeRedisVersion sends a Redis INFO command and extracts the
+// redis_version field from its server section, e.g. "Redis 7.2.4".
+func probeRedisVersion(addr Addr, connectTimeout time.Duration) (string, bool) {
+	conn, err := dialTCP(context.Background(), addr.Dial(), connectTimeout)
+	if err != nil {
+		return "", false
+	}
+	defer conn.Close()
+	conn.SetDeadline(time.Now().Add(versionDetectReadTimeout))
+
+	if _, err := conn.Write([]byte("INFO\r\n")); err != nil {
+		return "", false
+	}
+
+	reader := bufio.NewReader(conn)
+	// The bulk-string reply starts with a "$<len>\r\n" header line; skip it
+	// and scan the body for "redis_version:...".
+	if _, err := reader.ReadString('\n'); err != nil {
+		return "", false
+	}
+	for {
+		line, err := reader.ReadString('\n')
+		line = strings.TrimSpace(line)
+		if strings.HasPrefix(line, "redis_version:") {
+			return "Redis " + strings.TrimPrefix(line, "redis_version:"), true
+		}
+		if err != nil {
+			break
+		}
+	}
+	return "", false
+}
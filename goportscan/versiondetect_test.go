@@ -0,0 +1,66 @@
+package main
+
+import (
+	"bufio"
+	"net"
+	"testing"
+	"time"
+)
+
+func TestRunVersionDetect_SSHCleansUnderscoreSeparatedBanner(t *testing.T) {
+	info := ServiceInfo{Name: "ssh", Version: "OpenSSH_8.9p1 Ubuntu-3ubuntu0.4"}
+	version, ok := runVersionDetect(Addr{}, info, time.Second)
+	if !ok {
+		t.Fatal("runVersionDetect: want ok=true for a parseable ssh version")
+	}
+	if want := "OpenSSH 8.9p1"; version != want {
+		t.Errorf("version = %q, want %q", version, want)
+	}
+}
+
+func TestRunVersionDetect_HTTPCleansSlashSeparatedServerHeader(t *testing.T) {
+	info := ServiceInfo{Name: "http", Version: "nginx/1.24.0"}
+	version, ok := runVersionDetect(Addr{}, info, time.Second)
+	if !ok {
+		t.Fatal("runVersionDetect: want ok=true for a parseable http version")
+	}
+	if want := "nginx 1.24.0"; version != want {
+		t.Errorf("version = %q, want %q", version, want)
+	}
+}
+
+func TestRunVersionDetect_UnknownServiceLeavesVersionAlone(t *testing.T) {
+	info := ServiceInfo{Name: "microsoft-ds"}
+	if _, ok := runVersionDetect(Addr{}, info, time.Second); ok {
+		t.Error("runVersionDetect: want ok=false for a service with no version-detect support")
+	}
+}
+
+func TestRunVersionDetect_Redis(t *testing.T) {
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("listen: %v", err)
+	}
+	defer ln.Close()
+	go func() {
+		conn, err := ln.Accept()
+		if err != nil {
+			return
+		}
+		defer conn.Close()
+		bufio.NewReader(conn).ReadString('\n') // drain the INFO command
+		body := "# Server\r\nredis_version:7.2.4\r\nredis_mode:standalone\r\n"
+		conn.Write([]byte("$" + "123" + "\r\n"))
+		conn.Write([]byte(body))
+	}()
+
+	port := ln.Addr().(*net.TCPAddr).Port
+	info := ServiceInfo{Name: "redis"}
+	version, ok := runVersionDetect(Addr{IP: net.ParseIP("127.0.0.1"), Port: port}, info, 200*time.Millisecond)
+	if !ok {
+		t.Fatal("runVersionDetect: want ok=true when redis_version is present in the INFO reply")
+	}
+	if want := "Redis 7.2.4"; version != want {
+		t.Errorf("version = %q, want %q", version, want)
+	}
+}